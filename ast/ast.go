@@ -42,16 +42,64 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// import "path/to/mod" or import "path/to/mod" as name
+type ImportStatement struct {
+	Token token.Token //import
+	Path  *String
+	Name  *Identifier //the binding, from as or the last element of the path
+}
+
+func (i *ImportStatement) TokenLiteral() string { return i.Token.Literal }
+func (i *ImportStatement) statementNode()       {}
+func (i *ImportStatement) String() string {
+	return i.TokenLiteral() + " \"" + i.Path.Value + "\" as " + i.Name.String() + ";"
+}
+
+// export let name = value; or export const name = value;
+type ExportStatement struct {
+	Token     token.Token //export
+	Statement *LetStatement
+}
+
+func (e *ExportStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *ExportStatement) statementNode()       {}
+func (e *ExportStatement) String() string {
+	return e.TokenLiteral() + " " + e.Statement.String()
+}
+
+// let name = value; or const name = value;
 type LetStatement struct {
-	Token token.Token //this will have the LET token
-	Name  *Identifier
-	Value ExpressionNode
+	Token   token.Token //this will have the LET or CONST token
+	Name    *Identifier
+	Pattern Pattern  //set instead of Name by let [a, b] = ... and let {a} = ...
+	Type    TypeNode //let name: type = value; nil when the name is not annotated
+	Value   ExpressionNode
+}
+
+// Constant reports whether the statement is a const declaration.
+func (let *LetStatement) Constant() bool {
+	return let.Token.Type == token.CONST
+}
+
+// Names returns the names the statement binds.
+func (let *LetStatement) Names() []*Identifier {
+	if let.Pattern != nil {
+		return Bindings(let.Pattern)
+	}
+	return []*Identifier{let.Name}
 }
 
 func (let *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(let.TokenLiteral() + " ")
-	out.WriteString(let.Name.String())
+	if let.Pattern != nil {
+		out.WriteString(let.Pattern.String())
+	} else {
+		out.WriteString(let.Name.String())
+	}
+	if let.Type != nil {
+		out.WriteString(": " + let.Type.String())
+	}
 	out.WriteString(" = ")
 	if let.Value != nil {
 		out.WriteString(let.Value.String())
@@ -186,6 +234,7 @@ func (ife *IfExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token // { token
 	Statements []StatmentNode
+	End        token.Token // } token
 }
 
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
@@ -203,26 +252,62 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token //fn
 	Parameters []*Identifier
+	Defaults   []ExpressionNode //one per parameter, nil for a parameter without a default
+	Rest       *Identifier      //...name, which collects the arguments left over
 	Body       *BlockStatement
+
+	// The annotations are nil where there are none. Types has one entry
+	// per parameter, or is nil when no parameter is annotated.
+	Types      []TypeNode
+	RestType   TypeNode //the type of the array the rest parameter is bound to
+	ReturnType TypeNode
+}
+
+// ParameterList renders the parameters as they are written, with their
+// defaults and the rest parameter.
+func ParameterList(params []*Identifier, defaults []ExpressionNode, rest *Identifier) string {
+	return parameterList(params, nil, defaults, rest, nil)
+}
+
+func parameterList(params []*Identifier, types []TypeNode, defaults []ExpressionNode, rest *Identifier, restType TypeNode) string {
+	list := []string{}
+	for i, param := range params {
+		item := param.String()
+		if i < len(types) && types[i] != nil {
+			item += ": " + types[i].String()
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			item += " = " + defaults[i].String()
+		}
+		list = append(list, item)
+	}
+	if rest != nil {
+		item := "..." + rest.String()
+		if restType != nil {
+			item += ": " + restType.String()
+		}
+		list = append(list, item)
+	}
+	return strings.Join(list, ",")
 }
 
 func (fn *FunctionLiteral) TokenLiteral() string { return fn.Token.Literal }
 func (fn *FunctionLiteral) expressionNode()      {}
 func (fn *FunctionLiteral) String() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, param := range fn.Parameters {
-		params = append(params, param.String())
-	}
 	out.WriteString(fn.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ","))
+	out.WriteString(parameterList(fn.Parameters, fn.Types, fn.Defaults, fn.Rest, fn.RestType))
 	out.WriteString(")")
+	if fn.ReturnType != nil {
+		out.WriteString(" -> " + fn.ReturnType.String() + " ")
+	}
 	out.WriteString(fn.Body.String())
 	return out.String()
 }
 
-// add (5,6) or hello(5,func(5,4))
+// add (5,6) or hello(5,func(5,4)). Arguments may include a
+// SpreadExpression, and KeywordArguments after all the positional ones.
 type CallExpression struct {
 	Token     token.Token
 	Function  ExpressionNode
@@ -325,3 +410,518 @@ func (h *HashLiteral) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// spawn fn() { ... } or spawn f(x)
+type SpawnExpression struct {
+	Token token.Token //spawn
+	Call  ExpressionNode
+}
+
+func (s *SpawnExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SpawnExpression) expressionNode()      {}
+func (s *SpawnExpression) String() string {
+	return s.TokenLiteral() + " " + s.Call.String()
+}
+
+// select { case v = recv(ch) { ... } case send(ch, x) { ... } default { ... } }
+type SelectExpression struct {
+	Token token.Token //select
+	Cases []*SelectCase
+}
+
+func (s *SelectExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SelectExpression) expressionNode()      {}
+func (s *SelectExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("select {")
+	for _, c := range s.Cases {
+		out.WriteString(c.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// SelectCase is one arm of a select. Comm is a recv or send call, or nil for
+// the default arm, and Name, if set, is bound to the received value.
+type SelectCase struct {
+	Token token.Token //case or default
+	Name  *Identifier
+	Comm  *CallExpression
+	Body  *BlockStatement
+}
+
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+	out.WriteString(sc.Token.Literal)
+	if sc.Comm != nil {
+		out.WriteString(" ")
+		if sc.Name != nil {
+			out.WriteString(sc.Name.String() + " = ")
+		}
+		out.WriteString(sc.Comm.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(sc.Body.String())
+	return out.String()
+}
+
+// mod.name
+type MemberExpression struct {
+	Token    token.Token //. token
+	Object   ExpressionNode
+	Property *Identifier
+}
+
+func (m *MemberExpression) TokenLiteral() string { return m.Token.Literal }
+func (m *MemberExpression) expressionNode()      {}
+func (m *MemberExpression) String() string {
+	return "(" + m.Object.String() + "." + m.Property.String() + ")"
+}
+
+// struct Point { x, y fn norm() { self.x * self.x + self.y * self.y } }
+type StructStatement struct {
+	Token   token.Token //struct
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*StructMethod
+}
+
+func (s *StructStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *StructStatement) statementNode()       {}
+func (s *StructStatement) String() string {
+	var out bytes.Buffer
+	fields := []string{}
+	for _, field := range s.Fields {
+		fields = append(fields, field.String())
+	}
+	out.WriteString(s.TokenLiteral() + " " + s.Name.String() + " {")
+	out.WriteString(strings.Join(fields, ","))
+	for _, method := range s.Methods {
+		out.WriteString(" ")
+		out.WriteString(method.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// fn name(params) { body } inside a struct declaration
+type StructMethod struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (m *StructMethod) String() string {
+	params := ParameterList(m.Function.Parameters, m.Function.Defaults, m.Function.Rest)
+	return m.Function.TokenLiteral() + " " + m.Name.String() + "(" + params + ")" + m.Function.Body.String()
+}
+
+// x = value or p.x = value
+type AssignExpression struct {
+	Token  token.Token //=
+	Target ExpressionNode
+	Value  ExpressionNode
+}
+
+func (a *AssignExpression) TokenLiteral() string { return a.Token.Literal }
+func (a *AssignExpression) expressionNode()      {}
+func (a *AssignExpression) String() string {
+	return "(" + a.Target.String() + " = " + a.Value.String() + ")"
+}
+
+// enum Shape { Circle(r), Rect(w, h), Empty }
+type EnumStatement struct {
+	Token    token.Token //enum
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+func (e *EnumStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *EnumStatement) statementNode()       {}
+func (e *EnumStatement) String() string {
+	variants := []string{}
+	for _, variant := range e.Variants {
+		variants = append(variants, variant.String())
+	}
+	return e.TokenLiteral() + " " + e.Name.String() + " {" + strings.Join(variants, ",") + "}"
+}
+
+// Circle(r) inside an enum declaration. Fields is nil for a variant
+// without parentheses, which is a value rather than a constructor.
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (v *EnumVariant) String() string {
+	if v.Fields == nil {
+		return v.Name.String()
+	}
+	fields := []string{}
+	for _, field := range v.Fields {
+		fields = append(fields, field.String())
+	}
+	return v.Name.String() + "(" + strings.Join(fields, ",") + ")"
+}
+
+// match value { pattern if guard => result, ... }
+type MatchExpression struct {
+	Token   token.Token //match
+	Subject ExpressionNode
+	Arms    []*MatchArm
+	End     token.Token // } token
+}
+
+func (m *MatchExpression) TokenLiteral() string { return m.Token.Literal }
+func (m *MatchExpression) expressionNode()      {}
+func (m *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+	return m.TokenLiteral() + " " + m.Subject.String() + " {" + strings.Join(arms, ", ") + "}"
+}
+
+// MatchArm is one pattern of a match expression. An arm written as a bare
+// expression is parsed as a block holding that expression.
+type MatchArm struct {
+	Pattern Pattern
+	Guard   ExpressionNode //nil without if
+	Body    *BlockStatement
+}
+
+func (a *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(a.Pattern.String())
+	if a.Guard != nil {
+		out.WriteString(" if " + a.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(a.Body.String())
+	return out.String()
+}
+
+// Pattern is matched against a value, binding names to the parts of it.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// _
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (w *WildcardPattern) TokenLiteral() string { return w.Token.Literal }
+func (w *WildcardPattern) patternNode()         {}
+func (w *WildcardPattern) String() string       { return w.Token.Literal }
+
+// a name, bound to whatever it is matched against
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (b *BindingPattern) TokenLiteral() string { return b.Name.TokenLiteral() }
+func (b *BindingPattern) patternNode()         {}
+func (b *BindingPattern) String() string       { return b.Name.String() }
+
+// 1, -1, "a" or true, matching equal values
+type LiteralPattern struct {
+	Value ExpressionNode
+}
+
+func (l *LiteralPattern) TokenLiteral() string { return l.Value.TokenLiteral() }
+func (l *LiteralPattern) patternNode()         {}
+func (l *LiteralPattern) String() string {
+	if s, ok := l.Value.(*String); ok {
+		return "\"" + s.Value + "\""
+	}
+	return l.Value.String()
+}
+
+// [a, b, ...rest]
+type ArrayPattern struct {
+	Token    token.Token //[
+	Elements []Pattern
+	Rest     Pattern //nil without ..., matched against the remaining elements
+}
+
+func (a *ArrayPattern) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayPattern) patternNode()         {}
+func (a *ArrayPattern) String() string {
+	elements := []string{}
+	for _, ele := range a.Elements {
+		elements = append(elements, ele.String())
+	}
+	if a.Rest != nil {
+		elements = append(elements, "..."+a.Rest.String())
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+// {"kind": "circle", r}, where a bare name matches the field of that name
+type HashPattern struct {
+	Token token.Token //{
+	Pairs []*HashPatternPair
+}
+
+type HashPatternPair struct {
+	Key   *String
+	Value Pattern
+}
+
+func (h *HashPattern) TokenLiteral() string { return h.Token.Literal }
+func (h *HashPattern) patternNode()         {}
+func (h *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, "\""+pair.Key.Value+"\":"+pair.Value.String())
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Circle(r) or Shape.Circle(r), matching values built by that constructor,
+// or Shape.Empty, matching that variant without fields
+type ConstructorPattern struct {
+	Token       token.Token //the first identifier
+	Constructor ExpressionNode
+	Fields      []Pattern //nil for a variant without fields
+}
+
+func (c *ConstructorPattern) TokenLiteral() string { return c.Token.Literal }
+func (c *ConstructorPattern) patternNode()         {}
+func (c *ConstructorPattern) String() string {
+	fields := []string{}
+	for _, field := range c.Fields {
+		fields = append(fields, field.String())
+	}
+	if c.Fields == nil {
+		return c.Constructor.String()
+	}
+	return c.Constructor.String() + "(" + strings.Join(fields, ",") + ")"
+}
+
+// b = 2 inside an array or hash pattern, matching the default when the
+// element or field is missing
+type DefaultPattern struct {
+	Pattern Pattern
+	Default ExpressionNode
+}
+
+func (d *DefaultPattern) TokenLiteral() string { return d.Pattern.TokenLiteral() }
+func (d *DefaultPattern) patternNode()         {}
+func (d *DefaultPattern) String() string {
+	return d.Pattern.String() + " = " + d.Default.String()
+}
+
+// Bindings returns the names a pattern binds, in order of appearance.
+func Bindings(pattern Pattern) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		return []*Identifier{pattern.Name}
+	case *DefaultPattern:
+		return Bindings(pattern.Pattern)
+	case *ArrayPattern:
+		names := []*Identifier{}
+		for _, element := range pattern.Elements {
+			names = append(names, Bindings(element)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, Bindings(pattern.Rest)...)
+		}
+		return names
+	case *HashPattern:
+		names := []*Identifier{}
+		for _, pair := range pattern.Pairs {
+			names = append(names, Bindings(pair.Value)...)
+		}
+		return names
+	case *ConstructorPattern:
+		names := []*Identifier{}
+		for _, field := range pattern.Fields {
+			names = append(names, Bindings(field)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// ...arr in the arguments of a call, passing each element as an argument
+type SpreadExpression struct {
+	Token token.Token //...
+	Value ExpressionNode
+}
+
+func (s *SpreadExpression) TokenLiteral() string { return s.Token.Literal }
+func (s *SpreadExpression) expressionNode()      {}
+func (s *SpreadExpression) String() string       { return "..." + s.Value.String() }
+
+// y: 2 in the arguments of a call, passing 2 as the parameter y
+type KeywordArgument struct {
+	Token token.Token //the name
+	Name  *Identifier
+	Value ExpressionNode
+}
+
+func (k *KeywordArgument) TokenLiteral() string { return k.Token.Literal }
+func (k *KeywordArgument) expressionNode()      {}
+func (k *KeywordArgument) String() string       { return k.Name.String() + ": " + k.Value.String() }
+
+// TypeNode is a type annotation, as in let x: [int] = ... or
+// fn(a: string) -> bool { ... }.
+type TypeNode interface {
+	Node
+	typeNode()
+}
+
+// int, string, bool, null, any, or the name of a struct or an enum
+type NamedType struct {
+	Token token.Token //the name
+	Name  string
+}
+
+func (n *NamedType) TokenLiteral() string { return n.Token.Literal }
+func (n *NamedType) typeNode()            {}
+func (n *NamedType) String() string       { return n.Name }
+
+// [int], an array of ints
+type ArrayType struct {
+	Token   token.Token //[
+	Element TypeNode
+}
+
+func (a *ArrayType) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayType) typeNode()            {}
+func (a *ArrayType) String() string       { return "[" + a.Element.String() + "]" }
+
+// {string: int}, a hash from strings to ints
+type HashType struct {
+	Token token.Token //{
+	Key   TypeNode
+	Value TypeNode
+}
+
+func (h *HashType) TokenLiteral() string { return h.Token.Literal }
+func (h *HashType) typeNode()            {}
+func (h *HashType) String() string {
+	return "{" + h.Key.String() + ": " + h.Value.String() + "}"
+}
+
+// fn(int, int) -> int; the return type may be left out
+type FunctionType struct {
+	Token      token.Token //fn
+	Parameters []TypeNode
+	Return     TypeNode
+}
+
+func (f *FunctionType) TokenLiteral() string { return f.Token.Literal }
+func (f *FunctionType) typeNode()            {}
+func (f *FunctionType) String() string {
+	params := []string{}
+	for _, param := range f.Parameters {
+		params = append(params, param.String())
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if f.Return != nil {
+		out += " -> " + f.Return.String()
+	}
+	return out
+}
+
+// StartToken returns the first token of exp, where it starts in the
+// source.
+func StartToken(exp ExpressionNode) token.Token {
+	switch exp := exp.(type) {
+	case *Identifier:
+		return exp.Token
+	case *IntegerLiteral:
+		return exp.Token
+	case *String:
+		return exp.Token
+	case *Boolean:
+		return exp.Token
+	case *PrefixExpression:
+		return exp.Token
+	case *InfixExpression:
+		return StartToken(exp.Left)
+	case *IfExpression:
+		return exp.Token
+	case *FunctionLiteral:
+		return exp.Token
+	case *CallExpression:
+		return StartToken(exp.Function)
+	case *ArrayLiteral:
+		return exp.Token
+	case *IndexExpression:
+		return StartToken(exp.Left)
+	case *HashLiteral:
+		return exp.Token
+	case *SpawnExpression:
+		return exp.Token
+	case *SelectExpression:
+		return exp.Token
+	case *MemberExpression:
+		return StartToken(exp.Object)
+	case *AssignExpression:
+		return StartToken(exp.Target)
+	case *MatchExpression:
+		return exp.Token
+	case *SpreadExpression:
+		return exp.Token
+	case *KeywordArgument:
+		return exp.Token
+	}
+	return token.Token{}
+}
+
+// Start returns the first token of node, which may be a statement, an
+// expression or a pattern.
+func Start(node Node) token.Token {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) > 0 {
+			return Start(node.Statements[0])
+		}
+	case *BlockStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return StartToken(node.Expression)
+	case *LetStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ImportStatement:
+		return node.Token
+	case *ExportStatement:
+		return node.Token
+	case *StructStatement:
+		return node.Token
+	case *EnumStatement:
+		return node.Token
+	case Pattern:
+		return PatternStartToken(node)
+	case ExpressionNode:
+		return StartToken(node)
+	}
+	return token.Token{}
+}
+
+// PatternStartToken returns the first token of pattern.
+func PatternStartToken(pattern Pattern) token.Token {
+	switch pattern := pattern.(type) {
+	case *WildcardPattern:
+		return pattern.Token
+	case *BindingPattern:
+		return pattern.Name.Token
+	case *LiteralPattern:
+		return StartToken(pattern.Value)
+	case *ArrayPattern:
+		return pattern.Token
+	case *HashPattern:
+		return pattern.Token
+	case *ConstructorPattern:
+		return pattern.Token
+	case *DefaultPattern:
+		return PatternStartToken(pattern.Pattern)
+	}
+	return token.Token{}
+}
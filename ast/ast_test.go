@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/nishokbanand/interpreter/token"
@@ -32,3 +33,33 @@ func TestStringMethod(t *testing.T) {
 		t.Errorf("program.String wrong got=%q", program.String())
 	}
 }
+
+func ident(name string, line, column int) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name, Line: line, Column: column}, Value: name}
+}
+
+func TestInspect(t *testing.T) {
+	// let f = fn(a) { a + b }; struct P { fn m() { c } }
+	body := &BlockStatement{Statements: []StatmentNode{
+		&ExpressionStatement{Expression: &InfixExpression{Operator: "+", Left: ident("a", 1, 17), Right: ident("b", 1, 21)}},
+	}}
+	method := &BlockStatement{Statements: []StatmentNode{&ExpressionStatement{Expression: ident("c", 1, 45)}}}
+	program := &Program{Statements: []StatmentNode{
+		&LetStatement{Name: ident("f", 1, 5), Value: &FunctionLiteral{Parameters: []*Identifier{ident("a", 1, 12)}, Body: body}},
+		&StructStatement{Name: ident("P", 1, 33), Methods: []*StructMethod{{Name: ident("m", 1, 40), Function: &FunctionLiteral{Body: method}}}},
+	}}
+	names := []string{}
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return node != method // c is not visited
+	})
+	if got := strings.Join(names, " "); got != "f a a b P m" {
+		t.Errorf("wrong identifiers %q", got)
+	}
+	functions := FunctionNames(program)
+	if len(functions) != 2 || functions[body] != "f" || functions[method] != "P.m" {
+		t.Errorf("wrong function names %v", functions)
+	}
+}
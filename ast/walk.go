@@ -0,0 +1,176 @@
+package ast
+
+import "sort"
+
+// Inspect calls f for node and then, in source order, for every node
+// beneath it, as long as f returns true. The parts of compound nodes that
+// are not nodes themselves, such as match arms, are walked through.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	inspectAll := func(nodes ...Node) {
+		for _, n := range nodes {
+			Inspect(n, f)
+		}
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *LetStatement:
+		if node.Pattern != nil {
+			Inspect(node.Pattern, f)
+		} else {
+			Inspect(node.Name, f)
+		}
+		inspectAll(node.Value)
+	case *ReturnStatement:
+		inspectAll(node.Value)
+	case *ExpressionStatement:
+		inspectAll(node.Expression)
+	case *ImportStatement:
+		inspectAll(node.Path, node.Name)
+	case *ExportStatement:
+		inspectAll(node.Statement)
+	case *StructStatement:
+		Inspect(node.Name, f)
+		for _, field := range node.Fields {
+			Inspect(field, f)
+		}
+		for _, method := range node.Methods {
+			inspectAll(method.Name, method.Function)
+		}
+	case *EnumStatement:
+		Inspect(node.Name, f)
+		for _, variant := range node.Variants {
+			Inspect(variant.Name, f)
+			for _, field := range variant.Fields {
+				Inspect(field, f)
+			}
+		}
+	case *PrefixExpression:
+		inspectAll(node.Right)
+	case *InfixExpression:
+		inspectAll(node.Left, node.Right)
+	case *IfExpression:
+		inspectAll(node.Condition, node.Consequence)
+		if node.Alternative != nil {
+			Inspect(node.Alternative, f)
+		}
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			Inspect(param, f)
+			if i < len(node.Defaults) && node.Defaults[i] != nil {
+				Inspect(node.Defaults[i], f)
+			}
+		}
+		if node.Rest != nil {
+			Inspect(node.Rest, f)
+		}
+		Inspect(node.Body, f)
+	case *CallExpression:
+		Inspect(node.Function, f)
+		for _, arg := range node.Arguments {
+			Inspect(arg, f)
+		}
+	case *ArrayLiteral:
+		for _, ele := range node.Elements {
+			Inspect(ele, f)
+		}
+	case *IndexExpression:
+		inspectAll(node.Left, node.Index)
+	case *HashLiteral:
+		// the pairs of a hash are unordered, so walk them by position
+		keys := []ExpressionNode{}
+		for key := range node.Pairs {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := StartToken(keys[i]), StartToken(keys[j])
+			return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+		})
+		for _, key := range keys {
+			inspectAll(key, node.Pairs[key])
+		}
+	case *SpawnExpression:
+		inspectAll(node.Call)
+	case *SelectExpression:
+		for _, sc := range node.Cases {
+			if sc.Name != nil {
+				Inspect(sc.Name, f)
+			}
+			if sc.Comm != nil {
+				Inspect(sc.Comm, f)
+			}
+			Inspect(sc.Body, f)
+		}
+	case *MemberExpression:
+		inspectAll(node.Object, node.Property)
+	case *AssignExpression:
+		inspectAll(node.Target, node.Value)
+	case *MatchExpression:
+		Inspect(node.Subject, f)
+		for _, arm := range node.Arms {
+			Inspect(arm.Pattern, f)
+			if arm.Guard != nil {
+				Inspect(arm.Guard, f)
+			}
+			Inspect(arm.Body, f)
+		}
+	case *SpreadExpression:
+		inspectAll(node.Value)
+	case *KeywordArgument:
+		inspectAll(node.Name, node.Value)
+	case *BindingPattern:
+		Inspect(node.Name, f)
+	case *LiteralPattern:
+		inspectAll(node.Value)
+	case *ArrayPattern:
+		for _, element := range node.Elements {
+			Inspect(element, f)
+		}
+		if node.Rest != nil {
+			Inspect(node.Rest, f)
+		}
+	case *HashPattern:
+		for _, pair := range node.Pairs {
+			inspectAll(pair.Key, pair.Value)
+		}
+	case *ConstructorPattern:
+		Inspect(node.Constructor, f)
+		for _, field := range node.Fields {
+			Inspect(field, f)
+		}
+	case *DefaultPattern:
+		inspectAll(node.Pattern, node.Default)
+	}
+}
+
+// FunctionNames names the functions of program by where they are bound:
+// a function literal bound by let takes the name of the variable, and a
+// method is named after its struct, as in Point.norm. The names are keyed
+// by the body of each function, which is what function values keep.
+// Anonymous functions are left out.
+func FunctionNames(program *Program) map[*BlockStatement]string {
+	names := map[*BlockStatement]string{}
+	Inspect(program, func(node Node) bool {
+		switch node := node.(type) {
+		case *LetStatement:
+			if fn, ok := node.Value.(*FunctionLiteral); ok && node.Name != nil {
+				names[fn.Body] = node.Name.Value
+			}
+		case *StructStatement:
+			for _, method := range node.Methods {
+				names[method.Function.Body] = node.Name.Value + "." + method.Name.Value
+			}
+		}
+		return true
+	})
+	return names
+}
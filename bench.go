@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// benchCommand runs a script n times on one backend and reports the mean
+// wall time and allocations per run.
+func benchCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	n := fs.Int("n", 10, "number of runs")
+	engine := fs.String("engine", engineEval, "backend to run with: eval or vm")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: interpreter bench [-n runs] [-engine eval|vm] file")
+	}
+	if *n <= 0 {
+		return fmt.Errorf("-n must be positive, got %d", *n)
+	}
+	if err := checkEngine(*engine); err != nil {
+		return err
+	}
+	source, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	program, err := parseSource(string(source))
+	if err != nil {
+		return err
+	}
+	res, err := check(program, nil)
+	if err != nil {
+		return err
+	}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < *n; i++ {
+		if _, err := execute(program, res, *engine, filepath.Dir(fs.Arg(0))); err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	runs := uint64(*n)
+	fmt.Fprintf(out, "%s: %d runs on %s\n", fs.Arg(0), *n, *engine)
+	fmt.Fprintf(out, "  total   %v\n", elapsed)
+	fmt.Fprintf(out, "  per run %v\n", elapsed/time.Duration(*n))
+	fmt.Fprintf(out, "  allocs  %d/run\n", (after.Mallocs-before.Mallocs)/runs)
+	fmt.Fprintf(out, "  bytes   %d/run\n", (after.TotalAlloc-before.TotalAlloc)/runs)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/resolver"
+	"github.com/nishokbanand/interpreter/typecheck"
+)
+
+// checkCommand reports, without running them, the undefined names, unused
+// variables and type mismatches in each of the files named by args.
+func checkCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: interpreter check files...")
+	}
+	errs := 0
+	for _, path := range fs.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		program, err := parseSource(string(source))
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s\n", path, err)
+			errs++
+			continue
+		}
+		diagnostics := resolver.Resolve(program, evaluate.IsBuiltin).Diagnostics
+		diagnostics = append(diagnostics, typecheck.Check(program)...)
+		for _, d := range diagnostics {
+			fmt.Fprintf(out, "%s:%s\n", path, d)
+			if d.Severity == resolver.Error {
+				errs++
+			}
+		}
+	}
+	switch {
+	case errs == 1:
+		return fmt.Errorf("found 1 error")
+	case errs > 1:
+		return fmt.Errorf("found %d errors", errs)
+	}
+	return nil
+}
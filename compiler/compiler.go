@@ -0,0 +1,401 @@
+// Package compiler lowers programs to bytecode for the vm. It covers the
+// core language only: integers, booleans, strings, arrays, hashes, let and
+// const bindings, if, functions with closures and the builtins of
+// object.Builtins. Destructuring, default and rest parameters, keyword
+// arguments, member access, structs, enums, match, assignment, spawn,
+// select and import are run by the evaluator alone; Compile reports them
+// as errors. The conformance tests list them.
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/code"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+type CompilationScope struct {
+	instructions        code.Instructions
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+}
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+	scopes      []CompilationScope
+	scopeIndex  int
+}
+
+// Bytecode is the output of the compiler: the instructions of the main
+// program and the constant pool they index into.
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	GlobalNames  []string //indexed by global slot, for runtime error messages
+}
+
+func New() *Compiler {
+	symbolTable := NewSymbolTable()
+	for idx, def := range object.Builtins {
+		symbolTable.DefineBuiltin(idx, def.Name)
+	}
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{{instructions: code.Instructions{}}},
+	}
+}
+
+// NewWithState creates a compiler that keeps adding to an existing symbol
+// table and constant pool, which is how the REPL keeps globals across lines.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	c := New()
+	c.symbolTable = s
+	c.constants = constants
+	return c
+}
+
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.names(),
+	}
+}
+
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	case *ast.Program:
+		// top level lets are declared up front so functions can refer to
+		// globals defined after them, as they can in the evaluator
+		for _, stmt := range node.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok && let.Pattern == nil {
+				c.symbolTable.Define(let.Name.Value)
+			}
+		}
+		for _, stmt := range node.Statements {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		// the value of the last expression is the value of the program
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWith(code.OpReturnValue)
+		}
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return fmt.Errorf("cannot compile destructuring let %s", node.String())
+		}
+		name := ""
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			name = node.Name.Value
+		}
+		if err := c.compileValue(node.Value, name); err != nil {
+			return err
+		}
+		symbol := c.symbolTable.Define(node.Name.Value)
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("identifier not found %s", node.Value)
+		}
+		c.loadSymbol(symbol)
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: node.Value}))
+	case *ast.String:
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Value}))
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("Unknown operator %s", node.Operator)
+		}
+	case *ast.InfixExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "+":
+			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			c.emit(code.OpDiv)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
+		default:
+			return fmt.Errorf("Unknown operator %s", node.Operator)
+		}
+	case *ast.IfExpression:
+		return c.compileIf(node)
+	case *ast.ArrayLiteral:
+		for _, ele := range node.Elements {
+			if err := c.Compile(ele); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		keys := []ast.ExpressionNode{}
+		for key := range node.Pairs {
+			keys = append(keys, key)
+		}
+		// Pairs is a map, sort the keys so the output is deterministic
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			if err := c.Compile(key); err != nil {
+				return err
+			}
+			if err := c.Compile(node.Pairs[key]); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node, "")
+	case *ast.CallExpression:
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			if err := c.Compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+	default:
+		return fmt.Errorf("cannot compile %T", node)
+	}
+	return nil
+}
+
+func (c *Compiler) compileValue(node ast.ExpressionNode, name string) error {
+	if fn, ok := node.(*ast.FunctionLiteral); ok {
+		return c.compileFunction(fn, name)
+	}
+	return c.Compile(node)
+}
+
+func (c *Compiler) compileIf(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+	//placeholder offset, patched once the consequence is compiled
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	if err := c.compileBranch(node.Consequence); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBranch(node.Alternative); err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileBranch compiles a block used as an expression, leaving its value on
+// the stack instead of popping it.
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral, name string) error {
+	if node.Defaults != nil || node.Rest != nil {
+		return fmt.Errorf("cannot compile default or rest parameters in %s", node.String())
+	}
+	c.enterScope()
+	if name != "" {
+		c.symbolTable.DefineFunctionName(name)
+	}
+	for _, param := range node.Parameters {
+		c.symbolTable.Define(param.Value)
+	}
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWith(code.OpReturnValue)
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	instructions := c.leaveScope()
+	for _, symbol := range freeSymbols {
+		c.loadSymbol(symbol)
+	}
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+	}
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+	return nil
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+	c.setLastInstruction(op, pos)
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	pos := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	return pos
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWith(op code.Opcode) {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(op))
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = op
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.replaceInstruction(opPos, code.Make(op, operand))
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{instructions: code.Instructions{}})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+	return instructions
+}
@@ -0,0 +1,180 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/code"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+type compilerTest struct {
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestCompile(t *testing.T) {
+	tests := []compilerTest{
+		{
+			input:             "1 + 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "1 < 2; 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpJumpNotTruthy, 10),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpJump, 11),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input:             "let one = 1; let two = one;",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			input:             "[1, 2][0]",
+			expectedConstants: []interface{}{1, 2, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input: "fn(a) { fn(b) { a + b } }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpReturnValue),
+			},
+		},
+		{
+			input: "let f = fn() { f() }",
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			input:             "len([])",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, 0),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpReturnValue),
+			},
+		},
+	}
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		compiler := New()
+		if err := compiler.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := compiler.Bytecode()
+		testInstructions(t, tt.input, tt.expectedInstructions, bytecode.Instructions)
+		testConstants(t, tt.input, tt.expectedConstants, bytecode.Constants)
+	}
+}
+
+func TestCompileUndefinedIdentifier(t *testing.T) {
+	program := parser.New(lexer.New("fn() { x }")).ParseProgram()
+	err := New().Compile(program)
+	if err == nil || err.Error() != "identifier not found x" {
+		t.Errorf("expected identifier not found error, got %v", err)
+	}
+}
+
+func concatInstructions(s []code.Instructions) code.Instructions {
+	out := code.Instructions{}
+	for _, ins := range s {
+		out = append(out, ins...)
+	}
+	return out
+}
+
+func testInstructions(t *testing.T, input string, expected []code.Instructions, actual code.Instructions) {
+	t.Helper()
+	concatted := concatInstructions(expected)
+	if concatted.String() != actual.String() {
+		t.Errorf("wrong instructions for %q\nwant=\n%s\ngot=\n%s", input, concatted, actual)
+	}
+}
+
+func testConstants(t *testing.T, input string, expected []interface{}, actual []object.Object) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("wrong number of constants for %q, want %d, got %d", input, len(expected), len(actual))
+	}
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			if !ok || integer.Value != int64(constant) {
+				t.Errorf("constant %d for %q: want %d, got %v", i, input, constant, actual[i])
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				t.Errorf("constant %d for %q: want *object.CompiledFunction, got %T", i, input, actual[i])
+				continue
+			}
+			testInstructions(t, input, constant, fn.Instructions)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+type SymbolTable struct {
+	Outer          *SymbolTable
+	store          map[string]Symbol
+	numDefinitions int
+	FreeSymbols    []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: make(map[string]Symbol), FreeSymbols: []Symbol{}}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name in this table. Redefining a name already bound in the
+// same table reuses its slot, mirroring how Environment.Set overwrites.
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName binds the name a function literal is being let-bound
+// to inside its own scope, so it can refer to itself without capturing a
+// free variable that is not set yet.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		symbol, ok = s.Outer.Resolve(name)
+		if !ok {
+			return symbol, ok
+		}
+		if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+			return symbol, ok
+		}
+		return s.defineFree(symbol), true
+	}
+	return symbol, ok
+}
+
+// names returns the names of the slots defined in this table, by index.
+func (s *SymbolTable) names() []string {
+	names := make([]string, s.numDefinitions)
+	for _, symbol := range s.store {
+		if symbol.Scope == GlobalScope || symbol.Scope == LocalScope {
+			names[symbol.Index] = symbol.Name
+		}
+	}
+	return names
+}
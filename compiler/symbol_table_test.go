@@ -0,0 +1,43 @@
+package compiler
+
+import "testing"
+
+func TestResolveNested(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: FreeScope, Index: 0},
+		{Name: "c", Scope: LocalScope, Index: 0},
+	}
+	for _, want := range expected {
+		got, ok := second.Resolve(want.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", want.Name)
+			continue
+		}
+		if got != want {
+			t.Errorf("expected %s to resolve to %+v, got %+v", want.Name, want, got)
+		}
+	}
+	if len(second.FreeSymbols) != 1 || second.FreeSymbols[0].Name != "b" {
+		t.Errorf("expected b to be recorded as free, got %+v", second.FreeSymbols)
+	}
+	if _, ok := second.Resolve("d"); ok {
+		t.Errorf("d should not resolve")
+	}
+}
+
+func TestRedefineReusesSlot(t *testing.T) {
+	global := NewSymbolTable()
+	first := global.Define("a")
+	global.Define("b")
+	again := global.Define("a")
+	if first != again {
+		t.Errorf("expected redefinition to reuse %+v, got %+v", first, again)
+	}
+}
@@ -0,0 +1,129 @@
+package conformance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/compiler"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/token"
+	"github.com/nishokbanand/interpreter/vm"
+)
+
+// benchmarkSnippet is repeated to make the input of the front end
+// benchmarks.
+const benchmarkSnippet = `let fib = fn(n) {
+	if (n < 2) { return n; }
+	fib(n - 1) + fib(n - 2);
+};
+let words = ["alpha", "beta", "gamma"];
+let counts = {"alpha": 1, "beta": 2, "gamma": 3};
+let total = counts[words[0]] + counts["beta"] * 10 / 2 - 1;
+if (total != 5) { print("unexpected") } else { !true == false }
+`
+
+func BenchmarkLexer(b *testing.B) {
+	input := strings.Repeat(benchmarkSnippet, 1000)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := lexer.New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	input := strings.Repeat(benchmarkSnippet, 1000)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			b.Fatalf("parser errors: %v", p.Errors())
+		}
+	}
+}
+
+var workloads = []struct {
+	name  string
+	input string
+}{
+	{
+		"fibonacci",
+		`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+		fib(20)`,
+	},
+	{
+		"push",
+		`let build = fn(n, arr) { if (n == 0) { arr } else { build(n - 1, push(arr, n)) } };
+		len(build(500, []))`,
+	},
+	{
+		// counts how often each word occurs; hashes are immutable, so the
+		// counts are collected in an array, one per word
+		"wordcount",
+		`let words = ["the", "quick", "brown", "fox", "jumps", "over", "the", "lazy", "dog", "the", "fox"];
+		let occurrences = fn(word, i, n) { if (i == len(words)) { n } else { occurrences(word, i + 1, if (words[i] == word) { n + 1 } else { n }) } };
+		let counts = fn(i, acc) { if (i == len(words)) { acc } else { counts(i + 1, push(acc, occurrences(words[i], 0, 0))) } };
+		let repeat = fn(n, result) { if (n == 0) { result } else { repeat(n - 1, counts(0, [])) } };
+		repeat(100, [])`,
+	},
+	{
+		"closures",
+		`let compose = fn(f, g) { fn(x) { g(f(x)) } };
+		let adder = fn(n) { fn(x) { x + n } };
+		let chain = fn(n, f) { if (n == 0) { f } else { chain(n - 1, compose(f, adder(n))) } };
+		let apply = fn(n, total) { if (n == 0) { total } else { apply(n - 1, total + chain(50, adder(0))(n)) } };
+		apply(50, 0)`,
+	},
+}
+
+func BenchmarkEvaluator(b *testing.B) {
+	for _, w := range workloads {
+		program := parse(b, w.input)
+		b.Run(w.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				result := evaluate.Eval(program, object.NewEnvironment())
+				if errObj, ok := result.(*object.Error); ok {
+					b.Fatalf("evaluation failed: %s", errObj.Message)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVM(b *testing.B) {
+	for _, w := range workloads {
+		program := parse(b, w.input)
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			b.Fatalf("compiler error: %s", err)
+		}
+		bytecode := comp.Bytecode()
+		b.Run(w.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := vm.New(bytecode).Run(); err != nil {
+					b.Fatalf("vm error: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkloadsAgree(t *testing.T) {
+	for _, w := range workloads {
+		program := parse(t, w.input)
+		evaluated := runEvaluator(program)
+		executed := runVM(program)
+		if evaluated != executed {
+			t.Errorf("backends disagree for %s\n evaluator: %s\n vm:        %s", w.name, evaluated, executed)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package conformance runs the same programs through the tree-walking
+// evaluator and the bytecode vm and checks that both backends agree.
+package conformance
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/compiler"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/vm"
+)
+
+var programs = []string{
+	// integers and booleans
+	"5",
+	"-5 + 10 * 2 - 4 / 2",
+	"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+	"1 < 2",
+	"1 > 2",
+	"1 == 1",
+	"1 != 1",
+	"true == true",
+	"true != false",
+	"(1 < 2) == true",
+	"!true",
+	"!!false",
+	"!5",
+	// conditionals
+	"if (true) { 10 }",
+	"if (false) { 10 }",
+	"if (1 > 2) { 10 } else { 20 }",
+	"if (1) { 10 } else { 20 }",
+	"if (if (false) { 10 }) { 10 } else { 20 }",
+	// bindings
+	"let a = 5; let b = a * 2; a + b",
+	"let a = 1; let a = a + 1; a",
+	"let x = 5;",
+	// arrays, hashes, functions, builtins and null compare by identity
+	"[1] == [1]",
+	"[1] != [1]",
+	"let a = [1]; a == a",
+	"{1: 2} == {1: 2}",
+	"let h = {1: 2}; h == h",
+	"fn(x) { x } == fn(x) { x }",
+	"let f = fn(x) { x }; let g = f; f == g",
+	"len == len",
+	"len == first",
+	"let n = if (false) { 1 }; n == n",
+	// strings
+	`"hello" + " " + "world"`,
+	`let s = "mon"; s + "key"`,
+	`"a" == "a"`,
+	`"a" != "a"`,
+	`"a" - "b"`,
+	// arrays and hashes
+	"[1, 2 * 2, 3 + 3]",
+	"[1, 2, 3][1]",
+	"let arr = [1, 2, 3]; arr[0] + arr[1] + arr[2]",
+	"{1: 2, 3: 4}[3]",
+	`{"one": 1, "two": 2}["two"]`,
+	`{"one": 1}["three"]`,
+	"{true: 5}[true]",
+	// functions and closures
+	"let add = fn(a, b) { a + b }; add(1, 2)",
+	"let f = fn() { return 5; 10 }; f()",
+	"let f = fn() { }; f()",
+	"let f = fn() { let x = 1; }; f()",
+	"let f = fn(x) { if (x > 1) { return 1; } 2 }; f(3) + f(0)",
+	"let newAdder = fn(a) { fn(b) { a + b } }; let addTwo = newAdder(2); addTwo(3)",
+	"let a = fn(x) { fn(y) { fn(z) { x + y + z } } }; a(1)(2)(3)",
+	"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)",
+	"let wrapper = fn() { let inner = fn(n) { if (n == 0) { 0 } else { inner(n - 1) } }; inner(5) }; wrapper()",
+	"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(10)",
+	"let twice = fn(f, x) { f(f(x)) }; twice(fn(x) { x * 2 }, 3)",
+	"return 10; 20",
+	// calls in tail position reuse the frame, others do not
+	"let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)",
+	"let loop = fn(n) { if (n > 0) { loop(n - 1) } else { 0 } }; loop(100000)",
+	"let loop = fn(n) { if (n == 0) { return 0; } return loop(n - 1); }; loop(100000)",
+	"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(100001)",
+	"let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } }; count(100000)",
+	// annotations are not checked at run time
+	"let x: int = 5; let add = fn(a: int, b: int) -> int { a + b }; add(x, 1)",
+	`let greet: fn(string) -> string = fn(name: string) { "hi " + name }; greet("ann")`,
+	`let n: int = "five"; n`,
+	// builtins
+	`len("hello")`,
+	"len([1, 2, 3])",
+	"first([4, 5])",
+	"last([4, 5])",
+	"rest([1, 2, 3])",
+	"push([1], 2)",
+	"first([])",
+	"rest([])",
+	`let map = fn(arr, f) { let iter = fn(arr, acc) { if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) } }; iter(arr, []) }; map([1, 2, 3], fn(x) { x * x })`,
+	`let reduce = fn(arr, initial, f) { let iter = fn(arr, result) { if (len(arr) == 0) { result } else { iter(rest(arr), f(result, first(arr))) } }; iter(arr, initial) }; reduce([1, 2, 3, 4, 5], 0, fn(acc, x) { acc + x })`,
+	// errors
+	"5 + true",
+	"5 + true; 5",
+	"-true",
+	"true + false",
+	"if (10 > 1) { true + false }",
+	"foobar",
+	"[1, 2][5]",
+	"1 / 0",
+	`len(1)`,
+	"let x = 5; x()",
+	"{[1]: 2}",
+	`{"a": 1}[fn() { 1 }]`,
+	`{"a": 1}[len]`,
+	"let f = fn(x) { x }; f()",
+	"let f = fn(x) { x }; f(1, 2)",
+}
+
+func parse(t testing.TB, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// inspect renders a result so the two backends can be compared; errors
+// from either are compared by message.
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	if errObj, ok := obj.(*object.Error); ok {
+		return "ERROR: " + errObj.Message
+	}
+	return obj.Inspect()
+}
+
+func runEvaluator(program *ast.Program) string {
+	return inspect(evaluate.Eval(program, object.NewEnvironment()))
+}
+
+func runVM(program *ast.Program) string {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return "ERROR: " + err.Error()
+	}
+	machine := vm.New(comp.Bytecode())
+	if err := machine.Run(); err != nil {
+		return "ERROR: " + err.Error()
+	}
+	return inspect(machine.Result())
+}
+
+func TestBackendsAgree(t *testing.T) {
+	for _, input := range programs {
+		program := parse(t, input)
+		evaluated := runEvaluator(program)
+		executed := runVM(program)
+		if evaluated != executed {
+			t.Errorf("backends disagree for %q\n evaluator: %s\n vm:        %s", input, evaluated, executed)
+		}
+	}
+}
+
+// TestVMSubset names the constructs only the evaluator runs; the compiler
+// rejects each of them before the vm starts.
+func TestVMSubset(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1, 2]; a + b", "cannot compile destructuring let let [a,b] = [1,2];"},
+		{"let f = fn(a, b = 2) { a + b }; f(1)", "cannot compile default or rest parameters in fn(a,b = 2){(a + b)}"},
+		{"let f = fn(...rest) { len(rest) }; f(1, 2)", "cannot compile default or rest parameters in fn(...rest){len(rest)}"},
+		{"let f = fn(a) { a }; f(a: 1)", "cannot compile *ast.KeywordArgument"},
+		{`let h = {"a": 1}; h.a`, "cannot compile *ast.MemberExpression"},
+		{"struct P { x }; P(1)", "cannot compile *ast.StructStatement"},
+		{"enum Color { Red, Green }; Green", "cannot compile *ast.EnumStatement"},
+		{"match 1 { 1 => 2, _ => 3 }", "cannot compile *ast.MatchExpression"},
+		{"let x = 1; x = 2; x", "cannot compile *ast.AssignExpression"},
+		{"let t = spawn len([1]); await(t)", "cannot compile *ast.SpawnExpression"},
+		{"select { default { 0 } }", "cannot compile *ast.SelectExpression"},
+		{`import "m"; 1`, "cannot compile *ast.ImportStatement"},
+	}
+	for _, tt := range tests {
+		if executed := runVM(parse(t, tt.input)); executed != "ERROR: "+tt.expected {
+			t.Errorf("%q: expected the vm to fail with %q, got %s", tt.input, tt.expected, executed)
+		}
+	}
+}
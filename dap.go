@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/nishokbanand/interpreter/dap"
+)
+
+// dapCommand runs a debug adapter on stdin and stdout until the editor
+// that started it disconnects.
+func dapCommand(args []string) error {
+	fs := flag.NewFlagSet("dap", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return dap.NewServer(os.Stdin, os.Stdout).Serve()
+}
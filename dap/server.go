@@ -0,0 +1,377 @@
+// Package dap is a Debug Adapter Protocol server for the language,
+// speaking to an editor over a pair of streams. It launches one script
+// under a debugger.Debugger and reports where it stops; while it is
+// stopped, the editor can look at the stack, at the variables of each
+// environment enclosing a frame, and evaluate expressions.
+//
+// The script runs on a goroutine of its own, as the only thread the
+// adapter reports. The standard streams of the process carry the protocol,
+// so the script reads nothing, and what it writes is sent to the editor as
+// output events.
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/debugger"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// threadID identifies the script, the one thread there is.
+const threadID = 1
+
+type Server struct {
+	in *bufio.Reader
+
+	wmu sync.Mutex //guards out and seq
+	out io.Writer
+	seq int
+
+	// mu guards everything below, which both the goroutine serving
+	// requests and the one running the script use.
+	mu         sync.Mutex
+	path       string
+	program    *ast.Program
+	res        *resolver.Resolution
+	d          *debugger.Debugger
+	configured bool          //the editor sent configurationDone
+	running    bool          //the script has started
+	stopped    bool          //the script waits in the debugger for a resume
+	handles    []interface{} //what each variablesReference stands for, from 1
+	resume     chan error    //resumes the stopped script, or ends it with an error
+	cancel     context.CancelFunc
+	done       chan struct{} //closed when the script has finished
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, resume: make(chan error, 1)}
+}
+
+// Serve handles requests until the editor disconnects or closes the
+// input, and then ends the script if it is still running.
+func (s *Server) Serve() error {
+	defer s.terminate()
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Type != "request" {
+			continue
+		}
+		if msg.Command == "disconnect" {
+			s.terminate()
+			return s.respond(msg, nil, nil)
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers a request. The response is written before the script
+// resumes, so that it comes ahead of the events the script sends next.
+func (s *Server) handle(msg *message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, err := s.request(msg)
+	if err := s.respond(msg, body, err); err != nil {
+		return err
+	}
+	if err != nil {
+		return nil
+	}
+	switch msg.Command {
+	case "launch":
+		// the editor sends breakpoints once the program is known
+		if err := s.event("initialized", nil); err != nil {
+			return err
+		}
+	case "continue", "next", "stepIn", "stepOut":
+		s.stopped = false
+		s.handles = nil
+		s.resume <- nil
+	}
+	if s.program != nil && s.configured && !s.running {
+		s.start()
+	}
+	return nil
+}
+
+func (s *Server) respond(msg *message, body interface{}, err error) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.seq++
+	resp := &response{Seq: s.seq, Type: "response", RequestSeq: msg.Seq, Success: err == nil, Command: msg.Command, Body: body}
+	if err != nil {
+		resp.Message, resp.Body = err.Error(), nil
+	}
+	return writeMessage(s.out, resp)
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.seq++
+	return writeMessage(s.out, &event{Seq: s.seq, Type: "event", Event: name, Body: body})
+}
+
+// request handles a request, returning the body of its response or the
+// reason it failed.
+func (s *Server) request(msg *message) (interface{}, error) {
+	switch msg.Command {
+	case "initialize":
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsFunctionBreakpoints:      true,
+			SupportsEvaluateForHovers:        true,
+		}, nil
+	case "launch":
+		args := LaunchArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "threads":
+		return ThreadsResponse{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+	}
+	if s.d == nil {
+		return nil, errors.New("no program has been launched")
+	}
+	switch msg.Command {
+	case "setBreakpoints":
+		args := SetBreakpointsArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+	case "setFunctionBreakpoints":
+		args := SetFunctionBreakpointsArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		s.d.ClearFunctionBreakpoints()
+		resp := BreakpointsResponse{Breakpoints: []Breakpoint{}}
+		for _, bp := range args.Breakpoints {
+			s.d.SetFunctionBreakpoint(bp.Name)
+			resp.Breakpoints = append(resp.Breakpoints, Breakpoint{Verified: true})
+		}
+		return resp, nil
+	}
+	if !s.stopped {
+		return nil, errors.New("the program is not stopped")
+	}
+	switch msg.Command {
+	case "stackTrace":
+		args := StackTraceArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.stackTrace(args), nil
+	case "scopes":
+		args := ScopesArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args)
+	case "variables":
+		args := VariablesArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args)
+	case "evaluate":
+		args := EvaluateArguments{}
+		if err := decode(msg.Arguments, &args); err != nil {
+			return nil, err
+		}
+		frame := 0
+		if args.FrameID != nil {
+			frame = *args.FrameID
+		}
+		result, err := s.d.Evaluate(args.Expression, frame)
+		if err != nil {
+			return nil, err
+		}
+		return EvaluateResponse{Result: result.Inspect(), Type: object.TypeName(result), VariablesReference: s.reference(result)}, nil
+	case "continue":
+		s.d.Continue()
+		return ContinueResponse{AllThreadsContinued: true}, nil
+	case "next":
+		s.d.Next()
+		return nil, nil
+	case "stepIn":
+		s.d.StepIn()
+		return nil, nil
+	case "stepOut":
+		s.d.StepOut()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %s", msg.Command)
+}
+
+func decode(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("invalid arguments: %s", err)
+	}
+	return nil
+}
+
+// launch loads the script to debug. It runs once the editor is done
+// setting breakpoints.
+func (s *Server) launch(args LaunchArguments) error {
+	if s.program != nil {
+		return errors.New("a program has already been launched")
+	}
+	source, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	res := resolver.Resolve(program, evaluate.IsBuiltin)
+	msgs := []string{}
+	for _, d := range res.Diagnostics {
+		if d.Severity == resolver.Error {
+			msgs = append(msgs, d.String())
+		}
+	}
+	if len(msgs) != 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	s.path, s.program, s.res = args.Program, program, res
+	s.d = debugger.New(program)
+	s.d.SetStopOnEntry(args.StopOnEntry)
+	s.d.Stopped = s.stoppedAt
+	return nil
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) BreakpointsResponse {
+	resp := BreakpointsResponse{Breakpoints: []Breakpoint{}}
+	same := sameFile(args.Source.Path, s.path)
+	if same {
+		s.d.ClearBreakpoints()
+	}
+	for _, bp := range args.Breakpoints {
+		switch {
+		case !same:
+			resp.Breakpoints = append(resp.Breakpoints, Breakpoint{Line: bp.Line, Message: "not in the program being debugged"})
+		case s.d.SetBreakpoint(bp.Line):
+			resp.Breakpoints = append(resp.Breakpoints, Breakpoint{Verified: true, Line: bp.Line})
+		default:
+			resp.Breakpoints = append(resp.Breakpoints, Breakpoint{Line: bp.Line, Message: "no statement on this line"})
+		}
+	}
+	return resp
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// start runs the script on a goroutine of its own.
+func (s *Server) start() {
+	s.running = true
+	s.done = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	streams := &object.Streams{
+		Stdin:  strings.NewReader(""),
+		Stdout: &output{s: s, category: "stdout"},
+		Stderr: &output{s: s, category: "stderr"},
+	}
+	s.d.SetStreams(streams)
+	c := evaluate.NewContext(ctx, evaluate.Limits{})
+	c.SetStreams(streams)
+	c.SetModules(evaluate.NewModules(filepath.Dir(s.path)))
+	c.SetResolution(s.res)
+	c.SetHook(s.d)
+	go func() {
+		defer close(s.done)
+		result := c.Eval(s.program, object.NewEnvironment())
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		exitCode := 0
+		if errObj, ok := result.(*object.Error); ok {
+			exitCode = 1
+			if !errors.Is(c.Err(), debugger.ErrQuit) && !errors.Is(c.Err(), evaluate.ErrCanceled) {
+				s.event("output", OutputEvent{Category: "stderr", Output: errObj.Message + "\n"})
+			}
+		}
+		s.event("exited", ExitedEvent{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// stoppedAt is called by the debugger, on the goroutine running the
+// script, which waits for a request to resume it.
+func (s *Server) stoppedAt(reason string) error {
+	s.mu.Lock()
+	s.stopped = true
+	err := s.event("stopped", StoppedEvent{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return <-s.resume
+}
+
+// terminate ends the script, if it runs, and waits for it to finish.
+func (s *Server) terminate() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	if s.stopped {
+		s.stopped = false
+		s.resume <- debugger.ErrQuit
+	}
+	done := s.done
+	s.mu.Unlock()
+	<-done
+}
+
+// output sends what the script writes to a stream to the editor. It may
+// write while a request is being handled, when the expression of an
+// evaluate request prints.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.s.event("output", OutputEvent{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
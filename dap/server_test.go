@@ -0,0 +1,411 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const script = `let add = fn(a, b) {
+	let s = a + b;
+	s
+};
+let twice = fn(x) {
+	let y = add(x, x);
+	y
+};
+let list = [1, {"k": [2]}];
+let r = twice(3);
+print(r);
+r`
+
+// client drives an adapter running in the same process over a pair of
+// pipes, the way an editor would over stdio.
+type client struct {
+	t      *testing.T
+	in     chan *message //read as the adapter writes, which never waits for the test
+	out    io.WriteCloser
+	seq    int
+	events []*message
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: make(chan *message, 100), out: clientOut, done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	go func() {
+		r := bufio.NewReader(clientIn)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				close(c.in)
+				return
+			}
+			c.in <- msg
+		}
+	}()
+	c.call("initialize", map[string]interface{}{"adapterID": "interpreter"}, nil)
+	return c
+}
+
+// writeScript writes source to a file of its own and returns its path.
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mk")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func (c *client) read() *message {
+	c.t.Helper()
+	msg, ok := <-c.in
+	if !ok {
+		c.t.Fatal("the adapter closed its output")
+	}
+	return msg
+}
+
+// request sends a request and returns its response. Events that arrive
+// first are kept for later.
+func (c *client) request(command string, args interface{}) *message {
+	c.t.Helper()
+	raw, err := json.Marshal(args)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.seq++
+	if err := writeMessage(c.out, &message{Seq: c.seq, Type: "request", Command: command, Arguments: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.Type != "response" || msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("expected the response to %s %d, got %+v", command, c.seq, msg)
+		}
+		return msg
+	}
+}
+
+// call sends a request that must succeed and decodes the body of its
+// response into body.
+func (c *client) call(command string, args, body interface{}) {
+	c.t.Helper()
+	msg := c.request(command, args)
+	if !msg.Success {
+		c.t.Fatalf("%s failed: %s", command, msg.Message)
+	}
+	if body != nil {
+		if err := json.Unmarshal(msg.Body, body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// fail sends a request that must fail and returns its error message.
+func (c *client) fail(command string, args interface{}) string {
+	c.t.Helper()
+	msg := c.request(command, args)
+	if msg.Success {
+		c.t.Fatalf("expected %s to fail", command)
+	}
+	return msg.Message
+}
+
+// event returns the first event called name, waiting for it if need be.
+// The events before it are kept for later.
+func (c *client) event(name string, body interface{}) {
+	c.t.Helper()
+	for i := 0; ; i++ {
+		if i == len(c.events) {
+			c.events = append(c.events, c.read())
+		}
+		msg := c.events[i]
+		if msg.Type != "event" || msg.Event != name {
+			continue
+		}
+		c.events = append(c.events[:i], c.events[i+1:]...)
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+// output returns what the script wrote to category so far.
+func (c *client) output(category string) string {
+	out := ""
+	rest := []*message{}
+	for _, msg := range c.events {
+		body := OutputEvent{}
+		if msg.Event != "output" || json.Unmarshal(msg.Body, &body) != nil || body.Category != category {
+			rest = append(rest, msg)
+			continue
+		}
+		out += body.Output
+	}
+	c.events = rest
+	return out
+}
+
+// stopped waits for the script to stop and returns why and where.
+func (c *client) stopped() (string, []StackFrame) {
+	c.t.Helper()
+	event := StoppedEvent{}
+	c.event("stopped", &event)
+	if event.ThreadID != threadID {
+		c.t.Errorf("stopped on thread %d", event.ThreadID)
+	}
+	trace := StackTraceResponse{}
+	c.call("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+	return event.Reason, trace.StackFrames
+}
+
+func (c *client) disconnect() {
+	c.t.Helper()
+	c.call("disconnect", map[string]interface{}{}, nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// frames describes a stack as name:line pairs, innermost first.
+func frames(stack []StackFrame) string {
+	out := []string{}
+	for _, frame := range stack {
+		out = append(out, frame.Name+":"+strconv.Itoa(frame.Line))
+	}
+	return strings.Join(out, " ")
+}
+
+// variables describes what a reference stands for as name=value pairs.
+func (c *client) variables(ref int) (string, map[string]int) {
+	c.t.Helper()
+	resp := VariablesResponse{}
+	c.call("variables", VariablesArguments{VariablesReference: ref}, &resp)
+	out := []string{}
+	refs := map[string]int{}
+	for _, v := range resp.Variables {
+		out = append(out, v.Name+"="+v.Value)
+		refs[v.Name] = v.VariablesReference
+	}
+	return strings.Join(out, " "), refs
+}
+
+func (c *client) scopes(frame int) map[string]int {
+	c.t.Helper()
+	resp := ScopesResponse{}
+	c.call("scopes", ScopesArguments{FrameID: frame}, &resp)
+	refs := map[string]int{}
+	for _, scope := range resp.Scopes {
+		refs[scope.Name] = scope.VariablesReference
+	}
+	return refs
+}
+
+func TestSession(t *testing.T) {
+	path := writeScript(t, script)
+	c := newClient(t)
+	c.call("launch", LaunchArguments{Program: path, StopOnEntry: true}, nil)
+	c.event("initialized", nil)
+
+	breakpoints := BreakpointsResponse{}
+	c.call("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: path},
+		Breakpoints: []SourceBreakpoint{{Line: 2}, {Line: 4}},
+	}, &breakpoints)
+	if len(breakpoints.Breakpoints) != 2 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[1].Verified {
+		t.Errorf("expected line 2 to be verified and line 4 not, got %+v", breakpoints.Breakpoints)
+	}
+	c.call("setFunctionBreakpoints", SetFunctionBreakpointsArguments{Breakpoints: []FunctionBreakpoint{{Name: "twice"}}}, nil)
+	c.call("configurationDone", nil, nil)
+
+	reason, stack := c.stopped()
+	if reason != "entry" || frames(stack) != "<main>:1" {
+		t.Fatalf("expected to stop on entry, got %s at %s", reason, frames(stack))
+	}
+	if stack[0].Source == nil || stack[0].Source.Path != path {
+		t.Errorf("wrong source %+v", stack[0].Source)
+	}
+	threads := ThreadsResponse{}
+	c.call("threads", nil, &threads)
+	if len(threads.Threads) != 1 || threads.Threads[0].ID != threadID {
+		t.Errorf("expected a single thread, got %+v", threads.Threads)
+	}
+
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	reason, stack = c.stopped()
+	if reason != "function breakpoint" || frames(stack) != "twice:6 <main>:10" {
+		t.Fatalf("expected to stop in twice, got %s at %s", reason, frames(stack))
+	}
+	scopes := c.scopes(0)
+	if locals, _ := c.variables(scopes["Locals"]); locals != "x=3" {
+		t.Errorf("wrong locals %s", locals)
+	}
+	globals, refs := c.variables(scopes["Globals"])
+	if !strings.Contains(globals, `list=[1,{k:[2]}]`) || !strings.HasPrefix(globals, "add=fn(a,b)") {
+		t.Errorf("wrong globals %s", globals)
+	}
+	list, refs := c.variables(refs["list"])
+	if list != "[0]=1 [1]={k:[2]}" {
+		t.Errorf("wrong elements %s", list)
+	}
+	if hash, _ := c.variables(refs["[1]"]); hash != "k=[2]" {
+		t.Errorf("wrong pairs %s", hash)
+	}
+
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	reason, stack = c.stopped()
+	if reason != "breakpoint" || frames(stack) != "add:2 twice:6 <main>:10" {
+		t.Fatalf("expected to stop in add, got %s at %s", reason, frames(stack))
+	}
+	evaluations := []struct {
+		expression string
+		frame      int
+		expected   string
+	}{
+		{"a + b", 0, "6"},
+		{"x", 1, "3"},
+		{"print(\"hi\")", 0, "null"},
+	}
+	for _, tt := range evaluations {
+		frame := tt.frame
+		result := EvaluateResponse{}
+		c.call("evaluate", EvaluateArguments{Expression: tt.expression, FrameID: &frame, Context: "repl"}, &result)
+		if result.Result != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, result.Result)
+		}
+	}
+	if out := c.output("stdout"); out != "hi\n" {
+		t.Errorf("expected the evaluation to print hi, got %q", out)
+	}
+	if msg := c.fail("evaluate", EvaluateArguments{Expression: "y", Context: "watch"}); msg != "identifier not found y" {
+		t.Errorf("wrong error %q", msg)
+	}
+
+	c.call("next", map[string]int{"threadId": threadID}, nil)
+	reason, stack = c.stopped()
+	if reason != "step" || frames(stack) != "add:3 twice:6 <main>:10" {
+		t.Fatalf("expected to step to line 3, got %s at %s", reason, frames(stack))
+	}
+	if locals, _ := c.variables(c.scopes(0)["Locals"]); locals != "a=3 b=3 s=6" {
+		t.Errorf("wrong locals %s", locals)
+	}
+	c.call("stepOut", map[string]int{"threadId": threadID}, nil)
+	reason, stack = c.stopped()
+	if reason != "step" || frames(stack) != "twice:7 <main>:10" {
+		t.Fatalf("expected to step out to line 7, got %s at %s", reason, frames(stack))
+	}
+	c.call("stepOut", map[string]int{"threadId": threadID}, nil)
+	if _, stack = c.stopped(); frames(stack) != "<main>:11" {
+		t.Fatalf("expected to step out to line 11, got %s", frames(stack))
+	}
+	c.call("stepIn", map[string]int{"threadId": threadID}, nil)
+	if _, stack = c.stopped(); frames(stack) != "<main>:12" {
+		t.Fatalf("expected to step over the builtin to line 12, got %s", frames(stack))
+	}
+
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	exited := ExitedEvent{ExitCode: -1}
+	c.event("exited", &exited)
+	c.event("terminated", nil)
+	if exited.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", exited.ExitCode)
+	}
+	if out := c.output("stdout"); out != "6\n" {
+		t.Errorf("expected the script to print 6, got %q", out)
+	}
+	c.disconnect()
+}
+
+func TestRuntimeError(t *testing.T) {
+	path := writeScript(t, "let x = 1;\nprint(x);\nx + true")
+	c := newClient(t)
+	c.call("launch", LaunchArguments{Program: path}, nil)
+	c.call("configurationDone", nil, nil)
+	exited := ExitedEvent{}
+	c.event("exited", &exited)
+	if exited.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", exited.ExitCode)
+	}
+	if out := c.output("stdout"); out != "1\n" {
+		t.Errorf("wrong output %q", out)
+	}
+	if out := c.output("stderr"); out != "Operands are not of the same type : INTEGER + BOOLEAN\n" {
+		t.Errorf("wrong error output %q", out)
+	}
+	c.disconnect()
+}
+
+func TestDisconnectWhileStopped(t *testing.T) {
+	path := writeScript(t, script)
+	c := newClient(t)
+	c.call("launch", LaunchArguments{Program: path}, nil)
+	c.call("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 11}}}, nil)
+	c.call("configurationDone", nil, nil)
+	if reason, stack := c.stopped(); reason != "breakpoint" || frames(stack) != "<main>:11" {
+		t.Fatalf("expected to stop at line 11, got %s at %s", reason, frames(stack))
+	}
+	c.disconnect()
+	if out := c.output("stdout"); out != "" {
+		t.Errorf("expected the script to end before printing, got %q", out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	path := writeScript(t, "let x = ;")
+	c := newClient(t)
+	tests := []struct {
+		command  string
+		args     interface{}
+		expected string
+	}{
+		{"setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}}, "no program has been launched"},
+		{"stackTrace", StackTraceArguments{ThreadID: threadID}, "no program has been launched"},
+		{"launch", LaunchArguments{Program: filepath.Join(filepath.Dir(path), "missing.mk")}, "no such file"},
+		{"launch", LaunchArguments{Program: path}, "no prefix func found for ;"},
+		{"launch", LaunchArguments{Program: writeScript(t, "let f = fn() { y };")}, "y"},
+		{"launch", "not an object", "invalid arguments"},
+		{"pause", nil, "no program has been launched"},
+	}
+	for _, tt := range tests {
+		if msg := c.fail(tt.command, tt.args); !strings.Contains(msg, tt.expected) {
+			t.Errorf("%s: expected an error containing %q, got %q", tt.command, tt.expected, msg)
+		}
+	}
+
+	good := writeScript(t, "let x = 1;\nx")
+	c.call("launch", LaunchArguments{Program: good}, nil)
+	tests = []struct {
+		command  string
+		args     interface{}
+		expected string
+	}{
+		{"stackTrace", StackTraceArguments{ThreadID: threadID}, "not stopped"},
+		{"launch", LaunchArguments{Program: good}, "already been launched"},
+		{"pause", nil, "not stopped"},
+	}
+	for _, tt := range tests {
+		if msg := c.fail(tt.command, tt.args); !strings.Contains(msg, tt.expected) {
+			t.Errorf("%s: expected an error containing %q, got %q", tt.command, tt.expected, msg)
+		}
+	}
+	c.disconnect()
+}
@@ -0,0 +1,150 @@
+package dap
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// scope is the environments a scope of a frame is made of, innermost
+// first. A name bound in several of them shows its innermost binding.
+type scope []*object.Environment
+
+// stackTrace describes the calls in progress, innermost first. A frame is
+// identified by its index in the stack, which holds until the script
+// resumes.
+func (s *Server) stackTrace(args StackTraceArguments) StackTraceResponse {
+	stack := s.d.Stack()
+	source := &Source{Name: filepath.Base(s.path), Path: s.path}
+	frames := []StackFrame{}
+	for i := args.StartFrame; i < len(stack); i++ {
+		if args.Levels > 0 && len(frames) == args.Levels {
+			break
+		}
+		frames = append(frames, StackFrame{ID: i, Name: stack[i].Name, Source: source, Line: stack[i].Line, Column: 1})
+	}
+	return StackTraceResponse{StackFrames: frames, TotalFrames: len(stack)}
+}
+
+// scopes splits the environments enclosing a frame in three: those of the
+// call itself, with the parameters and the blocks nested in it, those the
+// function closes over, and the globals.
+func (s *Server) scopes(args ScopesArguments) (ScopesResponse, error) {
+	stack := s.d.Stack()
+	if args.FrameID < 0 || args.FrameID >= len(stack) {
+		return ScopesResponse{}, fmt.Errorf("no frame %d", args.FrameID)
+	}
+	frame := stack[args.FrameID]
+	locals, closed := scope{}, scope{}
+	inCall := true
+	env := frame.Env
+	for ; env != nil && env.Outer() != nil; env = env.Outer() {
+		if frame.Function != nil && env == frame.Function.Env {
+			inCall = false
+		}
+		if inCall {
+			locals = append(locals, env)
+		} else {
+			closed = append(closed, env)
+		}
+	}
+	scopes := []Scope{}
+	if frame.Function != nil || len(locals) != 0 {
+		scopes = append(scopes, Scope{Name: "Locals", VariablesReference: s.reference(locals)})
+	}
+	if len(closed) != 0 {
+		scopes = append(scopes, Scope{Name: "Closure", VariablesReference: s.reference(closed)})
+	}
+	if env != nil {
+		scopes = append(scopes, Scope{Name: "Globals", VariablesReference: s.reference(scope{env})})
+	}
+	return ScopesResponse{Scopes: scopes}, nil
+}
+
+// variables lists what a reference stands for: the names of a scope, or
+// the elements, pairs or fields of a value.
+func (s *Server) variables(args VariablesArguments) (VariablesResponse, error) {
+	ref := args.VariablesReference
+	if ref < 1 || ref > len(s.handles) {
+		return VariablesResponse{}, fmt.Errorf("no variables for reference %d", ref)
+	}
+	vars := []Variable{}
+	add := func(name string, value object.Object) {
+		vars = append(vars, Variable{Name: name, Value: value.Inspect(), Type: object.TypeName(value), VariablesReference: s.reference(value)})
+	}
+	switch handle := s.handles[ref-1].(type) {
+	case scope:
+		seen := map[string]bool{}
+		names := []string{}
+		for _, env := range handle {
+			for _, name := range env.Names() {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, _ := handle[0].Get(name)
+			add(name, value)
+		}
+	case *object.Array:
+		for i, element := range handle.Elements {
+			add(fmt.Sprintf("[%d]", i), element)
+		}
+	case *object.Hash:
+		pairs := []object.HashPair{}
+		for _, pair := range handle.Pairs {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key.Inspect() < pairs[j].Key.Inspect() })
+		for _, pair := range pairs {
+			add(pair.Key.Inspect(), pair.Value)
+		}
+	case *object.Instance:
+		for i, value := range handle.Values() {
+			add(handle.Struct.Fields[i], value)
+		}
+	case *object.Module:
+		names := []string{}
+		for name := range handle.Exports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(name, handle.Exports[name])
+		}
+	}
+	return VariablesResponse{Variables: vars}, nil
+}
+
+// reference returns the variablesReference of a scope, or of a value with
+// parts to expand, and 0 for a value without.
+func (s *Server) reference(v interface{}) int {
+	switch v := v.(type) {
+	case *object.Array:
+		if len(v.Elements) == 0 {
+			return 0
+		}
+	case *object.Hash:
+		if len(v.Pairs) == 0 {
+			return 0
+		}
+	case *object.Instance:
+		if len(v.Struct.Fields) == 0 {
+			return 0
+		}
+	case *object.Module:
+		if len(v.Exports) == 0 {
+			return 0
+		}
+	case scope:
+	default:
+		return 0
+	}
+	s.handles = append(s.handles, v)
+	return len(s.handles)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nishokbanand/interpreter/debugger"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// debugCommand runs a script under the terminal debugger, which stops
+// before its first statement and then takes commands from in.
+func debugCommand(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: interpreter debug file.mk")
+	}
+	path := fs.Arg(0)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	program, err := parseSource(string(source))
+	if err != nil {
+		return err
+	}
+	res, err := check(program, nil)
+	if err != nil {
+		return err
+	}
+	d := debugger.New(program)
+	debugger.AttachTerminal(d, string(source), in, out)
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	c.SetModules(evaluate.NewModules(filepath.Dir(path)))
+	c.SetResolution(res)
+	c.SetHook(d)
+	result := c.Eval(program, object.NewEnvironment())
+	if errors.Is(c.Err(), debugger.ErrQuit) {
+		return nil
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+	fmt.Fprintln(out, "program finished")
+	if result != nil && result != object.Null {
+		fmt.Fprintln(out, result.Inspect())
+	}
+	return nil
+}
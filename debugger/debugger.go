@@ -0,0 +1,310 @@
+// Package debugger stops a running evaluation at breakpoints and steps
+// through it statement by statement. A Debugger is attached to an
+// evaluation as its evaluate.Hook and keeps the stack of calls in
+// progress; whenever it stops, it hands control to a front end, such as
+// the terminal one in this package, until the front end resumes it.
+//
+// The debugger stops before statements only, and at most once per line of
+// each call: a line holding several statements is stepped over in one go.
+package debugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// ErrQuit stops the evaluation when the user quits the debugger.
+var ErrQuit = errors.New("debugger: quit")
+
+// Reasons for stopping.
+const (
+	ReasonEntry              = "entry"
+	ReasonBreakpoint         = "breakpoint"
+	ReasonFunctionBreakpoint = "function breakpoint"
+	ReasonStep               = "step"
+)
+
+// Frame is a call in progress, or the program itself at the bottom of the
+// stack.
+type Frame struct {
+	Name string
+	Line int //of the statement being run
+	// Env is the environment of the statement being run, which may be one
+	// nested in that of the call, such as that of a match arm.
+	Env      *object.Environment
+	Function *object.Function //nil for the program
+}
+
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepIn
+	modeNext
+	modeStepOut
+)
+
+// Debugger is an evaluate.Hook that stops the evaluation when it reaches
+// a breakpoint or the end of a step, and calls Stopped.
+type Debugger struct {
+	// Stopped is called when the evaluation stops, and the evaluation
+	// waits for it to return. It resumes the evaluation by calling one of
+	// Continue, Next, StepIn and StepOut before returning, and stops it by
+	// returning an error. Until it returns, another goroutine it waits for
+	// may use the debugger in its place.
+	Stopped func(reason string) error
+
+	names     map[*ast.BlockStatement]string
+	streams   *object.Streams //for Evaluate, nil for those of the process
+	stmtLines map[int]bool    //lines a statement starts on
+
+	// Breakpoints may change while the evaluation runs, from another
+	// goroutine, so mu guards them.
+	mu        sync.Mutex
+	lines     map[int]bool
+	functions map[string]bool
+
+	frames  []*Frame
+	mode    mode
+	depth   int  //number of frames when the step began
+	entry   bool //stop before the first statement
+	started bool //the evaluation has stopped at least once
+	entered bool //a function with a breakpoint was just called
+}
+
+// New returns a debugger for program that stops before its first
+// statement.
+func New(program *ast.Program) *Debugger {
+	stmtLines := map[int]bool{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if _, ok := node.(ast.StatmentNode); ok {
+			stmtLines[ast.Start(node).Line] = true
+		}
+		return true
+	})
+	return &Debugger{
+		names:     ast.FunctionNames(program),
+		lines:     map[int]bool{},
+		stmtLines: stmtLines,
+		functions: map[string]bool{},
+		frames:    []*Frame{{Name: "<main>"}},
+		entry:     true,
+	}
+}
+
+// SetStopOnEntry sets whether the evaluation stops before its first
+// statement, as it does by default.
+func (d *Debugger) SetStopOnEntry(stop bool) { d.entry = stop }
+
+// SetBreakpoint stops the evaluation before the statements on line. It
+// reports false, and sets nothing, if no statement starts on line.
+func (d *Debugger) SetBreakpoint(line int) bool {
+	if !d.stmtLines[line] {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lines[line] = true
+	return true
+}
+
+// SetFunctionBreakpoint stops the evaluation before the first statement of
+// every call of the function called name: a variable bound to a function
+// literal, or a method named like Point.norm.
+func (d *Debugger) SetFunctionBreakpoint(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.functions[name] = true
+}
+
+// ClearBreakpoint removes the breakpoint on line and reports whether
+// there was one.
+func (d *Debugger) ClearBreakpoint(line int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ok := d.lines[line]
+	delete(d.lines, line)
+	return ok
+}
+
+// ClearFunctionBreakpoint removes the breakpoint on the function called
+// name and reports whether there was one.
+func (d *Debugger) ClearFunctionBreakpoint(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ok := d.functions[name]
+	delete(d.functions, name)
+	return ok
+}
+
+// ClearBreakpoints removes every breakpoint on a line.
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lines = map[int]bool{}
+}
+
+// ClearFunctionBreakpoints removes every breakpoint on a function.
+func (d *Debugger) ClearFunctionBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.functions = map[string]bool{}
+}
+
+// Breakpoints describes the breakpoints, lines first, in order.
+func (d *Debugger) Breakpoints() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := []int{}
+	for line := range d.lines {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	out := []string{}
+	for _, line := range lines {
+		out = append(out, fmt.Sprintf("line %d", line))
+	}
+	functions := []string{}
+	for name := range d.functions {
+		functions = append(functions, "function "+name)
+	}
+	sort.Strings(functions)
+	return append(out, functions...)
+}
+
+// SetStreams makes the expressions given to Evaluate read and write
+// through streams rather than the standard ones of the process.
+func (d *Debugger) SetStreams(streams *object.Streams) { d.streams = streams }
+
+// Continue resumes the evaluation until the next breakpoint.
+func (d *Debugger) Continue() { d.mode = modeContinue }
+
+// Next resumes the evaluation until the next line of the current call,
+// stepping over the calls on this one.
+func (d *Debugger) Next() { d.resume(modeNext) }
+
+// StepIn resumes the evaluation until the next line run, in whichever call
+// it is.
+func (d *Debugger) StepIn() { d.resume(modeStepIn) }
+
+// StepOut resumes the evaluation until the current call returns.
+func (d *Debugger) StepOut() { d.resume(modeStepOut) }
+
+func (d *Debugger) resume(m mode) {
+	d.mode = m
+	d.depth = len(d.frames)
+}
+
+// Stack returns the calls in progress, innermost first. The evaluation
+// must be stopped.
+func (d *Debugger) Stack() []*Frame {
+	stack := make([]*Frame, len(d.frames))
+	for i, frame := range d.frames {
+		stack[len(d.frames)-1-i] = frame
+	}
+	return stack
+}
+
+// Evaluate evaluates an expression, or several statements, in the
+// environment of frame, the index of a frame in Stack. Assignments change
+// the variables of the stopped program.
+func (d *Debugger) Evaluate(source string, frame int) (object.Object, error) {
+	stack := d.Stack()
+	if frame < 0 || frame >= len(stack) {
+		return nil, fmt.Errorf("no frame %d", frame)
+	}
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	env := stack[frame].Env
+	if env == nil {
+		return nil, errors.New("the program has not started")
+	}
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	if d.streams != nil {
+		c.SetStreams(d.streams)
+	}
+	result := c.Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+	if result == nil {
+		result = evaluate.NULL
+	}
+	return result, nil
+}
+
+// FunctionName names the function fn as Stack does.
+func (d *Debugger) FunctionName(fn *object.Function) string {
+	if name, ok := d.names[fn.Body]; ok {
+		return name
+	}
+	return fmt.Sprintf("fn@%d", fn.Body.Token.Line)
+}
+
+func (d *Debugger) Node(node ast.Node, pos resolver.Position, env *object.Environment) error {
+	if _, ok := node.(ast.StatmentNode); !ok {
+		return nil
+	}
+	frame := d.frames[len(d.frames)-1]
+	frame.Env = env
+	newLine := pos.Line != frame.Line
+	frame.Line = pos.Line
+	d.mu.Lock()
+	breakpoint := newLine && d.lines[pos.Line]
+	d.mu.Unlock()
+	reason := ""
+	switch {
+	case !d.started && d.entry:
+		reason = ReasonEntry
+	case d.entered:
+		reason = ReasonFunctionBreakpoint
+	case breakpoint:
+		reason = ReasonBreakpoint
+	case !newLine:
+	case d.mode == modeStepIn,
+		d.mode == modeNext && len(d.frames) <= d.depth,
+		d.mode == modeStepOut && len(d.frames) < d.depth:
+		reason = ReasonStep
+	}
+	if reason == "" {
+		return nil
+	}
+	d.started, d.entered = true, false
+	d.mode = modeContinue
+	if d.Stopped == nil {
+		return nil
+	}
+	return d.Stopped(reason)
+}
+
+func (d *Debugger) Call(fn object.Object, args []object.Object, env *object.Environment) {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return
+	}
+	name := d.FunctionName(function)
+	d.frames = append(d.frames, &Frame{Name: name, Env: env, Function: function})
+	d.mu.Lock()
+	d.entered = d.entered || d.functions[name]
+	d.mu.Unlock()
+}
+
+func (d *Debugger) Return(fn object.Object, result object.Object) {
+	if _, ok := fn.(*object.Function); ok && len(d.frames) > 1 {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
@@ -0,0 +1,223 @@
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+const script = `let add = fn(a, b) {
+	let s = a + b;
+	s
+};
+let twice = fn(x) {
+	let y = add(x, x);
+	y
+};
+let r = twice(3);
+print(r);
+r`
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// run evaluates program with d attached, writing its output to out.
+func run(t *testing.T, d *Debugger, program *ast.Program, out *bytes.Buffer) (object.Object, *evaluate.Context) {
+	t.Helper()
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	c.SetStreams(&object.Streams{Stdout: out})
+	c.SetHook(d)
+	return c.Eval(program, object.NewEnvironment()), c
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(d *Debugger)
+		resume  []func(d *Debugger)
+		expects []string
+	}{
+		{
+			"next steps over calls",
+			nil,
+			[]func(d *Debugger){(*Debugger).Next, (*Debugger).Next, (*Debugger).Next, (*Debugger).Next, (*Debugger).Next},
+			[]string{"entry 1 <main>", "step 5 <main>", "step 9 <main>", "step 10 <main>", "step 11 <main>"},
+		},
+		{
+			"step goes into calls and out of them",
+			nil,
+			[]func(d *Debugger){
+				(*Debugger).Next, (*Debugger).Next, (*Debugger).StepIn, (*Debugger).StepIn,
+				(*Debugger).StepIn, (*Debugger).StepIn, (*Debugger).StepIn, (*Debugger).Continue,
+			},
+			[]string{"entry 1 <main>", "step 5 <main>", "step 9 <main>", "step 6 twice", "step 2 add", "step 3 add", "step 7 twice", "step 10 <main>"},
+		},
+		{
+			"out returns to the caller",
+			func(d *Debugger) { d.SetBreakpoint(2) },
+			[]func(d *Debugger){(*Debugger).Continue, (*Debugger).StepOut, (*Debugger).StepOut, (*Debugger).Continue},
+			[]string{"entry 1 <main>", "breakpoint 2 add", "step 7 twice", "step 10 <main>"},
+		},
+		{
+			"function breakpoints",
+			func(d *Debugger) { d.SetFunctionBreakpoint("add"); d.SetBreakpoint(10) },
+			[]func(d *Debugger){(*Debugger).Continue, (*Debugger).Continue, (*Debugger).Continue},
+			[]string{"entry 1 <main>", "function breakpoint 2 add", "breakpoint 10 <main>"},
+		},
+		{
+			"breakpoints without stopping on entry",
+			func(d *Debugger) { d.SetStopOnEntry(false); d.SetBreakpoint(1); d.SetBreakpoint(6) },
+			[]func(d *Debugger){(*Debugger).Continue, (*Debugger).Next, (*Debugger).Continue},
+			[]string{"breakpoint 1 <main>", "breakpoint 6 twice", "step 7 twice"},
+		},
+	}
+	program := parse(t, script)
+	for _, tt := range tests {
+		d := New(program)
+		if tt.setup != nil {
+			tt.setup(d)
+		}
+		stops := []string{}
+		d.Stopped = func(reason string) error {
+			frame := d.Stack()[0]
+			stops = append(stops, fmt.Sprintf("%s %d %s", reason, frame.Line, frame.Name))
+			if len(stops) > len(tt.resume) {
+				return errors.New("too many stops")
+			}
+			tt.resume[len(stops)-1](d)
+			return nil
+		}
+		out := &bytes.Buffer{}
+		result, _ := run(t, d, program, out)
+		if got := strings.Join(stops, ", "); got != strings.Join(tt.expects, ", ") {
+			t.Errorf("%s: expected stops\n%s\ngot\n%s", tt.name, strings.Join(tt.expects, ", "), got)
+		}
+		if result == nil || result.Inspect() != "6" || out.String() != "6\n" {
+			t.Errorf("%s: the program did not run to the end: %v, output %q", tt.name, result, out.String())
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	program := parse(t, script)
+	d := New(program)
+	d.SetBreakpoint(3)
+	checked := false
+	d.Stopped = func(reason string) error {
+		defer d.Continue()
+		if reason != ReasonBreakpoint {
+			return nil
+		}
+		checked = true
+		stack := d.Stack()
+		names := []string{}
+		for _, frame := range stack {
+			names = append(names, frame.Name)
+		}
+		if got := strings.Join(names, " "); got != "add twice <main>" {
+			t.Errorf("wrong stack %s", got)
+		}
+		tests := []struct {
+			expression string
+			frame      int
+			expected   string
+		}{
+			{"s", 0, "6"},
+			{"a * b", 0, "9"},
+			{"x", 1, "3"},
+			{"add(1, 2)", 2, "3"},
+			{"s = 10; s", 0, "10"},
+		}
+		for _, tt := range tests {
+			result, err := d.Evaluate(tt.expression, tt.frame)
+			if err != nil || result.Inspect() != tt.expected {
+				t.Errorf("%s in frame %d: expected %s, got %v, %v", tt.expression, tt.frame, tt.expected, result, err)
+			}
+		}
+		if _, err := d.Evaluate("y", 1); err == nil || err.Error() != "identifier not found y" {
+			t.Errorf("expected y to be undefined until the call returns, got %v", err)
+		}
+		if _, err := d.Evaluate("s", 3); err == nil {
+			t.Errorf("expected an error for a frame that does not exist")
+		}
+		return nil
+	}
+	result, _ := run(t, d, program, &bytes.Buffer{})
+	if !checked {
+		t.Fatal("the breakpoint was not reached")
+	}
+	// the assignment made from the debugger is returned by add
+	if result.Inspect() != "10" {
+		t.Errorf("expected 10, got %s", result.Inspect())
+	}
+}
+
+func TestQuit(t *testing.T) {
+	program := parse(t, script)
+	d := New(program)
+	d.Stopped = func(reason string) error { return ErrQuit }
+	out := &bytes.Buffer{}
+	_, c := run(t, d, program, out)
+	if !errors.Is(c.Err(), ErrQuit) || out.Len() != 0 {
+		t.Errorf("expected the program to stop before printing, got %v and %q", c.Err(), out.String())
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	program := parse(t, script)
+	d := New(program)
+	commands := "break add\nbreak 10\nbreak 4\nbreakpoints\nc\nbt\np a + b\nn\n\nf 1\np x\nl\nclear add\nclear 4\nout\nbogus\nc\n"
+	out := &bytes.Buffer{}
+	AttachTerminal(d, script, strings.NewReader(commands), out)
+	run(t, d, program, out)
+	expected := `stopped at line 1 in <main> (entry)
+>   1  let add = fn(a, b) {
+(debug) breakpoint at function add
+(debug) breakpoint at line 10
+(debug) no statement on line 4
+(debug) line 10
+function add
+(debug) stopped at line 2 in add (function breakpoint)
+>   2  	let s = a + b;
+(debug) *#0 add at line 2
+ #1 twice at line 6
+ #2 <main> at line 9
+(debug) 6
+(debug) stopped at line 3 in add (step)
+>   3  	s
+(debug) stopped at line 7 in twice (step)
+>   7  	y
+(debug) #1 <main> at line 9
+(debug) error: identifier not found x
+(debug)     6  	let y = add(x, x);
+    7  	y
+    8  };
+>   9  let r = twice(3);
+   10  print(r);
+   11  r
+(debug) (debug) no breakpoint at 4
+(debug) stopped at line 10 in <main> (breakpoint)
+>  10  print(r);
+(debug) unknown command bogus, try help
+(debug) 6
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,159 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const help = `break LINE|NAME  (b)   stop at a line, or in a function such as add or Point.norm
+clear LINE|NAME        remove a breakpoint
+breakpoints            list the breakpoints
+continue         (c)   run until the next breakpoint
+next             (n)   run to the next line, stepping over calls
+step             (s)   run to the next line, stepping into calls
+out              (o)   run until the current call returns
+print EXPR       (p)   evaluate an expression in the selected frame
+stack            (bt)  show the calls in progress
+frame N          (f)   select the frame print evaluates in
+list             (l)   show the source around the current line
+quit             (q)   stop the program
+An empty line repeats the last command.`
+
+// terminal reads the commands of a debugging session from in.
+type terminal struct {
+	d       *Debugger
+	lines   []string
+	in      *bufio.Scanner
+	out     io.Writer
+	last    string //the last command, run again by an empty line
+	current int    //the frame print evaluates in
+}
+
+// AttachTerminal drives d with commands read from in, one per line, and
+// writes what they show to out. source is the text of the program, for
+// listing the lines the debugger stops at.
+func AttachTerminal(d *Debugger, source string, in io.Reader, out io.Writer) {
+	t := &terminal{d: d, lines: strings.Split(source, "\n"), in: bufio.NewScanner(in), out: out}
+	d.Stopped = t.stopped
+}
+
+func (t *terminal) stopped(reason string) error {
+	t.current = 0
+	frame := t.d.Stack()[0]
+	fmt.Fprintf(t.out, "stopped at line %d in %s (%s)\n", frame.Line, frame.Name, reason)
+	t.list(frame.Line, 0)
+	for {
+		fmt.Fprint(t.out, "(debug) ")
+		if !t.in.Scan() {
+			fmt.Fprintln(t.out)
+			return ErrQuit
+		}
+		line := strings.TrimSpace(t.in.Text())
+		if line == "" {
+			line = t.last
+		}
+		t.last = line
+		resumed, err := t.command(line)
+		if err != nil || resumed {
+			return err
+		}
+	}
+}
+
+// command runs one command and reports whether it resumed the evaluation.
+func (t *terminal) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "":
+	case "break", "b":
+		if arg == "" {
+			fmt.Fprintln(t.out, "usage: break LINE|NAME")
+		} else if n, err := strconv.Atoi(arg); err == nil {
+			if t.d.SetBreakpoint(n) {
+				fmt.Fprintf(t.out, "breakpoint at line %d\n", n)
+			} else {
+				fmt.Fprintf(t.out, "no statement on line %d\n", n)
+			}
+		} else {
+			t.d.SetFunctionBreakpoint(arg)
+			fmt.Fprintf(t.out, "breakpoint at function %s\n", arg)
+		}
+	case "clear":
+		cleared := false
+		if n, err := strconv.Atoi(arg); err == nil {
+			cleared = t.d.ClearBreakpoint(n)
+		} else {
+			cleared = t.d.ClearFunctionBreakpoint(arg)
+		}
+		if !cleared {
+			fmt.Fprintf(t.out, "no breakpoint at %s\n", arg)
+		}
+	case "breakpoints":
+		for _, bp := range t.d.Breakpoints() {
+			fmt.Fprintln(t.out, bp)
+		}
+	case "continue", "c":
+		t.d.Continue()
+		return true, nil
+	case "next", "n":
+		t.d.Next()
+		return true, nil
+	case "step", "s":
+		t.d.StepIn()
+		return true, nil
+	case "out", "o":
+		t.d.StepOut()
+		return true, nil
+	case "print", "p":
+		result, err := t.d.Evaluate(arg, t.current)
+		if err != nil {
+			fmt.Fprintf(t.out, "error: %s\n", err)
+		} else {
+			fmt.Fprintln(t.out, result.Inspect())
+		}
+	case "stack", "bt":
+		for i, frame := range t.d.Stack() {
+			marker := " "
+			if i == t.current {
+				marker = "*"
+			}
+			fmt.Fprintf(t.out, "%s#%d %s at line %d\n", marker, i, frame.Name, frame.Line)
+		}
+	case "frame", "f":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n >= len(t.d.Stack()) {
+			fmt.Fprintf(t.out, "no frame %s\n", arg)
+			break
+		}
+		t.current = n
+		frame := t.d.Stack()[n]
+		fmt.Fprintf(t.out, "#%d %s at line %d\n", n, frame.Name, frame.Line)
+	case "list", "l":
+		t.list(t.d.Stack()[t.current].Line, 3)
+	case "quit", "q":
+		return false, ErrQuit
+	case "help", "h":
+		fmt.Fprintln(t.out, help)
+	default:
+		fmt.Fprintf(t.out, "unknown command %s, try help\n", name)
+	}
+	return false, nil
+}
+
+// list shows line and the context lines around it, marking line.
+func (t *terminal) list(line, context int) {
+	for n := line - context; n <= line+context; n++ {
+		if n < 1 || n > len(t.lines) {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(t.out, "%s%4d  %s\n", marker, n, t.lines[n-1])
+	}
+}
@@ -0,0 +1,511 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// Tasks started with spawn run on goroutines of their own. Channels are not
+// Go channels: a blocked task parks on a waiter, and whoever completes the
+// operation hands the value over and wakes it. Doing the hand-off ourselves
+// is what lets the scheduler know, at every moment, how many tasks can
+// still make progress, and report a deadlock once none can.
+
+// chanMu guards every channel, every task handle and every scheduler.
+// Operations under it are short, and a single lock keeps the hand-off
+// between tasks and the deadlock bookkeeping consistent without any lock
+// ordering to get wrong.
+var chanMu sync.Mutex
+
+// scheduler tracks the tasks of one evaluation. Deadlock detection only
+// sees those, so a task waiting on a channel that is fed from another
+// evaluation is reported as deadlocked once its own evaluation is stuck.
+// Awaiting a task of another evaluation is left out of the count, since
+// that task may well finish.
+type scheduler struct {
+	running int              //tasks not blocked, the evaluation itself included
+	blocked map[*waiter]bool //woken all at once when a deadlock is detected
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{running: 1, blocked: map[*waiter]bool{}}
+}
+
+func (s *scheduler) deadlock() {
+	for w := range s.blocked {
+		w.fire(-1, nil, ErrDeadlock)
+	}
+}
+
+// waiter is a task blocked in send, recv, await or select.
+type waiter struct {
+	sched *scheduler //nil when the wait is not counted as blocked
+	wake  chan struct{}
+	fired bool
+	index int //the case that completed
+	value object.Object
+	err   error
+}
+
+// fire completes the wait with the case at index. It reports false if the
+// waiter was already completed by another case, or gave up waiting.
+func (w *waiter) fire(index int, value object.Object, err error) bool {
+	if w.fired {
+		return false
+	}
+	w.fired, w.index, w.value, w.err = true, index, value, err
+	if w.sched != nil {
+		delete(w.sched.blocked, w)
+		w.sched.running++
+	}
+	w.wake <- struct{}{}
+	return true
+}
+
+// pending is a waiter queued on a channel or task for one of its cases.
+type pending struct {
+	w     *waiter
+	index int
+	value object.Object //the value to send
+}
+
+// Channel is the object created by chan(n), buffering up to n values.
+type Channel struct {
+	capacity int
+	buffer   []object.Object
+	closed   bool
+	recvq    []*pending
+	sendq    []*pending
+}
+
+func (ch *Channel) Type() object.ObjectType { return object.CHANNEL_OBJ }
+func (ch *Channel) Inspect() string         { return fmt.Sprintf("chan(%d)", ch.capacity) }
+
+// trySend delivers value to a waiting receiver or buffers it, reporting
+// false if it has to wait.
+func (ch *Channel) trySend(value object.Object) (bool, *object.Error) {
+	if ch.closed {
+		return false, newError("send on closed channel")
+	}
+	for len(ch.recvq) > 0 {
+		p := ch.recvq[0]
+		ch.recvq = ch.recvq[1:]
+		if p.w.fire(p.index, value, nil) {
+			return true, nil
+		}
+	}
+	if len(ch.buffer) < ch.capacity {
+		ch.buffer = append(ch.buffer, value)
+		return true, nil
+	}
+	return false, nil
+}
+
+// tryRecv takes a value from the buffer or a waiting sender. A closed and
+// drained channel yields null.
+func (ch *Channel) tryRecv() (object.Object, bool) {
+	if len(ch.buffer) > 0 {
+		value := ch.buffer[0]
+		ch.buffer = ch.buffer[1:]
+		// the slot just freed goes to the first sender still waiting
+		for len(ch.sendq) > 0 {
+			p := ch.sendq[0]
+			ch.sendq = ch.sendq[1:]
+			if p.w.fire(p.index, NULL, nil) {
+				ch.buffer = append(ch.buffer, p.value)
+				break
+			}
+		}
+		return value, true
+	}
+	for len(ch.sendq) > 0 {
+		p := ch.sendq[0]
+		ch.sendq = ch.sendq[1:]
+		if p.w.fire(p.index, NULL, nil) {
+			return p.value, true
+		}
+	}
+	if ch.closed {
+		return NULL, true
+	}
+	return nil, false
+}
+
+func (ch *Channel) close() *object.Error {
+	if ch.closed {
+		return newError("close of closed channel")
+	}
+	ch.closed = true
+	for _, p := range ch.recvq {
+		p.w.fire(p.index, NULL, nil)
+	}
+	for _, p := range ch.sendq {
+		p.w.fire(p.index, nil, errors.New("send on closed channel"))
+	}
+	ch.recvq, ch.sendq = nil, nil
+	return nil
+}
+
+// Task is the handle spawn returns, await(task) waits for its result.
+type Task struct {
+	sched   *scheduler //of the evaluation that spawned it
+	done    bool
+	result  object.Object
+	waiters []*pending
+}
+
+func (t *Task) Type() object.ObjectType { return object.TASK_OBJ }
+func (t *Task) Inspect() string         { return "task" }
+
+func (t *Task) finish(result object.Object) {
+	t.done, t.result = true, result
+	for _, p := range t.waiters {
+		p.w.fire(p.index, result, nil)
+	}
+	t.waiters = nil
+}
+
+// commCase is one operation of a blocking call: a send or receive on ch, or
+// waiting for task.
+type commCase struct {
+	ch    *Channel
+	send  bool
+	value object.Object
+	task  *Task
+}
+
+// ready runs the case if it can complete without blocking.
+func (cc commCase) ready() (object.Object, bool, *object.Error) {
+	switch {
+	case cc.task != nil:
+		return cc.task.result, cc.task.done, nil
+	case cc.send:
+		ok, err := cc.ch.trySend(cc.value)
+		return NULL, ok, err
+	default:
+		value, ok := cc.ch.tryRecv()
+		return value, ok, nil
+	}
+}
+
+func (cc commCase) enqueue(p *pending) {
+	switch {
+	case cc.task != nil:
+		cc.task.waiters = append(cc.task.waiters, p)
+	case cc.send:
+		cc.ch.sendq = append(cc.ch.sendq, p)
+	default:
+		cc.ch.recvq = append(cc.ch.recvq, p)
+	}
+}
+
+// comm completes the first case that is ready, in order, or blocks until
+// one is. It returns the index of that case and the value received, or -1
+// when nothing is ready and nonblocking is set.
+func (c *Context) comm(cases []commCase, nonblocking bool) (int, object.Object, *object.Error) {
+	chanMu.Lock()
+	for i, cc := range cases {
+		value, ok, err := cc.ready()
+		if err != nil || ok {
+			chanMu.Unlock()
+			return i, value, err
+		}
+	}
+	if nonblocking {
+		chanMu.Unlock()
+		return -1, nil, nil
+	}
+	w := &waiter{sched: c.sched, wake: make(chan struct{}, 1)}
+	for i, cc := range cases {
+		if cc.task != nil && cc.task.sched != c.sched {
+			w.sched = nil
+		}
+		cc.enqueue(&pending{w: w, index: i, value: cc.value})
+	}
+	if w.sched != nil {
+		c.sched.running--
+		c.sched.blocked[w] = true
+		if c.sched.running == 0 {
+			c.sched.deadlock()
+		}
+	}
+	chanMu.Unlock()
+
+	var timeout <-chan time.Time
+	if !c.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(c.deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var stop error
+	select {
+	case <-w.wake:
+	case <-c.ctx.Done():
+		stop = ErrCanceled
+		if c.ctx.Err() == context.DeadlineExceeded {
+			stop = ErrTimeout
+		}
+	case <-timeout:
+		stop = ErrTimeout
+	}
+	if stop != nil {
+		chanMu.Lock()
+		// completed just as we stopped waiting: keep the result, it has
+		// already been taken from the sender
+		gaveUp := !w.fired
+		if gaveUp {
+			w.fired = true
+			if w.sched != nil {
+				delete(w.sched.blocked, w)
+				w.sched.running++
+			}
+		}
+		chanMu.Unlock()
+		if gaveUp {
+			return 0, nil, c.fail(stop)
+		}
+	}
+	switch {
+	case w.err == ErrDeadlock:
+		// tasks stopped by cancellation or the timeout leave the others
+		// stuck, report the cause rather than the symptom
+		if err := c.interrupted(); err != nil {
+			return 0, nil, c.fail(err)
+		}
+		return 0, nil, c.fail(ErrDeadlock)
+	case w.err != nil:
+		return 0, nil, newError("%s", w.err.Error())
+	}
+	return w.index, w.value, nil
+}
+
+// evalMain evaluates the program an evaluation starts with. The tasks it
+// spawned and did not await are canceled when it ends, rather than left
+// running unseen.
+func (c *Context) evalMain(program *ast.Program, env *object.Environment) object.Object {
+	tasks, cancel := context.WithCancel(c.ctx)
+	c.tasks, c.program = tasks, true
+	defer func() {
+		cancel()
+		c.tasks, c.program = c.ctx, false
+	}()
+	return c.evalProgram(program.Statements, env)
+}
+
+// spawn runs fn with args on a new task.
+func (c *Context) spawn(fn object.Object, args []object.Object, keywords map[string]object.Object) *Task {
+	task := &Task{sched: c.sched}
+	child := c.fork()
+	chanMu.Lock()
+	c.sched.running++
+	chanMu.Unlock()
+	go func() {
+		result := child.applyCall(fn, args, keywords)
+		chanMu.Lock()
+		task.finish(result)
+		child.sched.running--
+		if child.sched.running == 0 && len(child.sched.blocked) > 0 {
+			child.sched.deadlock()
+		}
+		chanMu.Unlock()
+	}()
+	return task
+}
+
+func (c *Context) evalSpawnExpression(node *ast.SpawnExpression, env *object.Environment) object.Object {
+	var fn object.Object
+	var args []object.Object
+	var keywords map[string]object.Object
+	// spawn f(x) evaluates f and x here and makes only the call on the task
+	if call, ok := node.Call.(*ast.CallExpression); ok {
+		fn = c.Eval(call.Function, env)
+		if isError(fn) {
+			return fn
+		}
+		var err object.Object
+		args, keywords, err = c.evalArguments(call.Arguments, env)
+		if err != nil {
+			return err
+		}
+	} else {
+		fn = c.Eval(node.Call, env)
+		if isError(fn) {
+			return fn
+		}
+	}
+	switch fn.(type) {
+	case *object.Function, *object.Builtin, *contextBuiltin:
+	default:
+		return newError("cannot spawn %s", fn.Type())
+	}
+	share(fn)
+	for _, arg := range args {
+		share(arg)
+	}
+	for _, arg := range keywords {
+		share(arg)
+	}
+	if err := c.alloc(1); err != nil {
+		return err
+	}
+	return c.spawn(fn, args, keywords)
+}
+
+func (c *Context) evalSelectExpression(node *ast.SelectExpression, env *object.Environment) object.Object {
+	var cases []commCase
+	var arms []*ast.SelectCase
+	var fallback *ast.SelectCase
+	for _, sc := range node.Cases {
+		if sc.Comm == nil {
+			fallback = sc
+			continue
+		}
+		args := c.evalExpressions(sc.Comm.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		name := sc.Comm.Function.String()
+		ch, ok := args[0].(*Channel)
+		if !ok {
+			return newError("argument to '%s' must be CHANNEL, got %s", name, args[0].Type())
+		}
+		cc := commCase{ch: ch}
+		if name == "send" {
+			cc.send, cc.value = true, args[1]
+			share(cc.value)
+		}
+		cases = append(cases, cc)
+		arms = append(arms, sc)
+	}
+	index, value, err := c.comm(cases, fallback != nil)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return c.Eval(fallback.Body, env)
+	}
+	if arm := arms[index]; arm.Name != nil {
+		if err := env.Declare(arm.Name.Value, value, false); err != nil {
+			return newError("%s", err)
+		}
+	}
+	return c.Eval(arms[index].Body, env)
+}
+
+// share makes the environments closed over by obj safe to use from another
+// task.
+func share(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Function:
+		obj.Env.Share()
+	case *object.Array:
+		for _, ele := range obj.Elements {
+			share(ele)
+		}
+	case *object.Hash:
+		for _, pair := range obj.Pairs {
+			share(pair.Value)
+		}
+	case *object.Struct:
+		for _, method := range obj.Methods {
+			method.Env.Share()
+		}
+	case *object.Instance:
+		share(obj.Struct)
+		for _, value := range obj.Values() {
+			share(value)
+		}
+	case *contextBuiltin:
+		share(obj.receiver)
+	}
+}
+
+// concurrencyBuiltins need the Context they run on, to block and to honour
+// cancellation, so they run on the one applying them.
+var concurrencyBuiltins = map[string]*contextBuiltin{
+	"chan": {name: "chan", fn: func(c *Context, _ object.Object, args []object.Object) object.Object {
+		if len(args) > 1 {
+			return newError("Wrong Number of args, want 0 or 1, got %d", len(args))
+		}
+		capacity := int64(0)
+		if len(args) == 1 {
+			n, ok := args[0].(*object.Integer)
+			if !ok || n.Value < 0 {
+				return newError("argument to 'chan' must be a non-negative INTEGER, got %s", args[0].Inspect())
+			}
+			capacity = n.Value
+		}
+		return &Channel{capacity: int(capacity)}
+	}},
+	"send": {name: "send", fn: func(c *Context, _ object.Object, args []object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("Wrong Number of args, want 2, got %d", len(args))
+		}
+		ch, err := channelArg("send", args[0])
+		if err != nil {
+			return err
+		}
+		share(args[1])
+		if _, _, err := c.comm([]commCase{{ch: ch, send: true, value: args[1]}}, false); err != nil {
+			return err
+		}
+		return NULL
+	}},
+	"recv": {name: "recv", fn: func(c *Context, _ object.Object, args []object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("Wrong Number of args, want 1, got %d", len(args))
+		}
+		ch, err := channelArg("recv", args[0])
+		if err != nil {
+			return err
+		}
+		_, value, err := c.comm([]commCase{{ch: ch}}, false)
+		if err != nil {
+			return err
+		}
+		return value
+	}},
+	"close": {name: "close", fn: func(c *Context, _ object.Object, args []object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("Wrong Number of args, want 1, got %d", len(args))
+		}
+		ch, err := channelArg("close", args[0])
+		if err != nil {
+			return err
+		}
+		chanMu.Lock()
+		defer chanMu.Unlock()
+		if err := ch.close(); err != nil {
+			return err
+		}
+		return NULL
+	}},
+	"await": {name: "await", fn: func(c *Context, _ object.Object, args []object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("Wrong Number of args, want 1, got %d", len(args))
+		}
+		task, ok := args[0].(*Task)
+		if !ok {
+			return newError("argument to 'await' must be TASK, got %s", args[0].Type())
+		}
+		_, result, err := c.comm([]commCase{{task: task}}, false)
+		if err != nil {
+			return err
+		}
+		return result
+	}},
+}
+
+func channelArg(name string, arg object.Object) (*Channel, *object.Error) {
+	ch, ok := arg.(*Channel)
+	if !ok {
+		return nil, newError("argument to '%s' must be CHANNEL, got %s", name, arg.Type())
+	}
+	return ch, nil
+}
@@ -0,0 +1,136 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nishokbanand/interpreter/object"
+)
+
+func TestSpawnAndChannels(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"await(spawn fn() { 1 + 2 })", 3},
+		{"let add = fn(a, b) { a + b }; await(spawn add(2, 3))", 5},
+		{"let t = spawn len([1, 2]); await(t) + await(t)", 4},
+		{"let ch = chan(); spawn fn() { send(ch, 7) }; recv(ch)", 7},
+		{"let ch = chan(2); send(ch, 1); send(ch, 2); recv(ch) * 10 + recv(ch)", 12},
+		{"let ch = chan(1); send(ch, 1); close(ch); recv(ch)", 1},
+		{"let ch = chan(1); send(ch, 1); close(ch); recv(ch); recv(ch)", nil},
+		{`let ch = chan();
+		  let produce = fn(n) { if (n == 0) { close(ch) } else { send(ch, n); produce(n - 1) } };
+		  spawn produce(100);
+		  let sum = fn(acc) { let v = recv(ch); if (!v) { acc } else { sum(acc + v) } };
+		  sum(0)`, 5050},
+		{`let results = chan(10);
+		  let work = fn(n) { send(results, n * n) };
+		  let start = fn(n) { if (n > 0) { spawn work(n); start(n - 1) } };
+		  start(10);
+		  let collect = fn(n, acc) { if (n == 0) { acc } else { collect(n - 1, acc + recv(results)) } };
+		  collect(10, 0)`, 385},
+		{"let x = 10; let f = fn() { x * 2 }; await(spawn f)", 20},
+		{"let ch = chan(1); select { case v = recv(ch) { v } default { 42 } }", 42},
+		{"let ch = chan(1); send(ch, 5); select { case v = recv(ch) { v } default { 42 } }", 5},
+		{"let ch = chan(1); select { case send(ch, 3) { recv(ch) } }", 3},
+		{`let a = chan(); let b = chan();
+		  spawn fn() { send(b, 2) };
+		  select { case v = recv(a) { v } case v = recv(b) { v * 100 } }`, 200},
+		{"let ch = chan(); close(ch); select { case v = recv(ch) { v } }", nil},
+		{"await(spawn fn() { 1 + true })", errorMessage("Operands are not of the same type : INTEGER + BOOLEAN")},
+		{"spawn 5", errorMessage("cannot spawn INTEGER")},
+		{"recv(1)", errorMessage("argument to 'recv' must be CHANNEL, got INTEGER")},
+		{"await(chan())", errorMessage("argument to 'await' must be TASK, got CHANNEL")},
+		{"chan(-1)", errorMessage("argument to 'chan' must be a non-negative INTEGER, got -1")},
+		{"let ch = chan(); close(ch); close(ch)", errorMessage("close of closed channel")},
+		{"let ch = chan(1); close(ch); send(ch, 1)", errorMessage("send on closed channel")},
+		{"let ch = chan(); spawn fn() { recv(ch); close(ch) }; send(ch, 1); send(ch, 2)", errorMessage("send on closed channel")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	tests := []string{
+		"recv(chan())",
+		"send(chan(), 1)",
+		"select {}",
+		"let ch = chan(); let t = spawn fn() { recv(ch) }; await(t)",
+		"let a = chan(); let b = chan(); spawn fn() { recv(a); send(b, 1) }; recv(b)",
+		"let ch = chan(); spawn fn() { 1 }; recv(ch)",
+	}
+	for _, input := range tests {
+		c := NewContext(context.Background(), Limits{})
+		evaluated := evalWithContext(t, c, input)
+		testObject(t, evaluated, errorMessage(ErrDeadlock.Error()))
+		if !errors.Is(c.Err(), ErrDeadlock) {
+			t.Errorf("%q: expected ErrDeadlock, got %v", input, c.Err())
+		}
+	}
+}
+
+func TestBlockedTaskCancellation(t *testing.T) {
+	// a task kept busy means the blocked receive is not a deadlock, only
+	// the host context or the timeout can end it
+	input := `let ch = chan(); let spin = fn() { spin() }; spawn spin(); recv(ch)`
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	c := NewContext(ctx, Limits{})
+	testObject(t, evalWithContext(t, c, input), errorMessage(ErrCanceled.Error()))
+	if !errors.Is(c.Err(), ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", c.Err())
+	}
+
+	c = NewContext(context.Background(), Limits{Timeout: 10 * time.Millisecond})
+	testObject(t, evalWithContext(t, c, input), errorMessage(ErrTimeout.Error()))
+	if !errors.Is(c.Err(), ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", c.Err())
+	}
+}
+
+func TestTasksShareBudget(t *testing.T) {
+	c := NewContext(context.Background(), Limits{MaxSteps: 10000})
+	input := `let spin = fn(n) { spin(n + 1) }; await(spawn spin(0))`
+	testObject(t, evalWithContext(t, c, input), errorMessage(ErrStepLimit.Error()))
+	if c.Steps() <= 10000 {
+		t.Errorf("expected the task's steps to count, got %d", c.Steps())
+	}
+}
+
+// Tasks still running when the program ends are canceled. Each stops on a
+// Context of its own, even in a builtin the main program looked up, so the
+// evaluation itself still succeeded.
+func TestUnawaitedTasksCanceled(t *testing.T) {
+	c := NewContext(context.Background(), Limits{})
+	input := `let r = recv; let ch = chan();
+	let spin = fn() { spin() };
+	[spawn spin(), spawn r(ch)]`
+	tasks, ok := evalWithContext(t, c, input).(*object.Array)
+	if !ok {
+		t.Fatalf("expected the tasks, got %v", tasks)
+	}
+	for _, task := range tasks.Elements {
+		task := task.(*Task)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			chanMu.Lock()
+			done, result := task.done, task.result
+			chanMu.Unlock()
+			if done {
+				testObject(t, result, errorMessage(ErrCanceled.Error()))
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected the task to be canceled")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if c.Err() != nil {
+		t.Errorf("expected the evaluation to succeed, got %v", c.Err())
+	}
+}
@@ -0,0 +1,62 @@
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 1; x = 2; x`, 2},
+		{`let x = 1; x = x + 1`, 2},
+		{`let count = 0; let inc = fn() { count = count + 1 }; inc(); inc(); count`, 2},
+		{`let x = 1; let f = fn(x) { x = 5; x }; f(0) * 10 + x`, 51},
+		{`let a = 1; let b = 2; a = b = 3; a + b`, 6},
+		{`const x = 5; x * 2`, 10},
+		{`const [a, b] = [1, 2]; a + b`, 3},
+		{`const x = 1; let f = fn() { let x = 2; x = 3; x }; f()`, 3},
+		{`y = 1`, errorMessage("identifier not found y")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+// TestConstAcrossPrograms checks what the parser cannot see: declarations
+// made by an earlier program in the same environment, as in the REPL.
+func TestConstAcrossPrograms(t *testing.T) {
+	tests := []struct {
+		first    string
+		second   string
+		expected interface{}
+	}{
+		{`const x = 1;`, `x = 2`, errorMessage("cannot assign to constant x")},
+		{`const x = 1;`, `let x = 2;`, errorMessage("cannot redeclare constant x")},
+		{`const x = 1;`, `const x = 2;`, errorMessage("cannot redeclare constant x")},
+		{`let x = 1;`, `const x = 2;`, errorMessage("cannot redeclare x as a constant")},
+		{`const x = 1;`, `let f = fn() { x = 2 }; f()`, errorMessage("cannot assign to constant x")},
+		{`const [a, b] = [1, 2];`, `let [b, c] = [3, 4];`, errorMessage("cannot redeclare constant b")},
+		{`const P = 1;`, `struct P { x }`, errorMessage("cannot redeclare constant P")},
+		{`let x = 1;`, `let x = 2; x`, 2},
+	}
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		for _, input := range []string{tt.first, tt.second} {
+			p := parser.New(lexer.New(input))
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors for %q: %v", input, p.Errors())
+			}
+			result := Eval(program, env)
+			if input == tt.second {
+				testObject(t, result, tt.expected)
+			}
+		}
+	}
+}
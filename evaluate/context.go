@@ -0,0 +1,294 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// DefaultMaxDepth is the call depth used when Limits.MaxDepth is not set. It
+// keeps runaway recursion well below the point where the Go runtime would
+// abort the whole process with a stack overflow.
+const DefaultMaxDepth = 10000
+
+// checkInterval is how many steps pass between polls of the host context and
+// the wall clock, so that the common path stays a couple of integer compares.
+const checkInterval = 1024
+
+var (
+	ErrCanceled   = errors.New("evaluation canceled")
+	ErrTimeout    = errors.New("evaluation timed out")
+	ErrMaxDepth   = errors.New("maximum call depth exceeded")
+	ErrStepLimit  = errors.New("step budget exhausted")
+	ErrAllocLimit = errors.New("allocation budget exhausted")
+	ErrDeadlock   = errors.New("deadlock: every task is blocked")
+)
+
+// Limits bounds the resources one evaluation may use. Zero values mean no
+// limit, except MaxDepth which falls back to DefaultMaxDepth.
+type Limits struct {
+	Timeout   time.Duration // wall-clock time for the whole evaluation
+	MaxDepth  int           // nested (non-tail) function calls
+	MaxSteps  int64         // AST nodes evaluated
+	MaxAllocs int64         // objects created, counting array and hash elements
+}
+
+// Context carries the state of a single evaluation: the host context used for
+// cancellation, the configured limits and the counters checked against them,
+// and the builtins bound to this evaluation's streams.
+// A Context is not safe for concurrent use. Each task started with spawn
+// runs on a Context of its own that shares the limits, the step and
+// allocation budgets and the scheduler of the one that spawned it.
+type Context struct {
+	ctx      context.Context
+	limits   Limits
+	deadline time.Time
+	depth    int
+	budget   *budget
+	sched    *scheduler
+	err      error
+	builtins map[string]*object.Builtin //shadow the package builtins of the same name
+	modules  *Modules
+	tasks    context.Context //what spawned tasks run on, canceled when the program ends
+	program  bool            //evaluating a program, so the next one is an imported module
+
+	addresses map[*ast.Identifier]resolver.Address //from SetResolution
+	hook      Hook
+}
+
+// budget counts what all the tasks of an evaluation used together.
+type budget struct {
+	steps  atomic.Int64
+	allocs atomic.Int64
+}
+
+func NewContext(ctx context.Context, limits Limits) *Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = DefaultMaxDepth
+	}
+	c := &Context{
+		ctx:      ctx,
+		tasks:    ctx,
+		limits:   limits,
+		budget:   &budget{},
+		sched:    newScheduler(),
+		builtins: map[string]*object.Builtin{},
+	}
+	if limits.Timeout > 0 {
+		c.deadline = time.Now().Add(limits.Timeout)
+	}
+	return c
+}
+
+// fork returns the Context a spawned task runs on.
+func (c *Context) fork() *Context {
+	return &Context{
+		ctx:      c.tasks,
+		limits:   c.limits,
+		deadline: c.deadline,
+		budget:   c.budget,
+		sched:    c.sched,
+		builtins: c.builtins,
+		modules:  c.modules,
+		tasks:    c.tasks,
+		program:  true,
+
+		addresses: c.addresses,
+	}
+}
+
+// SetStreams routes the I/O builtins (print, println, printf, eprint, input
+// and read_line) through streams instead of the process streams.
+func (c *Context) SetStreams(streams *object.Streams) {
+	for name, builtin := range streams.Builtins() {
+		c.builtins[name] = builtin
+	}
+}
+
+// SetBuiltin makes builtin available under name for this evaluation only.
+// It takes precedence over a package builtin of the same name, while
+// variables bound in the environment still shadow it.
+func (c *Context) SetBuiltin(name string, builtin *object.Builtin) {
+	c.builtins[name] = builtin
+}
+
+// SetHook makes hook follow the evaluation. Tasks started with spawn run
+// without it.
+func (c *Context) SetHook(hook Hook) {
+	c.hook = hook
+}
+
+// SetResolution lets identifiers of the resolved program be looked up
+// directly in the environment that binds them. A name the resolver placed
+// but that is not bound there yet is still looked up the usual way.
+func (c *Context) SetResolution(res *resolver.Resolution) {
+	c.addresses = res.Addresses
+}
+
+// IsBuiltin reports whether name is bound to a builtin in every
+// evaluation, for use as the callback of resolver.Resolve.
+func IsBuiltin(name string) bool {
+	if _, ok := builtins[name]; ok {
+		return true
+	}
+	_, ok := concurrencyBuiltins[name]
+	return ok
+}
+
+// Builtins returns the names IsBuiltin accepts, in order.
+func Builtins() []string {
+	names := []string{}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range concurrencyBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinName returns the name builtin is bound to in this evaluation, for
+// a Hook to tell builtins apart. A builtin bound to several names is named
+// by the first of them in order, a method by its own name.
+func (c *Context) BuiltinName(builtin object.Object) (string, bool) {
+	if method, ok := builtin.(*contextBuiltin); ok {
+		return method.name, true
+	}
+	for _, bound := range []map[string]*object.Builtin{c.builtins, builtins} {
+		name := ""
+		for n, b := range bound {
+			if b == builtin && (name == "" || n < name) {
+				name = n
+			}
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Apply calls fn, which Callable accepts, with args.
+func (c *Context) Apply(fn object.Object, args []object.Object) object.Object {
+	return c.applyFunction(fn, args)
+}
+
+// Callable reports whether obj can be called: a function, a builtin, a
+// bound method or a struct, which constructs an instance.
+func Callable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin, *contextBuiltin, *object.Struct:
+		return true
+	}
+	return false
+}
+
+// Err reports which limit stopped the evaluation, or nil if none did. The
+// returned error is one of the Err* values of this package.
+func (c *Context) Err() error {
+	return c.err
+}
+
+// Steps returns the number of nodes evaluated so far, by this evaluation and
+// the tasks it spawned.
+func (c *Context) Steps() int64 {
+	return c.budget.steps.Load()
+}
+
+// Allocs returns the number of objects allocated so far, by this evaluation
+// and the tasks it spawned.
+func (c *Context) Allocs() int64 {
+	return c.budget.allocs.Load()
+}
+
+func (c *Context) fail(err error) *object.Error {
+	if c.err == nil {
+		c.err = err
+	}
+	return newError("%s", c.err.Error())
+}
+
+// step is called once per evaluated node, before node is evaluated in env.
+// Once any limit trips the error is sticky, so every enclosing Eval
+// unwinds with the same error.
+func (c *Context) step(node ast.Node, env *object.Environment) *object.Error {
+	if c.err != nil {
+		return newError("%s", c.err.Error())
+	}
+	steps := c.budget.steps.Add(1)
+	if c.limits.MaxSteps > 0 && steps > c.limits.MaxSteps {
+		return c.fail(ErrStepLimit)
+	}
+	if steps%checkInterval == 1 {
+		if err := c.interrupted(); err != nil {
+			return c.fail(err)
+		}
+	}
+	if c.hook != nil {
+		tok := ast.Start(node)
+		if err := c.hook.Node(node, resolver.Position{Line: tok.Line, Column: tok.Column}, env); err != nil {
+			return c.fail(err)
+		}
+	}
+	return nil
+}
+
+// interrupted reports whether the host context was canceled or the
+// timeout has passed.
+func (c *Context) interrupted() error {
+	switch c.ctx.Err() {
+	case nil:
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	default:
+		return ErrCanceled
+	}
+	if !c.deadline.IsZero() && time.Now().After(c.deadline) {
+		return ErrTimeout
+	}
+	return nil
+}
+
+func (c *Context) alloc(n int64) *object.Error {
+	if allocs := c.budget.allocs.Add(n); c.limits.MaxAllocs > 0 && allocs > c.limits.MaxAllocs {
+		return c.fail(ErrAllocLimit)
+	}
+	return nil
+}
+
+func (c *Context) enter() *object.Error {
+	c.depth++
+	if c.depth > c.limits.MaxDepth {
+		return c.fail(ErrMaxDepth)
+	}
+	return nil
+}
+
+func (c *Context) leave() {
+	c.depth--
+}
+
+// sizeOf is the allocation cost charged for an object handed back by a
+// builtin.
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return int64(len(obj.Elements)) + 1
+	case *object.Hash:
+		return int64(len(obj.Pairs)) + 1
+	case *object.Integer, *object.String:
+		return 1
+	default:
+		return 0
+	}
+}
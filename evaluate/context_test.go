@@ -0,0 +1,90 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func evalWithContext(t *testing.T, c *Context, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return c.Eval(program, object.NewEnvironment())
+}
+
+func TestContextLimits(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		limits   Limits
+		input    string
+		expected error
+	}{
+		{"depth", context.Background(), Limits{}, "let f = fn() { 1 + f() }; f()", ErrMaxDepth},
+		{"custom depth", context.Background(), Limits{MaxDepth: 10}, "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(20)", ErrMaxDepth},
+		{"steps", context.Background(), Limits{MaxSteps: 50}, "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(100)", ErrStepLimit},
+		{"allocs", context.Background(), Limits{MaxAllocs: 10}, "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]", ErrAllocLimit},
+		{"canceled", canceled, Limits{}, "1 + 1", ErrCanceled},
+		{"timeout", context.Background(), Limits{Timeout: time.Millisecond, MaxDepth: 1 << 20}, "let f = fn(n) { f(n + 1) }; f(0)", ErrTimeout},
+	}
+	for _, tt := range tests {
+		c := NewContext(tt.ctx, tt.limits)
+		result := evalWithContext(t, c, tt.input)
+		if !errors.Is(c.Err(), tt.expected) {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.expected, c.Err())
+			continue
+		}
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("%s: expected *object.Error, got %T (%v)", tt.name, result, result)
+			continue
+		}
+		if errObj.Message != tt.expected.Error() {
+			t.Errorf("%s: expected message %q, got %q", tt.name, tt.expected.Error(), errObj.Message)
+		}
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	c := NewContext(ctx, Limits{MaxDepth: 1 << 20})
+	evalWithContext(t, c, "let f = fn(n) { f(n + 1) }; f(0)")
+	if !errors.Is(c.Err(), ErrTimeout) {
+		t.Errorf("expected %v, got %v", ErrTimeout, c.Err())
+	}
+}
+
+func TestContextWithinLimits(t *testing.T) {
+	c := NewContext(context.Background(), Limits{MaxDepth: 100, MaxSteps: 10000, MaxAllocs: 10000})
+	result := evalWithContext(t, c, "let f = fn(n) { if (n == 0) { 0 } else { n + f(n - 1) } }; f(50)")
+	if c.Err() != nil {
+		t.Fatalf("unexpected error %v", c.Err())
+	}
+	integer, ok := result.(*object.Integer)
+	if !ok || integer.Value != 1275 {
+		t.Errorf("expected 1275, got %v", result)
+	}
+	if c.Steps() == 0 || c.Allocs() == 0 {
+		t.Errorf("expected counters to advance, got steps=%d allocs=%d", c.Steps(), c.Allocs())
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	result := evalWithContext(t, NewContext(context.Background(), Limits{}), "1 / 0")
+	errObj, ok := result.(*object.Error)
+	if !ok || errObj.Message != "division by zero" {
+		t.Errorf("expected division by zero error, got %v", result)
+	}
+}
@@ -0,0 +1,55 @@
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let [a, b] = [1, 2]; a * 10 + b`, 12},
+		{`let pair = fn() { [3, 4] }; let [x, y] = pair(); x + y`, 7},
+		{`let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + a`, 21},
+		{`let [a, ...rest] = [1]; len(rest)`, 0},
+		{`let [a, b = 5] = [1]; a + b`, 6},
+		{`let [a, b = a * 2] = [4]; b`, 8},
+		{`let [a, b = 5] = [1, 2]; b`, 2},
+		{`let [_, [x, y]] = [0, [1, 2]]; x + y`, 3},
+		{`let {name, age: years} = {"name": "Ada", "age": 36}; name`, "Ada"},
+		{`let {name, age: years} = {"name": "Ada", "age": 36}; years`, 36},
+		{`let {name = "anon"} = {}; name`, "anon"},
+		{`let {pos: [x, y]} = {"pos": [3, 4]}; x * y`, 12},
+		{`struct P { x, y }; let {x, y} = P(1, 2); x + y`, 3},
+		{`let f = fn(p) { let [a, b] = p; a - b }; f([5, 2])`, 3},
+		// a let binds names even when they refer to a variant
+		{`enum Color { Red, Green }; let c = Red; let f = fn(v) { let [c] = [v]; c }; f(Green) == Green`, true},
+		{`enum Color { Red, Green }; let f = fn(v) { let [Red] = [v]; Red }; f(Green) == Green`, true},
+		{`let [a, b] = [1]`, errorMessage("cannot destructure [1] with [a,b]: expected 2 elements, got 1")},
+		{`let [a, b] = [1, 2, 3]`, errorMessage("cannot destructure [1,2,3] with [a,b]: expected 2 elements, got 3")},
+		{`let [a, b = 1] = [1, 2, 3]`, errorMessage("cannot destructure [1,2,3] with [a,b = 1]: expected at most 2 elements, got 3")},
+		{`let [a, b, ...c] = [1]`, errorMessage("cannot destructure [1] with [a,b,...c]: expected at least 2 elements, got 1")},
+		{`let [a] = 1`, errorMessage("cannot destructure 1 with [a]: expected ARRAY, got INTEGER")},
+		{`let {a} = [1]`, errorMessage("cannot destructure [1] with {\"a\":a}: expected HASH, got ARRAY")},
+		{`let {name} = {"age": 1}`, errorMessage("cannot destructure {age:1} with {\"name\":name}: missing field name")},
+		{`let {a: [b]} = {"a": 1}`, errorMessage("cannot destructure {a:1} with {\"a\":[b]}: expected ARRAY, got INTEGER")},
+		{`let [a, b = c] = [1]`, errorMessage("identifier not found c")},
+		{`let [a, b] = [1]; a`, errorMessage("cannot destructure [1] with [a,b]: expected 2 elements, got 1")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringBindsNothingOnMismatch(t *testing.T) {
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.New(`let [a, b] = [1];`)).ParseProgram(), env)
+	if _, ok := env.Get("a"); ok {
+		t.Errorf("a bound by a failed destructuring let")
+	}
+}
@@ -1,6 +1,7 @@
 package evaluate
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/nishokbanand/interpreter/ast"
@@ -8,9 +9,9 @@ import (
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.NULL{}
+	TRUE  = object.True
+	FALSE = object.False
+	NULL  = object.Null
 )
 
 func newError(format string, a ...interface{}) *object.Error {
@@ -23,86 +24,134 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// Eval evaluates node without any limits beyond DefaultMaxDepth.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return NewContext(context.Background(), Limits{}).Eval(node, env)
+}
+
+func (c *Context) Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := c.step(node, env); err != nil {
+		return err
+	}
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		if c.program {
+			return c.evalProgram(node.Statements, env)
+		}
+		return c.evalMain(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return c.Eval(node.Expression, env)
 	case *ast.Boolean:
 		return nativeBooltoBooleanObject(node.Value)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return c.evalIdentifier(node, env)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := c.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return c.evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := c.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := c.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return c.evalInfixExpression(node.Operator, left, right)
 	case *ast.BlockStatement:
-		return evalStatements(node.Statements, env)
+		return c.evalStatements(node.Statements, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return c.evalIfExpression(node, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := c.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			return c.destructure(node, val, env)
+		}
+		if err := env.Declare(node.Name.Value, val, node.Constant()); err != nil {
+			return newError("%s", err)
+		}
 	case *ast.ReturnStatement:
-		val := Eval(node.Value, env)
+		val := c.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.IntegerLiteral:
+		if err := c.alloc(1); err != nil {
+			return err
+		}
 		return &object.Integer{
 			Value: node.Value,
 		}
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		if err := c.alloc(1); err != nil {
+			return err
+		}
+		return &object.Function{Parameters: node.Parameters, Defaults: node.Defaults, Rest: node.Rest, Body: node.Body, Env: env}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := c.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, keywords, err := c.evalArguments(node.Arguments, env)
+		if err != nil {
+			return err
 		}
-		return applyFunction(function, args)
+		return c.applyCall(function, args, keywords)
+	case *ast.SpreadExpression:
+		return newError("cannot spread %s outside the arguments of a call", node.Value.String())
+	case *ast.KeywordArgument:
+		return newError("cannot pass %s by name here", node.Name.Value)
 	case *ast.String:
+		if err := c.alloc(1); err != nil {
+			return err
+		}
 		return &object.String{Value: node.Value}
 	case *ast.ArrayLiteral:
-		ele := evalExpressions(node.Elements, env)
+		ele := c.evalExpressions(node.Elements, env)
 		if len(ele) == 1 && isError(ele[0]) {
 			return ele[0]
 		}
+		if err := c.alloc(int64(len(ele)) + 1); err != nil {
+			return err
+		}
 		return &object.Array{Elements: ele}
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := c.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
+		index := c.Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 	case *ast.HashLiteral:
-		return evalHash(node, env)
+		return c.evalHash(node, env)
+	case *ast.SpawnExpression:
+		return c.evalSpawnExpression(node, env)
+	case *ast.SelectExpression:
+		return c.evalSelectExpression(node, env)
+	case *ast.ImportStatement:
+		return c.evalImportStatement(node, env)
+	case *ast.ExportStatement:
+		return c.Eval(node.Statement, env)
+	case *ast.MemberExpression:
+		return c.evalMemberExpression(node, env)
+	case *ast.StructStatement:
+		return c.evalStructStatement(node, env)
+	case *ast.AssignExpression:
+		return c.evalAssignExpression(node, env)
+	case *ast.EnumStatement:
+		return c.evalEnumStatement(node, env)
+	case *ast.MatchExpression:
+		return c.evalMatchExpression(node, env)
 	}
 	return nil
 }
@@ -114,10 +163,10 @@ func nativeBooltoBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalProgram(stmts []ast.StatmentNode, env *object.Environment) object.Object {
+func (c *Context) evalProgram(stmts []ast.StatmentNode, env *object.Environment) object.Object {
 	var result object.Object
 	for _, stmt := range stmts {
-		result = Eval(stmt, env)
+		result = c.Eval(stmt, env)
 		if result != nil {
 			switch result := result.(type) {
 			case *object.ReturnValue:
@@ -129,10 +178,10 @@ func evalProgram(stmts []ast.StatmentNode, env *object.Environment) object.Objec
 	}
 	return result
 }
-func evalStatements(stmts []ast.StatmentNode, env *object.Environment) object.Object {
+func (c *Context) evalStatements(stmts []ast.StatmentNode, env *object.Environment) object.Object {
 	var result object.Object
 	for _, stmt := range stmts {
-		result = Eval(stmt, env)
+		result = c.Eval(stmt, env)
 		if result != nil {
 			rt := result.Type()
 			if rt == object.ERROR_OBJ || rt == object.RETURN_OBJ {
@@ -143,12 +192,12 @@ func evalStatements(stmts []ast.StatmentNode, env *object.Environment) object.Ob
 	return result
 }
 
-func evalPrefixExpression(Operator string, right object.Object) object.Object {
+func (c *Context) evalPrefixExpression(Operator string, right object.Object) object.Object {
 	switch Operator {
 	case "!":
 		return evaluateBangExpression(right)
 	case "-":
-		return evaluateMinusExpression(right)
+		return c.evaluateMinusExpression(right)
 	default:
 		return newError("Unknown operator %s %s", Operator, right.Type())
 	}
@@ -163,50 +212,58 @@ func evaluateBangExpression(right object.Object) object.Object {
 	case NULL:
 		return TRUE
 	default:
-		return NULL
+		return FALSE
 	}
 }
 
-func evaluateMinusExpression(right object.Object) object.Object {
+func (c *Context) evaluateMinusExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("Unknown operator -%s", right.Type())
 	}
 	value := right.(*object.Integer).Value
-	return &object.Integer{
-		Value: -value,
+	return c.newInteger(-value)
+}
+
+func (c *Context) newInteger(value int64) object.Object {
+	if err := c.alloc(1); err != nil {
+		return err
 	}
+	return &object.Integer{Value: value}
 }
 
-func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func (c *Context) evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	switch {
-	case left.Type() != right.Type():
-		return newError("Operands are not of the same type : %s %s %s", left.Type(), operator, right.Type())
+	case !sameType(left, right):
+		return newError("Operands are not of the same type : %s %s %s", typeOf(left), operator, typeOf(right))
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evaluateIntegerInfixExpression(operator, left, right)
+		return c.evaluateIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evaluateStringInfixExpression(operator, left, right)
+		return c.evaluateStringInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBooltoBooleanObject(left == right)
+		return nativeBooltoBooleanObject(objectsEqual(left, right))
 	case operator == "!=":
-		return nativeBooltoBooleanObject(left != right)
+		return nativeBooltoBooleanObject(!objectsEqual(left, right))
 	default:
-		return newError("Unknown Operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError("Unknown Operator: %s %s %s", typeOf(left), operator, typeOf(right))
 	}
 }
 
-func evaluateIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func (c *Context) evaluateIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 	switch operator {
 	//int producing operators
 	case "+":
-		return &object.Integer{Value: (leftVal + rightVal)}
+		return c.newInteger(leftVal + rightVal)
 	case "-":
-		return &object.Integer{Value: (leftVal - rightVal)}
+		return c.newInteger(leftVal - rightVal)
 	case "*":
-		return &object.Integer{Value: (leftVal * rightVal)}
+		return c.newInteger(leftVal * rightVal)
 	case "/":
-		return &object.Integer{Value: (leftVal / rightVal)}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return c.newInteger(leftVal / rightVal)
 		//boolean producing operators
 	case "<":
 		return nativeBooltoBooleanObject(leftVal < rightVal)
@@ -220,15 +277,15 @@ func evaluateIntegerInfixExpression(operator string, left object.Object, right o
 	return newError("Unknown Operator %s %s %s", left.Type(), operator, right.Type())
 }
 
-func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(node.Condition, env)
+func (c *Context) evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := c.Eval(node.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(node.Consequence, env)
+		return c.Eval(node.Consequence, env)
 	} else if node.Alternative != nil {
-		return Eval(node.Alternative, env)
+		return c.Eval(node.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -247,20 +304,31 @@ func isTruthy(condition object.Object) bool {
 	}
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (c *Context) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if addr, ok := c.addresses[node]; ok {
+		if val, ok := env.GetAt(addr.Depth, addr.Slot, node.Value); ok {
+			return val
+		}
+	}
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
+	if builtin, ok := c.builtins[node.Value]; ok {
+		return builtin
+	}
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
+	if builtin, ok := concurrencyBuiltins[node.Value]; ok {
+		return builtin
+	}
 	return newError("identifier not found %s", node.Value)
 }
 
-func evalExpressions(exps []ast.ExpressionNode, env *object.Environment) []object.Object {
+func (c *Context) evalExpressions(exps []ast.ExpressionNode, env *object.Environment) []object.Object {
 	var result []object.Object
 	for _, e := range exps {
-		evaluated := Eval(e, env)
+		evaluated := c.Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -269,25 +337,200 @@ func evalExpressions(exps []ast.ExpressionNode, env *object.Environment) []objec
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (c *Context) applyFunction(fn object.Object, args []object.Object) object.Object {
+	return c.applyCall(fn, args, nil)
+}
+
+// applyCall applies fn to positional arguments and arguments passed by
+// name, which only functions and struct constructors take.
+func (c *Context) applyCall(fn object.Object, args []object.Object, keywords map[string]object.Object) object.Object {
+	if len(keywords) > 0 {
+		switch fn.(type) {
+		case *object.Function, *object.Struct:
+		default:
+			return newError("%s does not take arguments by name", fn.Type())
+		}
+	}
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendedFuncEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrappedValue(evaluated)
+		if err := c.enter(); err != nil {
+			c.leave()
+			return err
+		}
+		defer c.leave()
+		// calls in tail position come back as a *tailCall and are run by
+		// this loop, so tail recursion does not grow the Go stack
+		for {
+			extendedEnv, err := c.extendedFuncEnv(fn, args, keywords)
+			if err != nil {
+				return err
+			}
+			if c.hook != nil {
+				c.hook.Call(fn, args, extendedEnv)
+			}
+			evaluated := unwrappedValue(c.evalTail(fn.Body, extendedEnv, true))
+			if evaluated == nil {
+				// an empty body, or one ending in a let
+				evaluated = NULL
+			}
+			call, ok := evaluated.(*tailCall)
+			if c.hook != nil && ok {
+				c.hook.Return(fn, nil)
+			} else if c.hook != nil {
+				c.hook.Return(fn, evaluated)
+			}
+			if !ok {
+				return evaluated
+			}
+			next, ok := call.fn.(*object.Function)
+			if !ok {
+				return c.applyCall(call.fn, call.args, call.keywords)
+			}
+			fn, args, keywords = next, call.args, call.keywords
+		}
+	case *object.Struct:
+		return c.construct(fn, args, keywords)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return c.applyBuiltin(fn, args, fn.Fn)
+	case *contextBuiltin:
+		return c.applyBuiltin(fn, args, func(args ...object.Object) object.Object {
+			return fn.fn(c, fn.receiver, args)
+		})
 	default:
 		return newError("not a function %s :", fn.Type())
 	}
 }
 
-func extendedFuncEnv(f *object.Function, args []object.Object) *object.Environment {
+// applyBuiltin runs call, the code of the builtin fn, on args.
+func (c *Context) applyBuiltin(fn object.Object, args []object.Object, call object.BuiltinFunction) object.Object {
+	if c.hook != nil {
+		c.hook.Call(fn, args, nil)
+	}
+	result := call(args...)
+	// the result is allocated before the call returns, for a hook
+	// counting allocations to credit it to the builtin
+	err := c.alloc(sizeOf(result))
+	if c.hook != nil {
+		c.hook.Return(fn, result)
+	}
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// evalArguments evaluates the arguments of a call in order, expanding
+// spreads and collecting the arguments passed by name.
+func (c *Context) evalArguments(nodes []ast.ExpressionNode, env *object.Environment) ([]object.Object, map[string]object.Object, object.Object) {
+	var args []object.Object
+	var keywords map[string]object.Object
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *ast.SpreadExpression:
+			value := c.Eval(node.Value, env)
+			if isError(value) {
+				return nil, nil, value
+			}
+			arr, ok := value.(*object.Array)
+			if !ok {
+				return nil, nil, newError("cannot spread %s, want ARRAY", value.Type())
+			}
+			args = append(args, arr.Elements...)
+		case *ast.KeywordArgument:
+			value := c.Eval(node.Value, env)
+			if isError(value) {
+				return nil, nil, value
+			}
+			if keywords == nil {
+				keywords = map[string]object.Object{}
+			}
+			keywords[node.Name.Value] = value
+		default:
+			value := c.Eval(node, env)
+			if isError(value) {
+				return nil, nil, value
+			}
+			args = append(args, value)
+		}
+	}
+	return args, keywords, nil
+}
+
+// extendedFuncEnv binds the parameters of f to the positional arguments,
+// then to those passed by name, then to their defaults. Defaults are
+// evaluated in the new environment, so they can refer to the parameters
+// before them. The rest parameter collects the positional arguments left
+// over.
+func (c *Context) extendedFuncEnv(f *object.Function, args []object.Object, keywords map[string]object.Object) (*object.Environment, *object.Error) {
+	if err := c.alloc(1); err != nil {
+		return nil, err
+	}
+	if len(args) > len(f.Parameters) && f.Rest == nil {
+		return nil, arityError(f, len(args)+len(keywords))
+	}
+	for name := range keywords {
+		idx := -1
+		for i, param := range f.Parameters {
+			if param.Value == name {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return nil, newError("unexpected argument %s", name)
+		}
+		if idx < len(args) {
+			return nil, newError("got multiple values for argument %s", name)
+		}
+	}
 	extendedEnv := object.NewEnclosedEnvironment(f.Env)
 	for idx, param := range f.Parameters {
-		extendedEnv.Set(param.Value, args[idx])
+		var value object.Object
+		if idx < len(args) {
+			value = args[idx]
+		} else if keyword, ok := keywords[param.Value]; ok {
+			value = keyword
+		} else if idx < len(f.Defaults) && f.Defaults[idx] != nil {
+			value = c.Eval(f.Defaults[idx], extendedEnv)
+			if err, ok := value.(*object.Error); ok {
+				return nil, err
+			}
+		} else if len(keywords) == 0 {
+			return nil, arityError(f, len(args))
+		} else {
+			return nil, newError("missing argument %s", param.Value)
+		}
+		extendedEnv.Set(param.Value, value)
 	}
-	return extendedEnv
+	if f.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(f.Parameters) {
+			rest = append(rest, args[len(f.Parameters):]...)
+		}
+		if err := c.alloc(int64(len(rest)) + 1); err != nil {
+			return nil, err
+		}
+		extendedEnv.Set(f.Rest.Value, &object.Array{Elements: rest})
+	}
+	return extendedEnv, nil
+}
+
+// arityError reports a call to f with the wrong number of arguments,
+// describing the numbers f accepts.
+func arityError(f *object.Function, got int) *object.Error {
+	required := len(f.Parameters)
+	for idx := range f.Parameters {
+		if idx < len(f.Defaults) && f.Defaults[idx] != nil {
+			required = idx
+			break
+		}
+	}
+	switch {
+	case f.Rest != nil:
+		return newError("wrong number of arguments: want at least %d, got=%d", required, got)
+	case required < len(f.Parameters):
+		return newError("wrong number of arguments: want=%d to %d, got=%d", required, len(f.Parameters), got)
+	}
+	return newError("wrong number of arguments: want=%d, got=%d", required, got)
 }
 
 func unwrappedValue(obj object.Object) object.Object {
@@ -297,15 +540,22 @@ func unwrappedValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evaluateStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func (c *Context) evaluateStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 	switch operator {
-	//int producing operators
 	case "+":
+		if err := c.alloc(1); err != nil {
+			return err
+		}
 		return &object.String{Value: (leftVal + rightVal)}
+	//strings are compared by value, not by object identity
+	case "==":
+		return nativeBooltoBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBooltoBooleanObject(leftVal != rightVal)
 	default:
-		return newError("Unknown Operator %s %s %s", left, operator, right)
+		return newError("Unknown Operator %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 func evalIndexExpression(left object.Object, index object.Object) object.Object {
@@ -315,12 +565,15 @@ func evalIndexExpression(left object.Object, index object.Object) object.Object
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index Operator not supported %s ", left)
+		return newError("index Operator not supported %s", typeOf(left))
 	}
 }
 
 func evalArrayIndexExpression(arr object.Object, index object.Object) object.Object {
-	arrayObject := arr.(*object.Array)
+	arrayObject, ok := arr.(*object.Array)
+	if !ok {
+		return newError("index Operator not supported %s", typeOf(arr))
+	}
 	idx := index.(*object.Integer).Value
 	max := int64(len(arrayObject.Elements) - 1)
 	if idx < 0 || idx > max {
@@ -329,10 +582,10 @@ func evalArrayIndexExpression(arr object.Object, index object.Object) object.Obj
 	return arrayObject.Elements[idx]
 }
 
-func evalHash(hash *ast.HashLiteral, env *object.Environment) object.Object {
+func (c *Context) evalHash(hash *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 	for keyNode, valueNode := range hash.Pairs {
-		key := Eval(keyNode, env)
+		key := c.Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
@@ -340,7 +593,7 @@ func evalHash(hash *ast.HashLiteral, env *object.Environment) object.Object {
 		if !ok {
 			return newError("Cannot use as HashKey %s", key.Type())
 		}
-		value := Eval(valueNode, env)
+		value := c.Eval(valueNode, env)
 		if isError(value) {
 			return value
 		}
@@ -350,6 +603,9 @@ func evalHash(hash *ast.HashLiteral, env *object.Environment) object.Object {
 			Value: value,
 		}
 	}
+	if err := c.alloc(int64(len(pairs)) + 1); err != nil {
+		return err
+	}
 	return &object.Hash{
 		Pairs: pairs,
 	}
@@ -357,9 +613,12 @@ func evalHash(hash *ast.HashLiteral, env *object.Environment) object.Object {
 
 func evalHashIndexExpression(hash object.Object, index object.Object) object.Object {
 	hashObject, ok := hash.(*object.Hash)
+	if !ok {
+		return newError("index Operator not supported %s", typeOf(hash))
+	}
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("Index unusable as hashKey %s", hash.Type())
+		return newError("Index unusable as hashKey %s", index.Type())
 	}
 	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
@@ -367,3 +626,39 @@ func evalHashIndexExpression(hash object.Object, index object.Object) object.Obj
 	}
 	return pair.Value
 }
+
+// evalAssignExpression rebinds a variable, x = value, or sets the field of a
+// struct instance, p.x = value.
+func (c *Context) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	if ident, ok := node.Target.(*ast.Identifier); ok {
+		value := c.Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		if err := env.Assign(ident.Value, value); err != nil {
+			return newError("%s", err)
+		}
+		return value
+	}
+	target, ok := node.Target.(*ast.MemberExpression)
+	if !ok {
+		return newError("cannot assign to %s", node.Target.String())
+	}
+	obj := c.Eval(target.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	value := c.Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	name := target.Property.Value
+	instance, ok := obj.(*object.Instance)
+	if !ok {
+		return newError("cannot assign to field %s of %s", name, obj.Type())
+	}
+	if !instance.Set(name, value) {
+		return newError("%s has no field %s", instance.Struct.Name, name)
+	}
+	return value
+}
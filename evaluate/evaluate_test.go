@@ -0,0 +1,457 @@
+package evaluate
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return Eval(program, object.NewEnvironment())
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	t.Helper()
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	t.Helper()
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	t.Helper()
+	if obj != NULL {
+		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
+		return false
+	}
+	return true
+}
+
+// testObject checks obj against an expected int, bool, string, nil (NULL)
+// or error message wrapped in errorMessage.
+func testObject(t *testing.T, obj object.Object, expected interface{}) bool {
+	t.Helper()
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+	case bool:
+		return testBooleanObject(t, obj, expected)
+	case string:
+		str, ok := obj.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+			return false
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			return false
+		}
+		return true
+	case errorMessage:
+		errObj, ok := obj.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T (%+v)", obj, obj)
+			return false
+		}
+		if errObj.Message != string(expected) {
+			t.Errorf("wrong error message. got=%q, want=%q", errObj.Message, expected)
+			return false
+		}
+		return true
+	case nil:
+		return testNullObject(t, obj)
+	}
+	t.Errorf("unsupported expectation %T", expected)
+	return false
+}
+
+type errorMessage string
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"-5", -5},
+		{"--5", 5},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 / 2", 3},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"(1 > 2) == true", false},
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`let s = "mon"; s + "key" == "monkey"`, true},
+		{"let a = [1]; a == a", true},
+		{"[1] == [1]", false},
+	}
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+		{`!""`, false},
+		{"!if (false) { 1 }", true},
+	}
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (0) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (if (false) { 1 }) { 10 } else { 20 }", 20},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"let f = fn(x) { return x; x + 10; }; f(10);", 10},
+		{"let f = fn(x) { let result = x + 10; return result; return 10; }; f(10);", 20},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "Operands are not of the same type : INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "Operands are not of the same type : INTEGER + BOOLEAN"},
+		{"-true", "Unknown operator -BOOLEAN"},
+		{"true + false;", "Unknown Operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "Unknown Operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "Unknown Operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { if (10 > 1) { return true + false; } return 1; }", "Unknown Operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found foobar"},
+		{`"Hello" - "World"`, "Unknown Operator STRING - STRING"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "Index unusable as hashKey FUNCTION"},
+		{`{[1]: 2}`, "Cannot use as HashKey ARRAY"},
+		{"5[0]", "index Operator not supported INTEGER"},
+		{"[1, 2, 3][3]", "Out of bound Error :3 greater than 2"},
+		{"[1, 2, 3][-1]", "Out of bound Error :-1 greater than 2"},
+		{"1 / 0", "division by zero"},
+		{"let x = 5; x(1)", "not a function INTEGER :"},
+		{"let x = 1 + true; x", "Operands are not of the same type : INTEGER + BOOLEAN"},
+		{"[1, foo]", "identifier not found foo"},
+		{"len(foo)", "identifier not found foo"},
+		{"foo(1)", "identifier not found foo"},
+		{"!foo", "identifier not found foo"},
+		{"if (foo) { 1 }", "identifier not found foo"},
+		{"{1: foo}", "identifier not found foo"},
+		{"{foo: 1}", "identifier not found foo"},
+		{"foo[0]", "identifier not found foo"},
+		{"[1][foo]", "identifier not found foo"},
+		{"return foo;", "identifier not found foo"},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), errorMessage(tt.expected))
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let a = 1; let a = a + 1; a", 2},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+	if result := testEval(t, "let a = 5;"); result != nil {
+		t.Errorf("let statement should have no value, got %v", result)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	evaluated := testEval(t, "fn(x) { x + 2; };")
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Parameters) != 1 || fn.Parameters[0].String() != "x" {
+		t.Fatalf("wrong parameters, got %v", fn.Parameters)
+	}
+	if fn.Body.String() != "{(x + 2)}" {
+		t.Fatalf("body is not %q. got=%q", "{(x + 2)}", fn.Body.String())
+	}
+	if fn.Inspect() != "fn(x) {(x + 2)}" {
+		t.Errorf("wrong Inspect, got %q", fn.Inspect())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"fn() { }()", nil},
+		{"let f = fn() { let a = 1; }; f()", nil},
+		{"let x = 1; let f = fn(x) { x }; f(2) + x", 3},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let newAdder = fn(x) { fn(y) { x + y }; }; let addTwo = newAdder(2); addTwo(2);", 4},
+		{"let counter = fn(x) { if (x > 100) { return x; } counter(x + 1); }; counter(0);", 101},
+		{"let a = fn() { b() }; let b = fn() { 7 }; a()", 7},
+		{"let x = 1; let f = fn() { x }; let x = 2; f()", 2},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringLiteralAndConcatenation(t *testing.T) {
+	testObject(t, testEval(t, `"Hello World!"`), "Hello World!")
+	testObject(t, testEval(t, `"Hello" + " " + "World!"`), "Hello World!")
+	testObject(t, testEval(t, `"digits 0 and 1"`), "digits 0 and 1")
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{"len([1, 2, 3])", 3},
+		{"len([])", 0},
+		{"len(1)", errorMessage("argument to 'len' not supported, got INTEGER")},
+		{`len("one", "two")`, errorMessage("Wrong Number of args, want 1, got 2")},
+		{"first([1, 2, 3])", 1},
+		{"first([])", nil},
+		{"first(1)", errorMessage("argument to 'first' not supported, got INTEGER")},
+		{"first()", errorMessage("Wrong Number of args, want 1, got 0")},
+		{"last([1, 2, 3])", 3},
+		{"last([])", nil},
+		{"last(1)", errorMessage("argument to 'last' not supported, got INTEGER")},
+		{"rest([])", nil},
+		{"len(rest([1, 2, 3]))", 2},
+		{"rest([1, 2, 3])[0]", 2},
+		{"rest(1)", errorMessage("argument to 'rest' not supported, got INTEGER")},
+		{"push([], 1)[0]", 1},
+		{"let a = [1]; let b = push(a, 2); len(a) + len(b)", 3},
+		{"push(1, 1)", errorMessage("argument to 'push' not supported, got INTEGER")},
+		{"push([])", errorMessage("Wrong Number of args, want 2, got 1")},
+		{"print()", nil},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+	if result.Inspect() != "[1,4,6]" {
+		t.Errorf("wrong Inspect, got %q", result.Inspect())
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+	evaluated := testEval(t, input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+			continue
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEnvironmentIsolation(t *testing.T) {
+	env := object.NewEnvironment()
+	for _, input := range []string{"let a = 1;", "let f = fn() { let a = 2; a }; f();"} {
+		Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+	a, ok := env.Get("a")
+	if !ok {
+		t.Fatalf("a not defined")
+	}
+	testIntegerObject(t, a, 1)
+}
@@ -0,0 +1,79 @@
+package evaluate
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.out with the current output")
+
+// TestGolden runs every testdata/*.mk script and compares what it printed,
+// followed by its value or error, with the matching .out file.
+func TestGolden(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "*.mk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("no golden scripts found")
+	}
+	for _, script := range scripts {
+		t.Run(filepath.Base(script), func(t *testing.T) {
+			t.Parallel()
+			source, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := runGolden(t, string(source))
+			golden := strings.TrimSuffix(script, ".mk") + ".out"
+			if *update {
+				if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s (run with -update to create it)", err)
+			}
+			if actual != string(expected) {
+				t.Errorf("output mismatch for %s\n--- want\n%s--- got\n%s", script, expected, actual)
+			}
+		})
+	}
+}
+
+func runGolden(t *testing.T, source string) string {
+	t.Helper()
+	var out bytes.Buffer
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			out.WriteString("parse error: " + msg + "\n")
+		}
+		return out.String()
+	}
+	// what the script prints goes straight into the output, ahead of its
+	// value, so the scripts can run in parallel
+	c := NewContext(context.Background(), Limits{})
+	c.SetStreams(&object.Streams{Stdin: strings.NewReader(""), Stdout: &out, Stderr: &out})
+	result := c.Eval(program, object.NewEnvironment())
+	switch result := result.(type) {
+	case nil:
+	case *object.Error:
+		out.WriteString("error: " + result.Message + "\n")
+	default:
+		out.WriteString("=> " + result.Inspect() + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,25 @@
+package evaluate
+
+import (
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// Hook follows an evaluation as it runs, so that a debugger can stop it
+// and look at its environments. Its methods are called on the goroutine
+// doing the evaluation, which waits for them to return.
+type Hook interface {
+	// Node is called before each statement and expression is evaluated,
+	// with the position it starts at and the environment it is evaluated
+	// in. An error stops the evaluation, which fails with it.
+	Node(node ast.Node, pos resolver.Position, env *object.Environment) error
+	// Call is called when a function or builtin is applied to args. For a
+	// function, env is the environment its body runs in, with the
+	// parameters bound; for a builtin it is nil.
+	Call(fn object.Object, args []object.Object, env *object.Environment)
+	// Return is called when the call of fn ends, with its value or error.
+	// A function that ends by calling another in tail position returns
+	// nil, and that call is reported as one of its own.
+	Return(fn object.Object, result object.Object)
+}
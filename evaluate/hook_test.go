@@ -0,0 +1,95 @@
+package evaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// recorder logs the calls made on a Hook.
+type recorder struct {
+	events []string
+	lines  []int
+	stopAt int
+}
+
+func (r *recorder) Node(node ast.Node, pos resolver.Position, env *object.Environment) error {
+	if _, ok := node.(ast.StatmentNode); ok {
+		r.lines = append(r.lines, pos.Line)
+	}
+	if pos.Line == r.stopAt {
+		return errors.New("stopped")
+	}
+	return nil
+}
+
+func (r *recorder) Call(fn object.Object, args []object.Object, env *object.Environment) {
+	inspected := []string{}
+	for _, arg := range args {
+		inspected = append(inspected, arg.Inspect())
+	}
+	r.events = append(r.events, fmt.Sprintf("call %s(%s) env=%t", name(fn), strings.Join(inspected, ", "), env != nil))
+}
+
+func (r *recorder) Return(fn object.Object, result object.Object) {
+	value := "<tail>"
+	if result != nil {
+		value = result.Inspect()
+	}
+	r.events = append(r.events, fmt.Sprintf("return %s %s", name(fn), value))
+}
+
+func name(fn object.Object) string {
+	if f, ok := fn.(*object.Function); ok {
+		return fmt.Sprintf("fn/%d", len(f.Parameters))
+	}
+	return "builtin"
+}
+
+func TestHook(t *testing.T) {
+	input := `let f = fn(n) {
+	if (n == 0) { len("ab") } else { f(n - 1) }
+};
+let g = fn(a, b) { a + f(b) };
+g(1, 1)`
+	r := &recorder{}
+	c := NewContext(context.Background(), Limits{})
+	c.SetHook(r)
+	result := evalWithContext(t, c, input)
+	testIntegerObject(t, result, 3)
+	expected := []string{
+		"call fn/2(1, 1) env=true",
+		"call fn/1(1) env=true",
+		"return fn/1 <tail>",
+		"call fn/1(0) env=true",
+		"return fn/1 <tail>",
+		"call builtin(ab) env=false",
+		"return builtin 2",
+		"return fn/2 3",
+	}
+	if got := strings.Join(r.events, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("expected events\n%s\ngot\n%s", strings.Join(expected, "\n"), got)
+	}
+	if got := fmt.Sprint(r.lines); got != "[1 4 5 4 2 2 2 2]" {
+		t.Errorf("wrong statement lines %s", got)
+	}
+}
+
+func TestHookError(t *testing.T) {
+	r := &recorder{stopAt: 2}
+	c := NewContext(context.Background(), Limits{})
+	c.SetHook(r)
+	result := evalWithContext(t, c, "let x = 1;\nlet y = 2;\nx + y")
+	if c.Err() == nil || c.Err().Error() != "stopped" {
+		t.Fatalf("expected the hook's error, got %v", c.Err())
+	}
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "stopped" {
+		t.Errorf("expected an error result, got %v", result)
+	}
+}
@@ -0,0 +1,278 @@
+package evaluate
+
+import (
+	"fmt"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// evalEnumStatement binds the enum and each of its variants, so that both
+// Circle(1) and Shape.Circle(1) construct a circle.
+func (c *Context) evalEnumStatement(node *ast.EnumStatement, env *object.Environment) object.Object {
+	if err := c.alloc(int64(len(node.Variants)) + 1); err != nil {
+		return err
+	}
+	enum := &object.Enum{Name: node.Name.Value, Variants: map[string]object.Object{}}
+	for _, variant := range node.Variants {
+		s := &object.Struct{Name: variant.Name.Value, Enum: enum}
+		if variant.Fields == nil {
+			enum.Variants[s.Name] = object.NewInstance(s, nil)
+		} else {
+			s.Fields = []string{}
+			for _, field := range variant.Fields {
+				s.Fields = append(s.Fields, field.Value)
+			}
+			enum.Variants[s.Name] = s
+		}
+		if err := env.Declare(s.Name, enum.Variants[s.Name], false); err != nil {
+			return newError("%s", err)
+		}
+	}
+	if err := env.Declare(enum.Name, enum, false); err != nil {
+		return newError("%s", err)
+	}
+	return nil
+}
+
+func (c *Context) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	arm, armEnv, err := c.matchArm(node, env)
+	if err != nil {
+		return err
+	}
+	return c.Eval(arm.Body, armEnv)
+}
+
+// matchArm returns the first arm whose pattern matches the subject and whose
+// guard holds, with an environment holding the names the pattern binds.
+func (c *Context) matchArm(node *ast.MatchExpression, env *object.Environment) (*ast.MatchArm, *object.Environment, object.Object) {
+	subject := c.Eval(node.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+	for _, arm := range node.Arms {
+		if err := c.alloc(1); err != nil {
+			return nil, nil, err
+		}
+		armEnv := object.NewEnclosedEnvironment(env)
+		mismatch, err := c.matchPattern(arm.Pattern, subject, armEnv, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mismatch != "" {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := c.Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return arm, armEnv, nil
+	}
+	return nil, nil, newError("non-exhaustive match: no arm matches %s", subject.Inspect())
+}
+
+// matchPattern matches value against pattern, binding names in env as it
+// goes. It returns why the value does not match, or "" if it does. A failed
+// match may leave some names bound. arm is set for the pattern of a match
+// arm, where a bare name may stand for a variant; a let always binds.
+func (c *Context) matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment, arm bool) (string, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return "", nil
+	case *ast.BindingPattern:
+		// the name of a variant without fields, such as Empty, matches that
+		// variant instead of binding. Like the resolver, only a name that
+		// still refers to the variant counts, not another name bound to it.
+		if bound, ok := env.Get(pattern.Name.Value); arm && ok && isVariant(bound, pattern.Name.Value) {
+			if bound != value {
+				return fmt.Sprintf("expected %s, got %s", bound.Inspect(), value.Inspect()), nil
+			}
+			return "", nil
+		}
+		env.Set(pattern.Name.Value, value)
+		return "", nil
+	case *ast.LiteralPattern:
+		if !literalMatches(pattern.Value, value) {
+			return fmt.Sprintf("expected %s, got %s", pattern.String(), value.Inspect()), nil
+		}
+		return "", nil
+	case *ast.DefaultPattern:
+		return c.matchPattern(pattern.Pattern, value, env, arm)
+	case *ast.ArrayPattern:
+		return c.matchArrayPattern(pattern, value, env, arm)
+	case *ast.HashPattern:
+		switch value.(type) {
+		case *object.Hash, *object.Instance:
+		default:
+			return fmt.Sprintf("expected HASH, got %s", value.Type()), nil
+		}
+		for _, pair := range pattern.Pairs {
+			field, ok := fieldOf(value, pair.Key.Value)
+			if !ok {
+				withDefault, hasDefault := pair.Value.(*ast.DefaultPattern)
+				if !hasDefault {
+					return fmt.Sprintf("missing field %s", pair.Key.Value), nil
+				}
+				if field = c.Eval(withDefault.Default, env); isError(field) {
+					return "", field
+				}
+			}
+			if mismatch, err := c.matchPattern(pair.Value, field, env, arm); mismatch != "" || err != nil {
+				return mismatch, err
+			}
+		}
+		return "", nil
+	case *ast.ConstructorPattern:
+		constructor := c.Eval(pattern.Constructor, env)
+		if isError(constructor) {
+			return "", constructor
+		}
+		if pattern.Fields == nil {
+			if !isEnumValue(constructor) {
+				return "", newError("%s is not a variant without fields", pattern.Constructor.String())
+			}
+			if constructor != value {
+				return fmt.Sprintf("expected %s, got %s", constructor.Inspect(), value.Inspect()), nil
+			}
+			return "", nil
+		}
+		s, ok := constructor.(*object.Struct)
+		if !ok {
+			return "", newError("%s is not a constructor", pattern.Constructor.String())
+		}
+		if len(pattern.Fields) != len(s.Fields) {
+			return "", newError("wrong number of fields in pattern for %s: want=%d, got=%d", s.Name, len(s.Fields), len(pattern.Fields))
+		}
+		instance, ok := value.(*object.Instance)
+		if !ok || instance.Struct != s {
+			return fmt.Sprintf("expected %s, got %s", s.Name, value.Inspect()), nil
+		}
+		for i, field := range instance.Values() {
+			if mismatch, err := c.matchPattern(pattern.Fields[i], field, env, arm); mismatch != "" || err != nil {
+				return mismatch, err
+			}
+		}
+		return "", nil
+	}
+	return "", newError("unknown pattern %s", pattern.String())
+}
+
+// matchArrayPattern matches the elements of an array in order. Elements
+// after the last one without a default may be missing, and ...rest takes
+// any left over.
+func (c *Context) matchArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment, arm bool) (string, object.Object) {
+	arr, ok := value.(*object.Array)
+	if !ok {
+		return fmt.Sprintf("expected ARRAY, got %s", value.Type()), nil
+	}
+	required := 0
+	for i, element := range pattern.Elements {
+		if _, ok := element.(*ast.DefaultPattern); !ok {
+			required = i + 1
+		}
+	}
+	n := len(arr.Elements)
+	switch {
+	case pattern.Rest == nil && required == len(pattern.Elements) && n != required:
+		return fmt.Sprintf("expected %d elements, got %d", required, n), nil
+	case n < required:
+		return fmt.Sprintf("expected at least %d elements, got %d", required, n), nil
+	case pattern.Rest == nil && n > len(pattern.Elements):
+		return fmt.Sprintf("expected at most %d elements, got %d", len(pattern.Elements), n), nil
+	}
+	for i, element := range pattern.Elements {
+		var ele object.Object
+		if i < n {
+			ele = arr.Elements[i]
+		} else if ele = c.Eval(element.(*ast.DefaultPattern).Default, env); isError(ele) {
+			return "", ele
+		}
+		if mismatch, err := c.matchPattern(element, ele, env, arm); mismatch != "" || err != nil {
+			return mismatch, err
+		}
+	}
+	if pattern.Rest == nil {
+		return "", nil
+	}
+	rest := []object.Object{}
+	if n > len(pattern.Elements) {
+		rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+	}
+	if err := c.alloc(int64(len(rest)) + 1); err != nil {
+		return "", err
+	}
+	return c.matchPattern(pattern.Rest, &object.Array{Elements: rest}, env, arm)
+}
+
+// destructure binds the names in the pattern of a let statement. Unlike a
+// match arm, a value that does not fit the pattern is an error.
+func (c *Context) destructure(node *ast.LetStatement, value object.Object, env *object.Environment) object.Object {
+	if err := c.alloc(1); err != nil {
+		return err
+	}
+	// bind into a scope of our own first, so that nothing is bound when
+	// the value does not fit
+	scope := object.NewEnclosedEnvironment(env)
+	mismatch, err := c.matchPattern(node.Pattern, value, scope, false)
+	if err != nil {
+		return err
+	}
+	if mismatch != "" {
+		return newError("cannot destructure %s with %s: %s", value.Inspect(), node.Pattern.String(), mismatch)
+	}
+	for _, name := range node.Names() {
+		if value, ok := scope.Get(name.Value); ok {
+			if err := env.Declare(name.Value, value, node.Constant()); err != nil {
+				return newError("%s", err)
+			}
+		}
+	}
+	return nil
+}
+
+func isEnumValue(obj object.Object) bool {
+	instance, ok := obj.(*object.Instance)
+	return ok && instance.Struct.Enum != nil && instance.Struct.Fields == nil
+}
+
+// isVariant reports whether obj is the variant without fields called name.
+func isVariant(obj object.Object, name string) bool {
+	return isEnumValue(obj) && obj.(*object.Instance).Struct.Name == name
+}
+
+// literalMatches compares value with a literal pattern without evaluating
+// it, which would allocate.
+func literalMatches(literal ast.ExpressionNode, value object.Object) bool {
+	switch literal := literal.(type) {
+	case *ast.IntegerLiteral:
+		n, ok := value.(*object.Integer)
+		return ok && n.Value == literal.Value
+	case *ast.PrefixExpression:
+		n, ok := value.(*object.Integer)
+		return ok && n.Value == -literal.Right.(*ast.IntegerLiteral).Value
+	case *ast.String:
+		s, ok := value.(*object.String)
+		return ok && s.Value == literal.Value
+	case *ast.Boolean:
+		return value == nativeBooltoBooleanObject(literal.Value)
+	}
+	return false
+}
+
+// fieldOf reads the field called name of a hash or a struct instance.
+func fieldOf(obj object.Object, name string) (object.Object, bool) {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		key := &object.String{Value: name}
+		pair, ok := obj.Pairs[key.HashKey()]
+		return pair.Value, ok
+	case *object.Instance:
+		return obj.Get(name)
+	}
+	return nil, false
+}
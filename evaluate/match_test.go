@@ -0,0 +1,98 @@
+package evaluate
+
+import "testing"
+
+const shapeEnum = `enum Shape { Circle(r), Rect(w, h), Empty };
+let area = fn(s) {
+	match s {
+		Circle(r) => 3 * r * r,
+		Rect(w, h) if w == h => w * w,
+		Shape.Rect(w, h) => w * h,
+		Empty => 0,
+	}
+};
+`
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match 1 { 1 => "one", _ => "many" }`, "one"},
+		{`match 5 { 1 => "one", _ => "many" }`, "many"},
+		{`match -2 { -2 => true, _ => false }`, true},
+		{`match "b" { "a" => 1, "b" => 2 }`, 2},
+		{`match false { true => 1, false => 0 }`, 0},
+		{`match 7 { n => n * 2 }`, 14},
+		{`match 7 { n if n > 10 => "big", n if n > 5 => "medium", _ => "small" }`, "medium"},
+		{`match [1, 2] { [a] => a, [a, b] => a + b }`, 3},
+		{`match [] { [] => "empty", _ => "more" }`, "empty"},
+		{`match [1, 2, 3] { [a, ...rest] => len(rest) + first(rest) }`, 4},
+		{`match [1] { [a, b, ...rest] => 0, [a, ..._] => a }`, 1},
+		{`match [[1, 2], 3] { [[a, b], c] => a + b + c }`, 6},
+		{`match {"kind": "circle", "r": 2} { {"kind": "square"} => 0, {"kind": "circle", r} => r }`, 2},
+		{`match {"a": 1} { {b} => b, {a: x} => x * 10 }`, 10},
+		{`match 1 { [a] => a, {a} => a, _ => "neither" }`, "neither"},
+		{`let x = 1; match 2 { x => x }; x`, 1},
+		{`let f = fn() { match 1 { 1 => { return 5; 6 } } }; f()`, 5},
+		{`match 1 { 2 => 3 }`, errorMessage("non-exhaustive match: no arm matches 1")},
+		{`match [1, 2] { [a] => a }`, errorMessage("non-exhaustive match: no arm matches [1,2]")},
+		{`match 1 { n if n + "a" => n }`, errorMessage("Operands are not of the same type : INTEGER + STRING")},
+		{`let loop = fn(n) { match n { 0 => "done", _ => loop(n - 1) } }; loop(100000)`, "done"},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`area(Circle(2))`, 12},
+		{`area(Rect(3, 3))`, 9},
+		{`area(Shape.Rect(2, 3))`, 6},
+		{`area(Empty)`, 0},
+		{`area(Shape.Empty)`, 0},
+		{`Circle(2).r`, 2},
+		{`type(Circle(1))`, "Shape"},
+		{`type(Empty)`, "Shape"},
+		{`Circle(1) == Circle(1)`, true},
+		{`Circle(1) == Rect(1, 1)`, false},
+		{`Empty == Shape.Empty`, true},
+		{`match Rect(1, 2) { Circle(_) => "circle", _ => "other" }`, "other"},
+		{`enum Color { Red, Green }; match Green { Red => "red", Green => "green" }`, "green"},
+		{`enum Color { Red, Green }; match Green { Color.Red => "red", Color.Green => "green" }`, "green"},
+		// c names a variable holding a variant, so it binds like any name
+		{`enum Color { Red, Green }; let c = Red; match Green { c => "bound", _ => "fell through" }`, "bound"},
+		{`match Circle(1) { Shape.Empty => "empty", _ => "other" }`, "other"},
+		{`area(1)`, errorMessage("non-exhaustive match: no arm matches 1")},
+		{`Circle()`, errorMessage("wrong number of fields for Circle: want=1, got=0")},
+		{`Shape.Square`, errorMessage("enum Shape has no variant Square")},
+		{`match Circle(1) { Circle(a, b) => a }`, errorMessage("wrong number of fields in pattern for Circle: want=1, got=2")},
+		{`let f = 1; match 1 { f(x) => x }`, errorMessage("f is not a constructor")},
+		{`match 1 { Square(x) => x }`, errorMessage("identifier not found Square")},
+		{`match 1 { Shape.Circle => 1 }`, errorMessage("(Shape.Circle) is not a variant without fields")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, shapeEnum+tt.input), tt.expected)
+	}
+}
+
+func TestEnumInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Rect(1, "a")`, "Rect(1,a)"},
+		{`Empty`, "Empty"},
+		{`Circle`, "Shape.Circle"},
+		{`Shape`, "enum Shape"},
+	}
+	for _, tt := range tests {
+		if actual := testEval(t, shapeEnum+tt.input).Inspect(); actual != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package evaluate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// method implements value.name(args) for one type, receiving the value the
+// method was looked up on.
+type method func(c *Context, receiver object.Object, args []object.Object) object.Object
+
+// methods holds the method table of each type that has methods. It is
+// filled in init since methods such as map call back into the evaluator.
+var methods map[object.ObjectType]map[string]method
+
+func init() {
+	methods = map[object.ObjectType]map[string]method{
+		object.STRING_OBJ: stringMethods,
+		object.ARRAY_OBJ:  arrayMethods,
+		object.HASH_OBJ:   hashMethods,
+	}
+}
+
+// contextBuiltin is a builtin that runs on the Context applying it rather
+// than on the one it was looked up with, so that it can be handed to another
+// task: a method bound to its receiver, or a concurrency builtin.
+type contextBuiltin struct {
+	name     string
+	receiver object.Object //nil for a concurrency builtin
+	fn       method
+}
+
+func (b *contextBuiltin) Type() object.ObjectType { return object.BUILTIN_OBJ }
+func (b *contextBuiltin) Inspect() string         { return "Builtin function" }
+
+// lookupMethod returns the method name of receiver bound to it, so that
+// both s.upper() and let up = s.upper; up() work.
+func (c *Context) lookupMethod(receiver object.Object, name string) object.Object {
+	m, ok := methods[receiver.Type()][name]
+	if !ok {
+		return newError("undefined method %s for %s", name, receiver.Type())
+	}
+	return &contextBuiltin{name: name, receiver: receiver, fn: m}
+}
+
+func wantArgs(name string, args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newError("Wrong Number of args to '%s', want %d, got %d", name, want, len(args))
+	}
+	return nil
+}
+
+func stringArg(name string, arg object.Object) (string, *object.Error) {
+	s, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("argument to '%s' must be STRING, got %s", name, arg.Type())
+	}
+	return s.Value, nil
+}
+
+func functionArg(name string, arg object.Object) *object.Error {
+	switch arg.(type) {
+	case *object.Function, *object.Builtin, *contextBuiltin:
+		return nil
+	default:
+		return newError("argument to '%s' must be FUNCTION, got %s", name, arg.Type())
+	}
+}
+
+// stringFunc adapts a func(string) string to a method without arguments.
+func stringFunc(name string, fn func(string) string) method {
+	return func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs(name, args, 0); err != nil {
+			return err
+		}
+		return &object.String{Value: fn(receiver.(*object.String).Value)}
+	}
+}
+
+// stringTest adapts a func(s, arg string) bool to a method taking one
+// string.
+func stringTest(name string, fn func(string, string) bool) method {
+	return func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs(name, args, 1); err != nil {
+			return err
+		}
+		arg, err := stringArg(name, args[0])
+		if err != nil {
+			return err
+		}
+		return nativeBooltoBooleanObject(fn(receiver.(*object.String).Value, arg))
+	}
+}
+
+var stringMethods = map[string]method{
+	"len": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("len", args, 0); err != nil {
+			return err
+		}
+		return &object.Integer{Value: int64(len(receiver.(*object.String).Value))}
+	},
+	"upper":       stringFunc("upper", strings.ToUpper),
+	"lower":       stringFunc("lower", strings.ToLower),
+	"trim":        stringFunc("trim", strings.TrimSpace),
+	"contains":    stringTest("contains", strings.Contains),
+	"starts_with": stringTest("starts_with", strings.HasPrefix),
+	"ends_with":   stringTest("ends_with", strings.HasSuffix),
+	"split": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("split", args, 1); err != nil {
+			return err
+		}
+		sep, err := stringArg("split", args[0])
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(receiver.(*object.String).Value, sep)
+		elements := make([]object.Object, len(parts))
+		for i, part := range parts {
+			elements[i] = &object.String{Value: part}
+		}
+		return &object.Array{Elements: elements}
+	},
+	"replace": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("replace", args, 2); err != nil {
+			return err
+		}
+		old, err := stringArg("replace", args[0])
+		if err != nil {
+			return err
+		}
+		replacement, err := stringArg("replace", args[1])
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: strings.ReplaceAll(receiver.(*object.String).Value, old, replacement)}
+	},
+}
+
+var arrayMethods = map[string]method{
+	"len": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("len", args, 0); err != nil {
+			return err
+		}
+		return &object.Integer{Value: int64(len(receiver.(*object.Array).Elements))}
+	},
+	"first": builtinMethod("first"),
+	"last":  builtinMethod("last"),
+	"rest":  builtinMethod("rest"),
+	"push":  builtinMethod("push"),
+	"join": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("join", args, 1); err != nil {
+			return err
+		}
+		sep, err := stringArg("join", args[0])
+		if err != nil {
+			return err
+		}
+		parts := []string{}
+		for _, ele := range receiver.(*object.Array).Elements {
+			if s, ok := ele.(*object.String); ok {
+				parts = append(parts, s.Value)
+			} else {
+				parts = append(parts, ele.Inspect())
+			}
+		}
+		return &object.String{Value: strings.Join(parts, sep)}
+	},
+	"contains": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("contains", args, 1); err != nil {
+			return err
+		}
+		for _, ele := range receiver.(*object.Array).Elements {
+			if objectsEqual(ele, args[0]) {
+				return TRUE
+			}
+		}
+		return FALSE
+	},
+	"reverse": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("reverse", args, 0); err != nil {
+			return err
+		}
+		elements := receiver.(*object.Array).Elements
+		reversed := make([]object.Object, len(elements))
+		for i, ele := range elements {
+			reversed[len(elements)-1-i] = ele
+		}
+		return &object.Array{Elements: reversed}
+	},
+	"map": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("map", args, 1); err != nil {
+			return err
+		}
+		if err := functionArg("map", args[0]); err != nil {
+			return err
+		}
+		elements := receiver.(*object.Array).Elements
+		mapped := make([]object.Object, len(elements))
+		for i, ele := range elements {
+			value := c.applyFunction(args[0], []object.Object{ele})
+			if isError(value) {
+				return value
+			}
+			mapped[i] = value
+		}
+		return &object.Array{Elements: mapped}
+	},
+	"filter": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("filter", args, 1); err != nil {
+			return err
+		}
+		if err := functionArg("filter", args[0]); err != nil {
+			return err
+		}
+		kept := []object.Object{}
+		for _, ele := range receiver.(*object.Array).Elements {
+			keep := c.applyFunction(args[0], []object.Object{ele})
+			if isError(keep) {
+				return keep
+			}
+			if isTruthy(keep) {
+				kept = append(kept, ele)
+			}
+		}
+		return &object.Array{Elements: kept}
+	},
+	"reduce": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("reduce", args, 2); err != nil {
+			return err
+		}
+		if err := functionArg("reduce", args[0]); err != nil {
+			return err
+		}
+		acc := args[1]
+		for _, ele := range receiver.(*object.Array).Elements {
+			acc = c.applyFunction(args[0], []object.Object{acc, ele})
+			if isError(acc) {
+				return acc
+			}
+		}
+		return acc
+	},
+}
+
+// builtinMethod makes the builtin called name, which takes the array as its
+// first argument, available as a method.
+func builtinMethod(name string) method {
+	builtin := object.GetBuiltinByName(name)
+	return func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		return builtin.Fn(append([]object.Object{receiver}, args...)...)
+	}
+}
+
+// sortedPairs returns the pairs of a hash ordered by key, so that keys()
+// and values() are deterministic.
+func sortedPairs(hash *object.Hash) []object.HashPair {
+	pairs := make([]object.HashPair, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+	return pairs
+}
+
+var hashMethods = map[string]method{
+	"len": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("len", args, 0); err != nil {
+			return err
+		}
+		return &object.Integer{Value: int64(len(receiver.(*object.Hash).Pairs))}
+	},
+	"keys": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("keys", args, 0); err != nil {
+			return err
+		}
+		pairs := sortedPairs(receiver.(*object.Hash))
+		keys := make([]object.Object, len(pairs))
+		for i, pair := range pairs {
+			keys[i] = pair.Key
+		}
+		return &object.Array{Elements: keys}
+	},
+	"values": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("values", args, 0); err != nil {
+			return err
+		}
+		pairs := sortedPairs(receiver.(*object.Hash))
+		values := make([]object.Object, len(pairs))
+		for i, pair := range pairs {
+			values[i] = pair.Value
+		}
+		return &object.Array{Elements: values}
+	},
+	"has": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("has", args, 1); err != nil {
+			return err
+		}
+		key, ok := args[0].(object.Hashable)
+		if !ok {
+			return newError("Index unusable as hashKey %s", args[0].Type())
+		}
+		_, ok = receiver.(*object.Hash).Pairs[key.HashKey()]
+		return nativeBooltoBooleanObject(ok)
+	},
+	"get": func(c *Context, receiver object.Object, args []object.Object) object.Object {
+		if err := wantArgs("get", args, 2); err != nil {
+			return err
+		}
+		key, ok := args[0].(object.Hashable)
+		if !ok {
+			return newError("Index unusable as hashKey %s", args[0].Type())
+		}
+		if pair, ok := receiver.(*object.Hash).Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+		return args[1]
+	},
+}
+
+// objectsEqual compares integers, strings and struct instances by value
+// and everything else by identity, as == does.
+func objectsEqual(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Integer:
+		b, ok := b.(*object.Integer)
+		return ok && a.Value == b.Value
+	case *object.String:
+		b, ok := b.(*object.String)
+		return ok && a.Value == b.Value
+	case *object.Instance:
+		b, ok := b.(*object.Instance)
+		return ok && instancesEqual(a, b)
+	}
+	return a == b
+}
+
+// evalMemberExpression reads x.name: a module export, a hash field (a
+// missing one reads as null, like h["name"]), a field or method of a struct
+// instance, a variant of an enum or a method of x's type.
+// Hash fields take precedence over hash methods of the same name.
+func (c *Context) evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := c.Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	name := node.Property.Value
+	switch obj := obj.(type) {
+	case *object.Module:
+		value, ok := obj.Exports[name]
+		if !ok {
+			return newError("module %s has no export %s", obj.Name, name)
+		}
+		return value
+	case *object.Hash:
+		key := &object.String{Value: name}
+		if pair, ok := obj.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+		if _, ok := hashMethods[name]; ok {
+			return c.lookupMethod(obj, name)
+		}
+		return NULL
+	case *object.Instance:
+		return c.instanceMember(obj, name)
+	case *object.Enum:
+		variant, ok := obj.Variants[name]
+		if !ok {
+			return newError("enum %s has no variant %s", obj.Name, name)
+		}
+		return variant
+	}
+	return c.lookupMethod(obj, name)
+}
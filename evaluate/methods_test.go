@@ -0,0 +1,104 @@
+package evaluate
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMemberAccess(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let person = {"name": "Ada", "age": 36}; person.name`, "Ada"},
+		{`{"age": 36}.age + 1`, 37},
+		{`{"a": {"b": 2}}.a.b`, 2},
+		{`{"a": 1}.missing`, nil},
+		{`{"keys": 5}.keys`, 5},
+		{`let point = {"scale": fn(k) { k * 10 }}; point.scale(3)`, 30},
+		{`let up = "abc".upper; up()`, "ABC"},
+		{`let n = 1; n.foo`, errorMessage("undefined method foo for INTEGER")},
+		{`"abc".foo()`, errorMessage("undefined method foo for STRING")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello".len()`, "5"},
+		{`"Hello".upper()`, "HELLO"},
+		{`"Hello".lower()`, "hello"},
+		{`"  hi  ".trim()`, "hi"},
+		{`"a,b,c".split(",")`, "[a,b,c]"},
+		{`"hello".contains("ell")`, "true"},
+		{`"hello".starts_with("he")`, "true"},
+		{`"hello".ends_with("he")`, "false"},
+		{`"a-b-c".replace("-", "+")`, "a+b+c"},
+		{`[1, 2, 3].len()`, "3"},
+		{`[1, 2, 3].push(4)`, "[1,2,3,4]"},
+		{`[1, 2, 3].first() + [1, 2, 3].last()`, "4"},
+		{`[1, 2, 3].rest()`, "[2,3]"},
+		{`[1, 2, 3].reverse()`, "[3,2,1]"},
+		{`["a", 1, true].join("-")`, "a-1-true"},
+		{`[1, 2, 3].contains(2)`, "true"},
+		{`["x"].contains("y")`, "false"},
+		{`[1, 2, 3].map(fn(x) { x * x })`, "[1,4,9]"},
+		{`[1, 2, 3, 4].filter(fn(x) { x > 2 })`, "[3,4]"},
+		{`[1, 2, 3, 4].reduce(fn(acc, x) { acc + x }, 0)`, "10"},
+		{`[1, 2, 3].map(fn(x) { x * 2 }).filter(fn(x) { x > 2 }).len()`, "2"},
+		{`"a b".split(" ").map(len)`, "[1,1]"},
+		{`{"b": 2, "a": 1}.keys()`, "[a,b]"},
+		{`{"b": 2, "a": 1}.values()`, "[1,2]"},
+		{`{"a": 1}.len()`, "1"},
+		{`{"a": 1}.has("a")`, "true"},
+		{`{"a": 1}.get("b", 0)`, "0"},
+		{`{"a": 1}.get("a", 0)`, "1"},
+	}
+	for _, tt := range tests {
+		if actual := testEval(t, tt.input).Inspect(); actual != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a".upper(1)`, "Wrong Number of args to 'upper', want 0, got 1"},
+		{`"a".split(1)`, "argument to 'split' must be STRING, got INTEGER"},
+		{`[1].map(1)`, "argument to 'map' must be FUNCTION, got INTEGER"},
+		{`[1].map(fn(x) { x + "a" })`, "Operands are not of the same type : INTEGER + STRING"},
+		{`{"a": 1}.has([1])`, "Index unusable as hashKey ARRAY"},
+		{`[1].push()`, "Wrong Number of args, want 2, got 1"},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), errorMessage(tt.expected))
+	}
+}
+
+// A method looked up by one task and called by others runs on the Context
+// of the task calling it: here the calls map makes are left out of the
+// hook, which only follows the main program.
+func TestMethodsRunOnCaller(t *testing.T) {
+	r := &recorder{}
+	c := NewContext(context.Background(), Limits{})
+	c.SetHook(r)
+	input := `let double = fn(x) { x * 2 };
+	let m = [1, 2, 3].map;
+	let a = spawn m(double); let b = spawn m(double);
+	await(a)[2] + await(b)[1]`
+	testObject(t, evalWithContext(t, c, input), 10)
+	for _, event := range r.events {
+		if strings.Contains(event, "fn/1") {
+			t.Errorf("unexpected %s in the main program", event)
+		}
+	}
+}
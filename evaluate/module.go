@@ -0,0 +1,122 @@
+package evaluate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+// ModuleExt is appended to import paths that have no extension.
+const ModuleExt = ".mk"
+
+// Modules loads the files named by import statements. Each file is
+// evaluated once, the first time it is imported, and later imports share
+// the resulting module. Relative paths are resolved against the directory
+// of the importing file, or Dir for the script being run.
+// Modules is not safe for concurrent use.
+type Modules struct {
+	Dir string
+
+	cache   map[string]*object.Module //by absolute path
+	loading []string                  //the chain of imports being evaluated
+}
+
+func NewModules(dir string) *Modules {
+	return &Modules{Dir: dir, cache: map[string]*object.Module{}}
+}
+
+// SetModules makes import use modules, so that a cache can be kept across
+// evaluations. Without it each Context loads modules afresh, relative to
+// the working directory.
+func (c *Context) SetModules(modules *Modules) {
+	c.modules = modules
+}
+
+func (c *Context) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	if c.modules == nil {
+		c.modules = NewModules("")
+	}
+	module := c.modules.load(c, node.Path.Value)
+	if isError(module) {
+		return module
+	}
+	if err := env.Declare(node.Name.Value, module, false); err != nil {
+		return newError("%s", err)
+	}
+	return nil
+}
+
+func (m *Modules) load(c *Context, importPath string) object.Object {
+	dir := m.Dir
+	if len(m.loading) > 0 {
+		dir = filepath.Dir(m.loading[len(m.loading)-1])
+	}
+	path := filepath.FromSlash(importPath)
+	if filepath.Ext(path) == "" {
+		path += ModuleExt
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return newError("cannot import %q: %s", importPath, err)
+	}
+	if module, ok := m.cache[path]; ok {
+		return module
+	}
+	for i, loading := range m.loading {
+		if loading == path {
+			return newError("import cycle: %s", m.chain(append(m.loading[i:], path)))
+		}
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return newError("cannot import %q: %s", importPath, err)
+	}
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("cannot import %q: %s", importPath, strings.Join(p.Errors(), "; "))
+	}
+
+	m.loading = append(m.loading, path)
+	defer func() { m.loading = m.loading[:len(m.loading)-1] }()
+	env := object.NewEnvironment()
+	if result := c.Eval(program, env); isError(result) {
+		return result
+	}
+	module := &object.Module{
+		Name:    strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path:    path,
+		Exports: map[string]object.Object{},
+	}
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			for _, name := range export.Statement.Names() {
+				module.Exports[name.Value], _ = env.Get(name.Value)
+			}
+		}
+	}
+	m.cache[path] = module
+	return module
+}
+
+// chain lists the files of an import cycle relative to Dir, which keeps the
+// message readable.
+func (m *Modules) chain(paths []string) string {
+	base, err := filepath.Abs(m.Dir)
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = path
+		if rel, relErr := filepath.Rel(base, path); err == nil && relErr == nil {
+			names[i] = rel
+		}
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,88 @@
+package evaluate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/object"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/shapes.mk": `
+			import "sizes";
+			tick();
+			let double = fn(x) { x * 2 };
+			export let area = fn(w, h) { double(w * h) / 2 };
+			export let unit = sizes.unit;`,
+		"lib/sizes.mk": `export let unit = 10;`,
+		"cycle/a.mk":   `import "b"; export let a = 1;`,
+		"cycle/b.mk":   `import "a"; export let b = 2;`,
+		"broken.mk":    `let = 1;`,
+		"failing.mk":   `export let x = 1 + true;`,
+	})
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/shapes"; shapes.area(3, 4)`, 12},
+		{`import "lib/shapes" as s; s.unit`, 10},
+		{`import "lib/shapes.mk"; import "lib/shapes" as again; again.area(1, 1) + shapes.unit`, 11},
+		{`import "lib/shapes"; let f = fn() { shapes.area(2, 2) }; f()`, 4},
+		{`import "lib/shapes"; shapes.double`, errorMessage("module shapes has no export double")},
+		{`let n = 5; n.a`, errorMessage("undefined method a for INTEGER")},
+		{`import "cycle/a"; a.a`, errorMessage("import cycle: " + filepath.Join("cycle", "a.mk") + " -> " +
+			filepath.Join("cycle", "b.mk") + " -> " + filepath.Join("cycle", "a.mk"))},
+		{`import "missing"`, errorMessage("cannot import \"missing\": open " + filepath.Join(dir, "missing.mk") + ": no such file or directory")},
+		{`import "broken"`, errorMessage("cannot import \"broken\": expected next token IDENT , got token =; no prefix func found for =")},
+		{`import "failing"`, errorMessage("Operands are not of the same type : INTEGER + BOOLEAN")},
+	}
+	for _, tt := range tests {
+		ticks := 0
+		c := NewContext(context.Background(), Limits{})
+		c.SetModules(NewModules(dir))
+		c.SetBuiltin("tick", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			ticks++
+			return NULL
+		}})
+		testObject(t, evalWithContext(t, c, tt.input), tt.expected)
+		if ticks > 1 {
+			t.Errorf("%q: module evaluated %d times", tt.input, ticks)
+		}
+	}
+}
+
+func TestModuleCacheAcrossEvaluations(t *testing.T) {
+	dir := writeModules(t, map[string]string{"counter.mk": `tick(); export let n = 1;`})
+	modules := NewModules(dir)
+	ticks := 0
+	for i := 0; i < 3; i++ {
+		c := NewContext(context.Background(), Limits{})
+		c.SetModules(modules)
+		c.SetBuiltin("tick", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			ticks++
+			return NULL
+		}})
+		testObject(t, evalWithContext(t, c, `import "counter"; counter.n`), 1)
+	}
+	if ticks != 1 {
+		t.Errorf("expected the module to be evaluated once, got %d", ticks)
+	}
+}
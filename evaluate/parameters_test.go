@@ -0,0 +1,43 @@
+package evaluate
+
+import "testing"
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x, y = 10) { x + y }; f(1)`, 11},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2)`, 3},
+		{`let f = fn(a = 1, b = a * 2) { b }; f()`, 2},
+		{`let f = fn(a = 1, b = a * 2) { b }; f(5)`, 10},
+		{`let f = fn(first, ...others) { len(others) }; f(1, 2, 3)`, 2},
+		{`let f = fn(first, ...others) { len(others) }; f(1)`, 0},
+		{`let f = fn(...all) { all.reduce(fn(a, b) { a + b }, 0) }; f(1, 2, 3)`, 6},
+		{`let f = fn(x, y) { x - y }; f(...[5, 2])`, 3},
+		{`let f = fn(...xs) { len(xs) }; f(1, ...[2, 3], ...[], 4)`, 4},
+		{`let f = fn(x, y) { x - y }; f(y: 2, x: 5)`, 3},
+		{`let f = fn(x, y = 1, z = 2) { x * 100 + y * 10 + z }; f(1, z: 5)`, 115},
+		{`let f = fn(x, y) { x - y }; let g = fn() { f(y: 1, x: 3) }; g()`, 2},
+		{`struct P { x, y }; P(y: 2, x: 1).x`, 1},
+		{`struct P { x, y }; P(1, y: 2).y`, 2},
+		{`struct P { x fn add(n = 1) { self.x + n } }; P(1).add() + P(1).add(n: 5)`, 8},
+		{`let count = fn(n, acc = 0) { if (n == 0) { acc } else { count(n - 1, acc: acc + 1) } }; count(100000)`, 100000},
+		{`let f = fn(x, y) { x - y }; await(spawn f(y: 1, x: 3))`, 2},
+		{`let f = fn(x) { x }; f()`, errorMessage("wrong number of arguments: want=1, got=0")},
+		{`let f = fn(x) { x }; f(1, 2)`, errorMessage("wrong number of arguments: want=1, got=2")},
+		{`let f = fn(x, y = 1) { x }; f()`, errorMessage("wrong number of arguments: want=1 to 2, got=0")},
+		{`let f = fn(x, ...r) { x }; f()`, errorMessage("wrong number of arguments: want at least 1, got=0")},
+		{`let f = fn(x, y) { x }; f(y: 1)`, errorMessage("missing argument x")},
+		{`let f = fn(x) { x }; f(z: 1)`, errorMessage("unexpected argument z")},
+		{`let f = fn(x, y) { x }; f(1, x: 2)`, errorMessage("got multiple values for argument x")},
+		{`let f = fn(x, y = z) { x }; f(1)`, errorMessage("identifier not found z")},
+		{`let f = fn(x) { x }; f(...1)`, errorMessage("cannot spread INTEGER, want ARRAY")},
+		{`len(x: "a")`, errorMessage("BUILTIN does not take arguments by name")},
+		{`struct P { x, y }; P(1, x: 2)`, errorMessage("got multiple values for field x")},
+		{`struct P { x, y }; P(1, z: 2)`, errorMessage("P has no field z")},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,49 @@
+package evaluate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// TestResolvedLookups checks that evaluating with addresses from the
+// resolver gives the same results as looking names up the usual way.
+func TestResolvedLookups(t *testing.T) {
+	tests := []string{
+		"let a = 1; let f = fn(b) { let c = 2; fn() { a + b + c } }; f(3)()",
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)",
+		// x is bound locally only when the branch runs
+		"let x = 1; let f = fn(c) { let g = fn() { x }; if (c) { let x = 2; }; g() }; f(true) * 10 + f(false)",
+		"let f = fn(a, b = a * 2, ...rest) { a + b + len(rest) }; f(1) + f(1, 1, 1, 1)",
+		"let [a, b = a + 1] = [1]; let {x: c} = {\"x\": b}; a + b + c",
+		"struct P { x, fn add(y) { self.x + y } }; P(1).add(2)",
+		"enum E { A(v), B }; let f = fn(e) { match (e) { A(v) if v > 1 => v, A(v) => -v, B => 0 } }; f(A(2)) + f(A(5)) + f(B)",
+		"let n = 1; let inc = fn() { n = n + 1 }; inc(); inc(); n",
+		"let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(100, 0)",
+	}
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", input, p.Errors())
+		}
+		res := resolver.Resolve(program, IsBuiltin)
+		if errs := res.Errors(); len(errs) != 0 {
+			t.Fatalf("resolver errors for %q: %v", input, errs)
+		}
+		if len(res.Addresses) == 0 {
+			t.Errorf("%q: expected identifiers to be resolved", input)
+		}
+		c := NewContext(context.Background(), Limits{})
+		c.SetResolution(res)
+		resolved := c.Eval(program, object.NewEnvironment())
+		plain := Eval(program, object.NewEnvironment())
+		if resolved.Inspect() != plain.Inspect() {
+			t.Errorf("%q: expected %s, got %s", input, plain.Inspect(), resolved.Inspect())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package evaluate
+
+import (
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+func (c *Context) evalStructStatement(node *ast.StructStatement, env *object.Environment) object.Object {
+	if err := c.alloc(int64(len(node.Methods)) + 1); err != nil {
+		return err
+	}
+	s := &object.Struct{Name: node.Name.Value, Methods: map[string]*object.Function{}}
+	for _, field := range node.Fields {
+		s.Fields = append(s.Fields, field.Value)
+	}
+	for _, method := range node.Methods {
+		s.Methods[method.Name.Value] = &object.Function{
+			Parameters: method.Function.Parameters,
+			Defaults:   method.Function.Defaults,
+			Rest:       method.Function.Rest,
+			Body:       method.Function.Body,
+			Env:        env,
+		}
+	}
+	if err := env.Declare(s.Name, s, false); err != nil {
+		return newError("%s", err)
+	}
+	return nil
+}
+
+// construct is called for Point(1, 2) or Point(y: 2, x: 1), taking one
+// value per field.
+func (c *Context) construct(s *object.Struct, args []object.Object, keywords map[string]object.Object) object.Object {
+	if len(args)+len(keywords) != len(s.Fields) {
+		return newError("wrong number of fields for %s: want=%d, got=%d", s.Name, len(s.Fields), len(args)+len(keywords))
+	}
+	for name := range keywords {
+		idx, ok := s.FieldIndex(name)
+		if !ok {
+			return newError("%s has no field %s", s.Name, name)
+		}
+		if idx < len(args) {
+			return newError("got multiple values for field %s", name)
+		}
+	}
+	if err := c.alloc(int64(len(s.Fields)) + 1); err != nil {
+		return err
+	}
+	values := append(make([]object.Object, 0, len(s.Fields)), args...)
+	for _, field := range s.Fields[len(args):] {
+		values = append(values, keywords[field])
+	}
+	return object.NewInstance(s, values)
+}
+
+// instanceMember reads a field of instance, or a method bound to it by
+// enclosing the method's environment with self.
+func (c *Context) instanceMember(instance *object.Instance, name string) object.Object {
+	if value, ok := instance.Get(name); ok {
+		return value
+	}
+	method, ok := instance.Struct.Methods[name]
+	if !ok {
+		return newError("%s has no field %s", instance.Struct.Name, name)
+	}
+	if err := c.alloc(2); err != nil {
+		return err
+	}
+	env := object.NewEnclosedEnvironment(method.Env)
+	env.Set("self", instance)
+	return &object.Function{Parameters: method.Parameters, Defaults: method.Defaults, Rest: method.Rest, Body: method.Body, Env: env}
+}
+
+// sameType reports whether a and b have one type. Instances do when they
+// come from the same struct declaration, or are variants of the same enum,
+// so a struct named like a built-in type never passes for it.
+func sameType(a, b object.Object) bool {
+	ai, ok := a.(*object.Instance)
+	bi, bok := b.(*object.Instance)
+	if !ok || !bok {
+		return a.Type() == b.Type()
+	}
+	if ai.Struct.Enum != nil {
+		return ai.Struct.Enum == bi.Struct.Enum
+	}
+	return ai.Struct == bi.Struct
+}
+
+// typeOf names the type of obj in error messages, telling a struct or enum
+// apart from a built-in type of the same name.
+func typeOf(obj object.Object) string {
+	if instance, ok := obj.(*object.Instance); ok {
+		if instance.Struct.Enum != nil {
+			return "enum " + instance.TypeName()
+		}
+		return "struct " + instance.TypeName()
+	}
+	return string(obj.Type())
+}
+
+// instancesEqual compares instances of the same struct field by field.
+func instancesEqual(a, b *object.Instance) bool {
+	if a == b {
+		return true
+	}
+	if a.Struct != b.Struct {
+		return false
+	}
+	bValues := b.Values()
+	for i, value := range a.Values() {
+		if !objectsEqual(value, bValues[i]) {
+			return false
+		}
+	}
+	return true
+}
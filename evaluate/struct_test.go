@@ -0,0 +1,81 @@
+package evaluate
+
+import "testing"
+
+const pointStruct = `struct Point {
+	x, y
+	fn norm() { self.x * self.x + self.y * self.y }
+	fn move(dx, dy) { self.x = self.x + dx; self.y = self.y + dy; self }
+	fn scaled(k) { Point(self.x * k, self.y * k) }
+};
+`
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let p = Point(1, 2); p.x + p.y`, 3},
+		{`Point(3, 4).norm()`, 25},
+		{`let p = Point(1, 2); p.move(1, 1); p.x * 10 + p.y`, 23},
+		{`let p = Point(1, 2); p.x = 5; p.x`, 5},
+		{`let p = Point(1, 2); p.y = 7`, 7},
+		{`Point(1, 2).scaled(3).y`, 6},
+		{`let n = Point(3, 4).norm; n()`, 25},
+		{`type(Point(1, 2))`, "Point"},
+		{`type(Point)`, "STRUCT"},
+		{`type(1)`, "INTEGER"},
+		{`Point(1, 2) == Point(1, 2)`, true},
+		{`Point(1, 2) == Point(2, 1)`, false},
+		{`Point(1, 2) != Point(1, 3)`, true},
+		{`Point("a", Point(1, 2)) == Point("a", Point(1, 2))`, true},
+		{`struct Other { x, y }; Point(1, 2) == Other(1, 2)`, errorMessage("Operands are not of the same type : struct Point == struct Other")},
+		{`[Point(1, 2)].contains(Point(1, 2))`, true},
+		{`let f = fn(p) { if (p.x > 9) { p.x } else { f(Point(p.x + 1, 0)) } }; f(Point(0, 0))`, 10},
+		// a struct may take the name of a built-in type without being one
+		{`struct INTEGER { v }; type(INTEGER(1))`, "INTEGER"},
+		{`struct ERROR { m }; let e = ERROR(1); e.m`, 1},
+		{`struct HASH { v }; HASH(1) == HASH(1)`, true},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, pointStruct+tt.input), tt.expected)
+	}
+}
+
+func TestStructInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Point(1, "a")`, "Point{x:1,y:a}"},
+		{`Point`, "struct Point"},
+		{`struct Empty {}; Empty()`, "Empty{}"},
+	}
+	for _, tt := range tests {
+		if actual := testEval(t, pointStruct+tt.input).Inspect(); actual != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Point(1)`, "wrong number of fields for Point: want=2, got=1"},
+		{`Point(1, 2).z`, "Point has no field z"},
+		{`let p = Point(1, 2); p.z = 1`, "Point has no field z"},
+		{`let h = {"a": 1}; h.a = 2`, "cannot assign to field a of HASH"},
+		{`struct INTEGER { v }; INTEGER(1) + INTEGER(2)`, "Unknown Operator: struct INTEGER + struct INTEGER"},
+		{`struct INTEGER { v }; INTEGER(1) + 2`, "Operands are not of the same type : struct INTEGER + INTEGER"},
+		{`struct INTEGER { v }; INTEGER(1) == 1`, "Operands are not of the same type : struct INTEGER == INTEGER"},
+		{`struct P { v }; let a = P(1); struct P { v }; a == P(1)`, "Operands are not of the same type : struct P == struct P"},
+		{`enum Shape { Circle(r) }; Circle(1) == Point(1, 2)`, "Operands are not of the same type : enum Shape == struct Point"},
+		{`struct HASH { v }; HASH(1)["x"]`, "index Operator not supported struct HASH"},
+		{`struct ARRAY { v }; ARRAY(1)[0]`, "index Operator not supported struct ARRAY"},
+	}
+	for _, tt := range tests {
+		testObject(t, testEval(t, pointStruct+tt.input), errorMessage(tt.expected))
+	}
+}
@@ -0,0 +1,105 @@
+package evaluate
+
+import (
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// tailCall is a call in tail position whose function and arguments have been
+// evaluated but which has not been applied yet. It never escapes
+// applyFunction, which runs it in place of the call that produced it.
+type tailCall struct {
+	fn       object.Object
+	args     []object.Object
+	keywords map[string]object.Object
+}
+
+func (t *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (t *tailCall) Inspect() string         { return "tail call" }
+
+// evalTail evaluates a function body. Calls whose value becomes the value of
+// the function, either through `return f(x)` or as the last expression of
+// the body, are returned as a *tailCall instead of being applied, so that
+// applyFunction can run them without growing the Go stack. tail reports
+// whether node itself is in tail position.
+func (c *Context) evalTail(node ast.Node, env *object.Environment, tail bool) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		var result object.Object
+		for idx, stmt := range node.Statements {
+			result = c.evalTail(stmt, env, tail && idx == len(node.Statements)-1)
+			if result != nil {
+				rt := result.Type()
+				if rt == object.ERROR_OBJ || rt == object.RETURN_OBJ {
+					return result
+				}
+			}
+		}
+		return result
+	case *ast.ExpressionStatement:
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		return c.evalTail(node.Expression, env, tail)
+	case *ast.ReturnStatement:
+		call, ok := node.Value.(*ast.CallExpression)
+		if !ok {
+			break
+		}
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		val := c.evalTailCall(call, env)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.IfExpression:
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		condition := c.Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return c.evalTail(node.Consequence, env, tail)
+		} else if node.Alternative != nil {
+			return c.evalTail(node.Alternative, env, tail)
+		}
+		return NULL
+	case *ast.MatchExpression:
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		arm, armEnv, err := c.matchArm(node, env)
+		if err != nil {
+			return err
+		}
+		return c.evalTail(arm.Body, armEnv, tail)
+	case *ast.CallExpression:
+		if !tail {
+			break
+		}
+		if err := c.step(node, env); err != nil {
+			return err
+		}
+		return c.evalTailCall(node, env)
+	}
+	return c.Eval(node, env)
+}
+
+func (c *Context) evalTailCall(node *ast.CallExpression, env *object.Environment) object.Object {
+	function := c.Eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+	args, keywords, err := c.evalArguments(node.Arguments, env)
+	if err != nil {
+		return err
+	}
+	return &tailCall{fn: function, args: args, keywords: keywords}
+}
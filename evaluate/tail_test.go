@@ -0,0 +1,61 @@
+package evaluate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/object"
+)
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } };
+			countdown(1000000)`,
+			0,
+		},
+		{
+			`let sum = fn(n, acc) { if (n == 0) { return acc; } return sum(n - 1, acc + n); };
+			sum(1000000, 0)`,
+			500000500000,
+		},
+		{
+			`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+			let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+			if (even(100001)) { 1 } else { 2 }`,
+			2,
+		},
+		{
+			`let loop = fn(n) { if (n > 0) { return loop(n - 1); } len([1, 2, 3]) };
+			loop(50000)`,
+			3,
+		},
+	}
+	for _, tt := range tests {
+		c := NewContext(context.Background(), Limits{})
+		result := evalWithContext(t, c, tt.input)
+		if c.Err() != nil {
+			t.Errorf("unexpected error %v", c.Err())
+			continue
+		}
+		integer, ok := result.(*object.Integer)
+		if !ok {
+			t.Errorf("expected *object.Integer, got %T (%v)", result, result)
+			continue
+		}
+		if integer.Value != tt.expected {
+			t.Errorf("expected %d, got %d", tt.expected, integer.Value)
+		}
+	}
+}
+
+func TestNonTailCallsStillLimited(t *testing.T) {
+	c := NewContext(context.Background(), Limits{})
+	evalWithContext(t, c, "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(1000000)")
+	if c.Err() != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, c.Err())
+	}
+}
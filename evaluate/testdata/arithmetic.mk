@@ -0,0 +1,6 @@
+let a = 10;
+let b = 3;
+print(a + b, a - b, a * b, a / b);
+print(-a + 2 * (b + 1));
+print(a > b, a < b, a == 10, b != 3);
+a * a - b
@@ -0,0 +1,18 @@
+let map = fn(arr, f) {
+	let iter = fn(arr, acc) {
+		if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) }
+	};
+	iter(arr, [])
+};
+let reduce = fn(arr, initial, f) {
+	let iter = fn(arr, result) {
+		if (len(arr) == 0) { result } else { iter(rest(arr), f(result, first(arr))) }
+	};
+	iter(arr, initial)
+};
+let numbers = [1, 2, 3, 4, 5];
+let squares = map(numbers, fn(x) { x * x });
+print(squares);
+print(reduce(squares, 0, fn(acc, x) { acc + x }));
+print(first([]), last([]), rest([]));
+squares[10]
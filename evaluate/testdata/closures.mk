@@ -0,0 +1,11 @@
+let counter = fn(start) {
+	fn(step) { start + step }
+};
+let fromTen = counter(10);
+print(fromTen(1), fromTen(5));
+let compose = fn(f, g) { fn(x) { g(f(x)) } };
+let double = fn(x) { x * 2 };
+let inc = fn(x) { x + 1 };
+print(compose(double, inc)(5), compose(inc, double)(5));
+let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+fib(20)
@@ -0,0 +1,4 @@
+let f = fn(x) { x + true };
+print("before");
+f(1);
+print("never printed");
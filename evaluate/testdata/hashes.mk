@@ -0,0 +1,7 @@
+let person = {"name": "Ada", "born": 1815};
+print(person["name"]);
+print(person["born"] + 200);
+print(person["missing"]);
+let key = fn() { "name" };
+print(person[key()]);
+person[[1]]
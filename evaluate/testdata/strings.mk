@@ -0,0 +1,11 @@
+let greeting = "hello";
+let name = "world";
+let message = greeting + ", " + name;
+print(message);
+print(len(message));
+print(message == "hello, world");
+print("a" != "b");
+println(greeting, name, len(name));
+printf("%s has %d letters", name, len(name));
+println();
+"x" - "y"
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nishokbanand/interpreter/format"
+)
+
+// fmtCommand formats each of the files named by args, printing the result
+// or, with -w, writing it back over any file whose layout changed.
+func fmtCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fs.Bool("w", false, "write the result back to the file instead of printing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: interpreter fmt [-w] files...")
+	}
+	for _, path := range fs.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		formatted, err := format.Source(source)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if !*write {
+			out.Write(formatted)
+			continue
+		}
+		if bytes.Equal(source, formatted) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,731 @@
+// Package format prints programs back out as source in a canonical layout:
+// one statement per line, tab indentation, single spaces around operators
+// and no more parentheses than the precedence of the operators needs.
+//
+// A block holding a single expression stays on one line, as in
+// fn(x) { x * 2 }; any other block is broken over several lines. Comments
+// and single blank lines between statements are kept. A comment inside an
+// expression that is printed on one line moves to a line of its own before
+// the statement.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+// the precedences of the parser, from loosest to tightest
+const (
+	lowest = iota
+	assign
+	equals
+	lessGreater
+	sum
+	product
+	prefix
+	call
+	index
+	atom //literals and the expressions that end in a closing bracket
+)
+
+var operators = map[string]int{
+	"==": equals,
+	"!=": equals,
+	"<":  lessGreater,
+	">":  lessGreater,
+	"+":  sum,
+	"-":  sum,
+	"*":  product,
+	"/":  product,
+}
+
+// Source formats a whole program, keeping its comments. It fails if src
+// does not parse.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	pr := &printer{comments: l.Comments(), lines: strings.Split(string(src), "\n")}
+	pr.statements(program.Statements, -1)
+	if pr.out.Len() > 0 {
+		pr.out.WriteString("\n")
+	}
+	return pr.out.Bytes(), nil
+}
+
+// Node formats a single node, which may be a program, a statement or an
+// expression.
+func Node(node ast.Node) string {
+	p := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		p.statements(node.Statements, -1)
+	case *ast.BlockStatement:
+		p.block(node)
+	case ast.StatmentNode:
+		p.statement(node, nil)
+	case ast.ExpressionNode:
+		p.expression(node, lowest)
+	case ast.Pattern:
+		p.pattern(node)
+	case ast.TypeNode:
+		p.write(node.String())
+	}
+	return p.out.String()
+}
+
+type printer struct {
+	out      bytes.Buffer
+	indent   int
+	comments []token.Token //not printed yet
+	lines    []string      //of the source, to place comments and blank lines
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteString("\n" + strings.Repeat("\t", p.indent))
+}
+
+// separate starts a new line for something at line of the source, leaving
+// a blank line before it if the source has one.
+func (p *printer) separate(line int) {
+	if p.out.Len() == 0 {
+		return
+	}
+	if line >= 2 && line-2 < len(p.lines) && strings.TrimSpace(p.lines[line-2]) == "" && !p.opened() {
+		p.out.WriteString("\n")
+	}
+	p.newline()
+}
+
+// opened reports whether the output ends with an opening brace, after which
+// a blank line is never kept.
+func (p *printer) opened() bool {
+	out := bytes.TrimRight(p.out.Bytes(), " \t")
+	return len(out) > 0 && out[len(out)-1] == '{'
+}
+
+// flush prints the comments that come before line, or all of them if line
+// is negative. A comment that follows code on its line stays at the end of
+// the line printed last.
+func (p *printer) flush(line int) {
+	for len(p.comments) > 0 && (line < 0 || p.comments[0].Line < line) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		if p.trailing(comment) && p.out.Len() > 0 {
+			p.write(" " + comment.Literal)
+			continue
+		}
+		p.separate(comment.Line)
+		p.write(comment.Literal)
+	}
+}
+
+func (p *printer) trailing(comment token.Token) bool {
+	if comment.Line-1 >= len(p.lines) {
+		return false
+	}
+	text := p.lines[comment.Line-1]
+	return comment.Column-1 <= len(text) && strings.TrimSpace(text[:comment.Column-1]) != ""
+}
+
+// pending reports whether a comment comes before line.
+func (p *printer) pending(line int) bool {
+	return len(p.comments) > 0 && p.comments[0].Line < line
+}
+
+// statements prints stmts one per line, then the comments before line end.
+func (p *printer) statements(stmts []ast.StatmentNode, end int) {
+	for i, stmt := range stmts {
+		start := statementStart(stmt)
+		p.flush(start.Line)
+		p.separate(start.Line)
+		var next ast.StatmentNode
+		limit := end
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+			limit = statementStart(next).Line
+		}
+		if p.hoist(start.Line, limit, stmt) {
+			p.newline()
+		}
+		p.statement(stmt, next)
+	}
+	p.flush(end)
+}
+
+// hoist prints the comments inside nodes, which start on line start and
+// end before line limit (anywhere if it is negative), on lines of their own,
+// reporting whether there were any. Those are the comments that would
+// otherwise be run together at the end of the line the nodes are printed
+// on: the ones before the last line of code, except for the ones within
+// braces, whose contents are broken over lines and keep their comments in
+// place.
+func (p *printer) hoist(start, limit int, nodes ...ast.Node) bool {
+	if len(p.comments) == 0 {
+		return false
+	}
+	if limit < 0 || limit > len(p.lines)+1 {
+		limit = len(p.lines) + 1
+	}
+	// the columns the comments in range start at, by line
+	columns := map[int]int{}
+	for _, comment := range p.comments {
+		if comment.Line >= limit {
+			break
+		}
+		columns[comment.Line] = comment.Column
+	}
+	last := start
+	for line := start; line < limit; line++ {
+		text := p.lines[line-1]
+		if column, ok := columns[line]; ok {
+			text = text[:column-1]
+		}
+		if strings.TrimSpace(text) != "" {
+			last = line
+		}
+	}
+	var braces [][2]int //lines from an opening brace up to its closing one
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStatement:
+				if n.Token.Type == token.LBRACES {
+					braces = append(braces, [2]int{n.Token.Line, n.End.Line})
+				}
+			case *ast.MatchExpression:
+				braces = append(braces, [2]int{n.Token.Line, n.End.Line})
+			case *ast.SelectExpression:
+				if len(n.Cases) > 0 {
+					braces = append(braces, [2]int{n.Token.Line, n.Cases[len(n.Cases)-1].Body.End.Line})
+				}
+			case *ast.StructStatement:
+				if len(n.Methods) > 0 {
+					braces = append(braces, [2]int{n.Token.Line, limit})
+				}
+			}
+			return true
+		})
+	}
+	inBraces := func(line int) bool {
+		for _, b := range braces {
+			if line >= b[0] && line < b[1] {
+				return true
+			}
+		}
+		return false
+	}
+	hoisted := false
+	remaining := p.comments[:0:0]
+	for _, comment := range p.comments {
+		if comment.Line >= start && comment.Line < last && !inBraces(comment.Line) {
+			if hoisted {
+				p.newline()
+			}
+			p.write(comment.Literal)
+			hoisted = true
+			continue
+		}
+		remaining = append(remaining, comment)
+	}
+	p.comments = remaining
+	return hoisted
+}
+
+func statementStart(stmt ast.StatmentNode) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return ast.StartToken(stmt.Expression)
+	case *ast.ImportStatement:
+		return stmt.Token
+	case *ast.ExportStatement:
+		return stmt.Token
+	case *ast.StructStatement:
+		return stmt.Token
+	case *ast.EnumStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
+// statement prints stmt, which next follows in the same block, if any.
+func (p *printer) statement(stmt ast.StatmentNode, next ast.StatmentNode) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.let(stmt)
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(stmt.Value, lowest)
+		p.write(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, lowest)
+		if next != nil && (!endsInBlock(stmt.Expression) || continues(next)) {
+			p.write(";")
+		}
+	case *ast.ImportStatement:
+		p.write("import ")
+		p.string(stmt.Path.Value)
+		name := strings.TrimSuffix(path.Base(stmt.Path.Value), path.Ext(stmt.Path.Value))
+		if stmt.Name.Value != name {
+			p.write(" as " + stmt.Name.Value)
+		}
+		p.write(";")
+	case *ast.ExportStatement:
+		p.write("export ")
+		p.let(stmt.Statement)
+	case *ast.StructStatement:
+		p.structStatement(stmt)
+	case *ast.EnumStatement:
+		p.write("enum " + stmt.Name.Value + " {")
+		for i, variant := range stmt.Variants {
+			if i > 0 {
+				p.write(",")
+			}
+			p.write(" " + variant.Name.Value)
+			if variant.Fields != nil {
+				p.write("(" + identifiers(variant.Fields) + ")")
+			}
+		}
+		if len(stmt.Variants) > 0 {
+			p.write(" ")
+		}
+		p.write("}")
+	}
+}
+
+// endsInBlock reports whether exp is written with a closing brace last,
+// after which the statement needs no semicolon.
+func endsInBlock(exp ast.ExpressionNode) bool {
+	switch exp.(type) {
+	case *ast.IfExpression, *ast.FunctionLiteral, *ast.MatchExpression, *ast.SelectExpression:
+		return true
+	}
+	return false
+}
+
+// continues reports whether stmt is printed starting with a token that
+// would carry on the expression before it without a semicolon between
+// them.
+func continues(stmt ast.StatmentNode) bool {
+	if _, ok := stmt.(*ast.ExpressionStatement); !ok {
+		return false
+	}
+	first := &printer{}
+	first.statement(stmt, nil)
+	out := first.out.String()
+	return strings.HasPrefix(out, "(") || strings.HasPrefix(out, "[") || strings.HasPrefix(out, "-")
+}
+
+func (p *printer) let(let *ast.LetStatement) {
+	p.write(let.Token.Literal + " ")
+	if let.Pattern != nil {
+		p.pattern(let.Pattern)
+	} else {
+		p.write(let.Name.Value)
+	}
+	if let.Type != nil {
+		p.write(": " + let.Type.String())
+	}
+	p.write(" = ")
+	p.expression(let.Value, lowest)
+	p.write(";")
+}
+
+func (p *printer) structStatement(stmt *ast.StructStatement) {
+	p.write("struct " + stmt.Name.Value + " {")
+	if len(stmt.Methods) == 0 {
+		if len(stmt.Fields) > 0 {
+			p.write(" " + identifiers(stmt.Fields) + " ")
+		}
+		p.write("}")
+		return
+	}
+	p.indent++
+	if len(stmt.Fields) > 0 {
+		p.newline()
+		p.write(identifiers(stmt.Fields))
+	}
+	for _, method := range stmt.Methods {
+		p.flush(method.Function.Token.Line)
+		p.separate(method.Function.Token.Line)
+		p.write("fn " + method.Name.Value)
+		p.signature(method.Function)
+		p.block(method.Function.Body)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func identifiers(idents []*ast.Identifier) string {
+	names := []string{}
+	for _, ident := range idents {
+		names = append(names, ident.Value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// block prints a block on one line if it holds a single expression that
+// fits on one, and over several lines otherwise.
+func (p *printer) block(block *ast.BlockStatement) {
+	if p.pending(block.End.Line) {
+		p.multiline(block)
+		return
+	}
+	if len(block.Statements) == 0 {
+		p.write("{}")
+		return
+	}
+	if len(block.Statements) == 1 && simple(block.Statements[0]) {
+		single := &printer{}
+		single.statement(block.Statements[0], nil)
+		if !strings.Contains(single.out.String(), "\n") {
+			p.write("{ ")
+			p.statement(block.Statements[0], nil)
+			p.write(" }")
+			return
+		}
+	}
+	p.multiline(block)
+}
+
+// lines_ prints block over several lines.
+func (p *printer) multiline(block *ast.BlockStatement) {
+	p.write("{")
+	p.indent++
+	p.statements(block.Statements, block.End.Line)
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+// simple reports whether stmt may be the only statement of a block written
+// on one line.
+func simple(stmt ast.StatmentNode) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return !endsInBlock(stmt.Expression)
+	case *ast.ReturnStatement:
+		return !endsInBlock(stmt.Value)
+	}
+	return false
+}
+
+func precedence(exp ast.ExpressionNode) int {
+	switch exp := exp.(type) {
+	case *ast.AssignExpression:
+		return assign
+	case *ast.InfixExpression:
+		return operators[exp.Operator]
+	case *ast.PrefixExpression, *ast.SpawnExpression:
+		return prefix
+	case *ast.CallExpression:
+		return call
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return index
+	}
+	return atom
+}
+
+// expression prints exp where an expression binding at least as tightly
+// as min is expected, parenthesizing it if it binds more loosely.
+func (p *printer) expression(exp ast.ExpressionNode, min int) {
+	if precedence(exp) < min {
+		p.write("(")
+		defer p.write(")")
+	}
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.write(exp.Value)
+	case *ast.IntegerLiteral:
+		p.write(exp.Token.Literal)
+	case *ast.String:
+		p.string(exp.Value)
+	case *ast.Boolean:
+		p.write(exp.Token.Literal)
+	case *ast.PrefixExpression:
+		p.write(exp.Operator)
+		p.expression(exp.Right, prefix)
+	case *ast.InfixExpression:
+		// the operators are left associative
+		p.expression(exp.Left, operators[exp.Operator])
+		p.write(" " + exp.Operator + " ")
+		p.expression(exp.Right, operators[exp.Operator]+1)
+	case *ast.AssignExpression:
+		p.expression(exp.Target, assign+1)
+		p.write(" = ")
+		p.expression(exp.Value, lowest)
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(exp.Condition, lowest)
+		p.write(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.write(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.write("fn")
+		p.signature(exp)
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.expression(exp.Function, call)
+		p.write("(")
+		p.list(exp.Arguments)
+		p.write(")")
+	case *ast.SpreadExpression:
+		p.write("...")
+		p.expression(exp.Value, lowest)
+	case *ast.KeywordArgument:
+		p.write(exp.Name.Value + ": ")
+		p.expression(exp.Value, lowest)
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.list(exp.Elements)
+		p.write("]")
+	case *ast.IndexExpression:
+		p.expression(exp.Left, call)
+		p.write("[")
+		p.expression(exp.Index, lowest)
+		p.write("]")
+	case *ast.MemberExpression:
+		p.expression(exp.Object, call)
+		p.write("." + exp.Property.Value)
+	case *ast.HashLiteral:
+		p.hash(exp)
+	case *ast.SpawnExpression:
+		p.write("spawn ")
+		p.expression(exp.Call, prefix)
+	case *ast.SelectExpression:
+		p.selectExpression(exp)
+	case *ast.MatchExpression:
+		p.match(exp)
+	}
+}
+
+func (p *printer) string(value string) {
+	p.write(`"` + value + `"`)
+}
+
+func (p *printer) list(exps []ast.ExpressionNode) {
+	for i, exp := range exps {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(exp, lowest)
+	}
+}
+
+// signature prints the parameters of fn and its return type, ending with
+// the space before the body.
+func (p *printer) signature(fn *ast.FunctionLiteral) {
+	p.write("(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Value)
+		if i < len(fn.Types) && fn.Types[i] != nil {
+			p.write(": " + fn.Types[i].String())
+		}
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			p.write(" = ")
+			p.expression(fn.Defaults[i], assign+1)
+		}
+	}
+	if fn.Rest != nil {
+		if len(fn.Parameters) > 0 {
+			p.write(", ")
+		}
+		p.write("..." + fn.Rest.Value)
+		if fn.RestType != nil {
+			p.write(": " + fn.RestType.String())
+		}
+	}
+	p.write(") ")
+	if fn.ReturnType != nil {
+		p.write("-> " + fn.ReturnType.String() + " ")
+	}
+}
+
+// hash prints the pairs of hash in the order they were written in.
+func (p *printer) hash(hash *ast.HashLiteral) {
+	keys := []ast.ExpressionNode{}
+	for key := range hash.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := ast.StartToken(keys[i]), ast.StartToken(keys[j])
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	p.write("{")
+	for i, key := range keys {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(key, lowest)
+		p.write(": ")
+		p.expression(hash.Pairs[key], lowest)
+	}
+	p.write("}")
+}
+
+func (p *printer) selectExpression(exp *ast.SelectExpression) {
+	p.write("select {")
+	p.indent++
+	for _, sc := range exp.Cases {
+		p.flush(sc.Token.Line)
+		p.separate(sc.Token.Line)
+		if sc.Comm == nil {
+			p.write("default ")
+		} else {
+			p.write("case ")
+			if sc.Name != nil {
+				p.write(sc.Name.Value + " = ")
+			}
+			p.expression(sc.Comm, lowest)
+			p.write(" ")
+		}
+		p.block(sc.Body)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) match(exp *ast.MatchExpression) {
+	p.write("match ")
+	p.expression(exp.Subject, lowest)
+	p.write(" {")
+	p.indent++
+	for i, arm := range exp.Arms {
+		start := ast.PatternStartToken(arm.Pattern)
+		p.flush(start.Line)
+		p.separate(start.Line)
+		limit := exp.End.Line
+		if i+1 < len(exp.Arms) {
+			limit = ast.PatternStartToken(exp.Arms[i+1].Pattern).Line
+		}
+		if p.hoist(start.Line, limit, arm.Guard, arm.Body) {
+			p.newline()
+		}
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			p.expression(arm.Guard, lowest)
+		}
+		p.write(" => ")
+		// an arm written as an expression is parsed into a block without
+		// braces
+		if arm.Body.Token.Type != token.LBRACES && len(arm.Body.Statements) == 1 {
+			body := arm.Body.Statements[0].(*ast.ExpressionStatement).Expression
+			// a brace after => would start a block
+			if strings.HasPrefix(Node(body), "{") {
+				p.write("(")
+				p.expression(body, lowest)
+				p.write(")")
+			} else {
+				p.expression(body, lowest)
+			}
+		} else {
+			p.block(arm.Body)
+		}
+		p.write(",")
+	}
+	p.flush(exp.End.Line)
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		p.write("_")
+	case *ast.BindingPattern:
+		p.write(pattern.Name.Value)
+	case *ast.LiteralPattern:
+		p.expression(pattern.Value, lowest)
+	case *ast.DefaultPattern:
+		p.pattern(pattern.Pattern)
+		p.write(" = ")
+		p.expression(pattern.Default, assign+1)
+	case *ast.ArrayPattern:
+		p.write("[")
+		for i, element := range pattern.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(element)
+		}
+		if pattern.Rest != nil {
+			if len(pattern.Elements) > 0 {
+				p.write(", ")
+			}
+			p.write("...")
+			p.pattern(pattern.Rest)
+		}
+		p.write("]")
+	case *ast.HashPattern:
+		p.write("{")
+		for i, pair := range pattern.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.hashPatternPair(pair)
+		}
+		p.write("}")
+	case *ast.ConstructorPattern:
+		p.expression(pattern.Constructor, lowest)
+		if pattern.Fields == nil {
+			return
+		}
+		p.write("(")
+		for i, field := range pattern.Fields {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(field)
+		}
+		p.write(")")
+	}
+}
+
+// hashPatternPair prints a pair binding the field of the same name in its
+// short form, {x} or {x = 1}.
+func (p *printer) hashPatternPair(pair *ast.HashPatternPair) {
+	binding := pair.Value
+	if def, ok := binding.(*ast.DefaultPattern); ok {
+		binding = def.Pattern
+	}
+	if b, ok := binding.(*ast.BindingPattern); ok && pair.Key.Token.Type == token.IDENT && b.Name.Value == pair.Key.Value {
+		p.pattern(pair.Value)
+		return
+	}
+	if pair.Key.Token.Type == token.IDENT {
+		p.write(pair.Key.Value)
+	} else {
+		p.string(pair.Key.Value)
+	}
+	p.write(": ")
+	p.pattern(pair.Value)
+}
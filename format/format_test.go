@@ -0,0 +1,253 @@
+package format
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1;let y=x*2+3\nx", "let x = 1;\nlet y = x * 2 + 3;\nx\n"},
+		{"(1 + 2) * 3; 1 + (2 * 3); 1 - (2 - 3); (1 - 2) - 3; -(a + b); (-a)[0]",
+			"(1 + 2) * 3;\n1 + 2 * 3;\n1 - (2 - 3);\n1 - 2 - 3;\n-(a + b);\n(-a)[0]\n"},
+		{"a.b.c(1)[2]; (a + b).c; f(x)(y)", "a.b.c(1)[2];\n(a + b).c;\nf(x)(y)\n"},
+		{"let f = fn(x) { let y = x; y }", "let f = fn(x) {\n\tlet y = x;\n\ty\n};\n"},
+		{"let f = fn(x) {\n\n  x * 2\n\n}", "let f = fn(x) { x * 2 };\n"},
+		{"fn(a: int, b = 1, ...rest: [int]) -> int { return a; }", "fn(a: int, b = 1, ...rest: [int]) -> int { return a; }\n"},
+		{"if (x) { 1 } else { if (y) { 2 } else { 3 } }", "if (x) { 1 } else {\n\tif (y) { 2 } else { 3 }\n}\n"},
+		{"if (x) { a }\nb", "if (x) { a }\nb\n"},
+		{"if (x) { a };\n(b)", "if (x) { a }\nb\n"},
+		{"if (x) { a };\n(b + c) * d", "if (x) { a };\n(b + c) * d\n"},
+		{"if (x) { a };\n-b", "if (x) { a };\n-b\n"},
+		{"{\"b\": 1, \"a\": [1,2], 3: {}}", "{\"b\": 1, \"a\": [1, 2], 3: {}}\n"},
+		{"f(1, ...xs, y: 2)", "f(1, ...xs, y: 2)\n"},
+		{"a = b = 1; p.x = 2", "a = b = 1;\np.x = 2\n"},
+		{"const [a, b = 1, ...c] = xs; let {x, y = 2, \"z\": [q], w: r} = h;",
+			"const [a, b = 1, ...c] = xs;\nlet {x, y = 2, \"z\": [q], w: r} = h;\n"},
+		{"let x : [int] = []; let h: {string: fn(int) -> int} = {};", "let x: [int] = [];\nlet h: {string: fn(int) -> int} = {};\n"},
+		{"match s { Circle(r) if r > 0 => r * r, Shape.Rect(w, h) => w * h, [a, ...b] => { let c = a; c }, -1 => 1, _ => 2 }",
+			"match s {\n\tCircle(r) if r > 0 => r * r,\n\tShape.Rect(w, h) => w * h,\n\t[a, ...b] => {\n\t\tlet c = a;\n\t\tc\n\t},\n\t-1 => 1,\n\t_ => 2,\n}\n"},
+		{"match s { Shape.Empty => 0, Shape.Circle() => 1 }", "match s {\n\tShape.Empty => 0,\n\tShape.Circle() => 1,\n}\n"},
+		{"struct Point { x, y }\nstruct P { x fn norm() { self.x } fn twice(n) { let m = n * 2; m } }",
+			"struct Point { x, y }\nstruct P {\n\tx\n\tfn norm() { self.x }\n\tfn twice(n) {\n\t\tlet m = n * 2;\n\t\tm\n\t}\n}\n"},
+		{"enum Shape { Circle(r), Rect(w, h), Empty }", "enum Shape { Circle(r), Rect(w, h), Empty }\n"},
+		{"select { case v = recv(ch) { v } case send(ch, 1) { 0 } default { 1 } }",
+			"select {\n\tcase v = recv(ch) { v }\n\tcase send(ch, 1) { 0 }\n\tdefault { 1 }\n}\n"},
+		{"import \"lib/math.mk\"; import \"lib/x\" as y; export let z = spawn f(1);",
+			"import \"lib/math.mk\";\nimport \"lib/x\" as y;\nexport let z = spawn f(1);\n"},
+		// comments and blank lines
+		{"// header\n\nlet x = 1; // one\n\n\n// about y\nlet y = 2;\n// trailing",
+			"// header\n\nlet x = 1; // one\n\n// about y\nlet y = 2;\n// trailing\n"},
+		{"let f = fn(x) { // takes x\n  // doubles it\n  x * 2\n}", "let f = fn(x) { // takes x\n\t// doubles it\n\tx * 2\n};\n"},
+		{"let f = fn(x) {\n  x * 2\n  // done\n}", "let f = fn(x) {\n\tx * 2\n\t// done\n};\n"},
+		{"match x {\n  // one\n  1 => a, // a\n  _ => b,\n}", "match x {\n\t// one\n\t1 => a, // a\n\t_ => b,\n}\n"},
+		{"match x { _ => ({\"a\": 1}) }", "match x {\n\t_ => ({\"a\": 1}),\n}\n"},
+		// comments inside an expression printed on one line go before it
+		{"let h = {\n \"a\": 1, // one\n \"b\": 2 // two\n};", "// one\n// two\nlet h = {\"a\": 1, \"b\": 2};\n"},
+		{"let x = 1;\n\nf(1, // first\n  // second\n  2); // last\ny", "let x = 1;\n\n// first\n// second\nf(1, 2); // last\ny\n"},
+		{"let f = fn() {\n  g([1, // one\n    2])\n}", "let f = fn() {\n\t// one\n\tg([1, 2])\n};\n"},
+		{"match x {\n  1 => [a, // a\n    b],\n  _ => c, // c\n}", "match x {\n\t// a\n\t1 => [a, b],\n\t_ => c, // c\n}\n"},
+		{"let f = fn(x) { // takes x\n  x\n}(1, // one\n 2)", "// one\nlet f = fn(x) { // takes x\n\tx\n}(1, 2);\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: %s", tt.input, err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf("%q:\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, out)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	if _, err := Source([]byte("let = 1")); err == nil || !strings.Contains(err.Error(), "expected next token IDENT") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestNode(t *testing.T) {
+	p := parser.New(lexer.New("let add = fn(a, b) { a + b };"))
+	program := p.ParseProgram()
+	let := program.Statements[0].(*ast.LetStatement)
+	if got := Node(let.Value); got != "fn(a, b) { a + b }" {
+		t.Errorf("wrong expression %q", got)
+	}
+	if got := Node(program); got != "let add = fn(a, b) { a + b };" {
+		t.Errorf("wrong program %q", got)
+	}
+}
+
+// TestRoundTrip checks, for the golden scripts and for randomly generated
+// programs, that formatting keeps the program the same and that formatting
+// again changes nothing.
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let h = {\n \"a\": 1, // one\n \"b\": 2 // two\n};",
+		"match x {\n  1 => [a, // a\n    b],\n  _ => c, // c\n}",
+	}
+	scripts, err := filepath.Glob(filepath.Join("..", "evaluate", "testdata", "*.mk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, script := range scripts {
+		if filepath.Base(script) == "parse_error.mk" {
+			continue
+		}
+		src, err := os.ReadFile(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(src))
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		inputs = append(inputs, generateProgram(r))
+	}
+	for _, input := range inputs {
+		before := parse(t, input)
+		formatted, err := Source([]byte(input))
+		if err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		after := parse(t, string(formatted))
+		if dump(reflect.ValueOf(before)) != dump(reflect.ValueOf(after)) {
+			t.Errorf("formatting changed the program\n%s\nto\n%s", input, formatted)
+			continue
+		}
+		again, err := Source(formatted)
+		if err != nil || string(again) != string(formatted) {
+			t.Errorf("formatting is not stable:\n%s\nthen\n%s", formatted, again)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// dump renders a node with the positions and spellings of its tokens left
+// out, so that two parses of the same program render the same.
+func dump(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		out := dump(v.Elem())
+		if let, ok := v.Interface().(*ast.LetStatement); ok && let.Constant() {
+			out = "const " + out
+		}
+		return out
+	case reflect.Struct:
+		fields := []string{}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Type() == reflect.TypeOf(token.Token{}) {
+				continue
+			}
+			fields = append(fields, v.Type().Field(i).Name+":"+dump(v.Field(i)))
+		}
+		return v.Type().Name() + "{" + strings.Join(fields, " ") + "}"
+	case reflect.Slice:
+		if v.IsNil() {
+			return "nil"
+		}
+		items := []string{}
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, dump(v.Index(i)))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	case reflect.Map:
+		items := []string{}
+		for _, key := range v.MapKeys() {
+			items = append(items, dump(key)+":"+dump(v.MapIndex(key)))
+		}
+		sort.Strings(items)
+		return "map[" + strings.Join(items, " ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// generateProgram writes a random program, parenthesizing every
+// subexpression so that its structure does not depend on precedence.
+func generateProgram(r *rand.Rand) string {
+	stmts := []string{}
+	for i := r.Intn(4) + 1; i > 0; i-- {
+		switch r.Intn(4) {
+		case 0:
+			stmts = append(stmts, "let "+generateName(r)+" = "+generateExpression(r, 3)+";")
+		case 1:
+			stmts = append(stmts, "return "+generateExpression(r, 3)+";")
+		default:
+			stmts = append(stmts, generateExpression(r, 3)+";")
+		}
+	}
+	return strings.Join(stmts, "\n")
+}
+
+func generateName(r *rand.Rand) string {
+	return []string{"a", "b", "xs", "f"}[r.Intn(4)]
+}
+
+func generateExpression(r *rand.Rand, depth int) string {
+	if depth == 0 {
+		switch r.Intn(4) {
+		case 0:
+			return fmt.Sprint(r.Intn(100))
+		case 1:
+			return `"s"`
+		case 2:
+			return "true"
+		default:
+			return generateName(r)
+		}
+	}
+	sub := func() string { return generateExpression(r, depth-1) }
+	switch r.Intn(11) {
+	case 0:
+		op := []string{"+", "-", "*", "/", "<", ">", "==", "!="}[r.Intn(8)]
+		return "(" + sub() + " " + op + " " + sub() + ")"
+	case 1:
+		return "(" + []string{"-", "!"}[r.Intn(2)] + sub() + ")"
+	case 2:
+		return "(" + sub() + ")(" + sub() + ", " + sub() + ")"
+	case 3:
+		return "(" + sub() + ")[" + sub() + "]"
+	case 4:
+		return "[" + sub() + ", " + sub() + "]"
+	case 5:
+		return "{" + sub() + ": " + sub() + ", " + sub() + ": " + sub() + "}"
+	case 6:
+		return "if (" + sub() + ") { " + sub() + " } else { let x = " + sub() + "; " + sub() + " }"
+	case 7:
+		return "fn(x, y = " + sub() + ") { " + sub() + " }"
+	case 8:
+		return "(" + generateName(r) + " = " + sub() + ")"
+	case 9:
+		return "(" + sub() + ").field"
+	default:
+		return "match " + sub() + " { [x, ...y] => (" + sub() + "), _ => { " + sub() + " } }"
+	}
+}
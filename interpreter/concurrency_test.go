@@ -0,0 +1,118 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nishokbanand/interpreter/object"
+)
+
+// These tests are meant to be run with -race.
+
+const stressWorkers = 16
+
+func TestIndependentInterpreters(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, stressWorkers)
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var out bytes.Buffer
+			interp := New()
+			interp.Stdout = &out
+			interp.RegisterBuiltin("worker", func() int { return w })
+			source := `
+let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };
+let xs = push([1, 2], worker());
+println(worker(), fib(15));
+{"id": worker(), "xs": xs}["id"]`
+			result, err := interp.Run(context.Background(), source)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.Inspect() != fmt.Sprint(w) || out.String() != fmt.Sprintf("%d 610\n", w) {
+				errs <- fmt.Errorf("worker %d: got %s, printed %q", w, result.Inspect(), out.String())
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSharedGlobals(t *testing.T) {
+	globals := object.NewSyncEnvironment()
+	setup := NewWithGlobals(globals)
+	_, err := setup.Run(context.Background(), `
+let make = fn() { let total = 0; fn(x) { let total = total + x; total } };
+let shared = make();
+let square = fn(x) { x * x };`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, stressWorkers)
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			interp := NewWithGlobals(globals)
+			for i := 0; i < 50; i++ {
+				source := fmt.Sprintf("let last = square(%d); shared(1); square(last)", w)
+				result, err := interp.Run(context.Background(), source)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if want := fmt.Sprint(w * w * w * w); result.Inspect() != want {
+					errs <- fmt.Errorf("worker %d: expected %s, got %s", w, want, result.Inspect())
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSpawnedTasks(t *testing.T) {
+	var out bytes.Buffer
+	interp := New()
+	interp.Stdout = &out
+	source := `
+let ch = chan();
+let worker = fn(n) { println("worker", n); send(ch, n) };
+let start = fn(n) { if (n > 0) { spawn worker(n); start(n - 1) } };
+start(8);
+let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + recv(ch)) } };
+sum(8, 0)`
+	result, err := interp.Run(context.Background(), source)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "36" {
+		t.Errorf("expected 36, got %s", result.Inspect())
+	}
+	if lines := bytes.Count(out.Bytes(), []byte("\n")); lines != 8 {
+		t.Errorf("expected 8 lines of output, got %d: %q", lines, out.String())
+	}
+
+	// a task left spinning is canceled when Run returns
+	if _, err := interp.Run(context.Background(), "let spin = fn() { spin() }; let t = spawn spin();"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = interp.Run(context.Background(), "await(t)")
+	if err == nil || err.Error() != "evaluation canceled" {
+		t.Errorf("expected the leftover task to be canceled, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package interpreter embeds the language in Go programs. An Interpreter
+// owns a global environment that persists across calls to Run, so a host can
+// load a script once and then call into it.
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+// ParseError lists the syntax errors that kept a script from running.
+type ParseError struct {
+	Messages []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Messages, "; ")
+}
+
+// RuntimeError is an error raised by a running script. When the script was
+// stopped by one of its limits Err holds the matching evaluate.Err* value,
+// so errors.Is(err, evaluate.ErrTimeout) and friends work.
+type RuntimeError struct {
+	Message string
+	Err     error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Interpreter runs scripts against a persistent global environment. The
+// exported fields may be changed between calls. An Interpreter is not safe
+// for concurrent use, but separate Interpreters share no mutable state and
+// may run in parallel. To share globals between goroutines give each its
+// own Interpreter over one environment from object.NewSyncEnvironment, see
+// NewWithGlobals.
+type Interpreter struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Limits evaluate.Limits
+	// Dir is the directory relative imports are resolved against, the
+	// working directory if empty.
+	Dir string
+
+	env      *object.Environment
+	builtins map[string]*object.Builtin
+	streams  object.Streams
+	modules  *evaluate.Modules //imported modules are cached across runs
+}
+
+func New() *Interpreter {
+	return NewWithGlobals(object.NewEnvironment())
+}
+
+// NewWithGlobals returns an Interpreter whose global environment is env.
+// Interpreters running concurrently over the same env need it to be
+// synchronized, as created by object.NewSyncEnvironment.
+func NewWithGlobals(env *object.Environment) *Interpreter {
+	return &Interpreter{
+		Stdin:    os.Stdin,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		env:      env,
+		builtins: map[string]*object.Builtin{},
+		modules:  evaluate.NewModules(""),
+	}
+}
+
+// RegisterBuiltin makes the Go function fn callable from scripts run by this
+// interpreter as name. Arguments and results are converted as described by
+// object.WrapFunc, so fn may take and return plain Go types, and an error
+// it returns surfaces as a runtime error in the script. Registrations are
+// private to the interpreter; other interpreters do not see them.
+func (i *Interpreter) RegisterBuiltin(name string, fn any) error {
+	builtin, err := object.WrapFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.builtins[name] = builtin
+	return nil
+}
+
+// Run parses and evaluates source. It returns the value of the last
+// statement, or object.Null if that statement has no value. Tasks the
+// script spawned and did not await are canceled when Run returns.
+func (i *Interpreter) Run(ctx context.Context, source string) (object.Object, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+	// tasks the script spawned do not outlive the run
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := i.newContext(ctx)
+	return result(c, c.Eval(program, i.env))
+}
+
+// Call invokes the function, bound method or struct constructor bound to
+// name in the global environment. Like Run, it stops when ctx is done, and
+// tasks the call spawned and did not await are canceled when it returns.
+func (i *Interpreter) Call(ctx context.Context, name string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("identifier not found %s", name)
+	}
+	if !evaluate.Callable(fn) {
+		return nil, fmt.Errorf("%s is not a function, got %s", name, fn.Type())
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := i.newContext(ctx)
+	return result(c, c.Apply(fn, args))
+}
+
+// Set binds name to value in the global environment.
+func (i *Interpreter) Set(name string, value object.Object) {
+	i.env.Set(name, value)
+}
+
+// Get returns the global bound to name.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+func (i *Interpreter) newContext(ctx context.Context) *evaluate.Context {
+	c := evaluate.NewContext(ctx, i.Limits)
+	// the streams value outlives a single Run so input buffered by one run
+	// is still there for the next
+	i.streams.Stdin, i.streams.Stdout, i.streams.Stderr = i.Stdin, i.Stdout, i.Stderr
+	c.SetStreams(&i.streams)
+	i.modules.Dir = i.Dir
+	c.SetModules(i.modules)
+	for name, builtin := range i.builtins {
+		c.SetBuiltin(name, builtin)
+	}
+	return c
+}
+
+func result(c *evaluate.Context, obj object.Object) (object.Object, error) {
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Message: errObj.Message, Err: c.Err()}
+	}
+	if obj == nil {
+		return object.Null, nil
+	}
+	return obj, nil
+}
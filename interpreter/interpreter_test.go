@@ -0,0 +1,230 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+func TestRunKeepsGlobals(t *testing.T) {
+	interp := New()
+	if _, err := interp.Run(context.Background(), "let double = fn(x) { x * 2 };"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := interp.Run(context.Background(), "double(21)")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "42" {
+		t.Errorf("expected 42, got %s", result.Inspect())
+	}
+}
+
+func TestRunWithoutValue(t *testing.T) {
+	result, err := New().Run(context.Background(), "let a = 1;")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result != object.Null {
+		t.Errorf("expected null, got %v", result)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := New().Run(context.Background(), "let = 1;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *ParseError, got %T (%v)", err, err)
+	}
+	if len(parseErr.Messages) == 0 {
+		t.Errorf("expected parse error messages")
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	_, err := New().Run(context.Background(), "1 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+	if runtimeErr.Message != "Operands are not of the same type : INTEGER + BOOLEAN" {
+		t.Errorf("wrong message %q", runtimeErr.Message)
+	}
+	if runtimeErr.Err != nil {
+		t.Errorf("expected no limit error, got %v", runtimeErr.Err)
+	}
+}
+
+func TestLimitErrors(t *testing.T) {
+	interp := New()
+	interp.Limits = evaluate.Limits{Timeout: 10 * time.Millisecond}
+	_, err := interp.Run(context.Background(), "let loop = fn() { loop() }; loop()")
+	if !errors.Is(err, evaluate.ErrTimeout) {
+		t.Errorf("expected timeout, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = interp.Run(ctx, "1")
+	if !errors.Is(err, evaluate.ErrCanceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
+
+func TestCallSetGet(t *testing.T) {
+	interp := New()
+	interp.Set("base", &object.Integer{Value: 10})
+	if _, err := interp.Run(context.Background(), "let add = fn(x) { base + x };"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := interp.Call(context.Background(), "add", &object.Integer{Value: 5})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "15" {
+		t.Errorf("expected 15, got %s", result.Inspect())
+	}
+	if _, err := interp.Call(context.Background(), "missing"); err == nil {
+		t.Errorf("expected error calling undefined function")
+	}
+	if _, err := interp.Call(context.Background(), "base"); err == nil {
+		t.Errorf("expected error calling an integer")
+	}
+	base, ok := interp.Get("base")
+	if !ok || base.Inspect() != "10" {
+		t.Errorf("expected base to be 10, got %v", base)
+	}
+}
+
+func TestCallables(t *testing.T) {
+	interp := New()
+	src := `struct P { x, fn get() { self.x } }; let p = P(3); let get = p.get; let pushTo = [1, 2].push;`
+	if _, err := interp.Run(context.Background(), src); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tests := []struct {
+		name     string
+		args     []object.Object
+		expected string
+	}{
+		{"P", []object.Object{&object.Integer{Value: 1}}, "P{x:1}"},
+		{"get", nil, "3"},
+		{"pushTo", []object.Object{&object.Integer{Value: 3}}, "[1,2,3]"},
+	}
+	for _, tt := range tests {
+		result, err := interp.Call(context.Background(), tt.name, tt.args...)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, result.Inspect())
+		}
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	interp := New()
+	if _, err := interp.Run(context.Background(), "let loop = fn(n) { loop(n + 1) };"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := interp.Call(ctx, "loop", &object.Integer{Value: 0})
+	if !errors.Is(err, evaluate.ErrCanceled) && !errors.Is(err, evaluate.ErrTimeout) {
+		t.Errorf("expected the call to stop with its context, got %v", err)
+	}
+}
+
+func TestOutputWriters(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := New()
+	interp.Stdout = &stdout
+	interp.Stderr = &stderr
+	if _, err := interp.Run(context.Background(), `print("out", 1); eprint("err")`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stdout.String() != "out\n1\n" {
+		t.Errorf("wrong stdout %q", stdout.String())
+	}
+	if stderr.String() != "err\n" {
+		t.Errorf("wrong stderr %q", stderr.String())
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	interp := New()
+	err := interp.RegisterBuiltin("div", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("divide by zero")
+		}
+		return a / b, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := interp.Run(context.Background(), "div(10, 2)")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "5" {
+		t.Errorf("expected 5, got %s", result.Inspect())
+	}
+	_, err = interp.Run(context.Background(), "div(1, 0)")
+	if err == nil || err.Error() != "divide by zero" {
+		t.Errorf("expected divide by zero error, got %v", err)
+	}
+	if _, err := New().Run(context.Background(), "div(10, 2)"); err == nil {
+		t.Errorf("builtin leaked into another interpreter")
+	}
+	if err := interp.RegisterBuiltin("bad", 1); err == nil {
+		t.Errorf("expected error registering a non-function")
+	}
+}
+
+func TestInputReader(t *testing.T) {
+	var stdout bytes.Buffer
+	interp := New()
+	interp.Stdin = strings.NewReader("Ada\nLovelace\n")
+	interp.Stdout = &stdout
+	first, err := interp.Run(context.Background(), `input("name: ")`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	second, err := interp.Run(context.Background(), `read_line()`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if first.Inspect() != "Ada" || second.Inspect() != "Lovelace" {
+		t.Errorf("expected Ada and Lovelace, got %s and %s", first.Inspect(), second.Inspect())
+	}
+	if stdout.String() != "name: " {
+		t.Errorf("wrong stdout %q", stdout.String())
+	}
+}
+
+func TestImportRelativeToDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "greet.mk"), []byte(`export let hello = fn(name) { "hello " + name };`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	interp := New()
+	interp.Dir = dir
+	if _, err := interp.Run(context.Background(), `import "greet";`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := interp.Run(context.Background(), `greet.hello("world")`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "hello world" {
+		t.Errorf("expected hello world, got %s", result.Inspect())
+	}
+}
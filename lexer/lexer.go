@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/nishokbanand/interpreter/token"
 )
 
@@ -9,15 +11,23 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int //of ch
+	column       int //of ch
+	comments     []token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 //0 character corresponds to null in ASCII
 	} else {
@@ -27,9 +37,17 @@ func (l *Lexer) readChar() {
 	l.readPosition += 1
 }
 
+// NextToken returns the next token, with the position it starts at.
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
 	l.skipWhiteSpace()
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 	switch l.ch {
 	//operators
 	case '=':
@@ -37,13 +55,21 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(l.ch) + string(ch)}
+		} else if l.peekchar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
 		tok = newToken(token.SUM, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekchar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.RETURNS, Literal: "->"}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
 	case '/':
@@ -82,6 +108,14 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = l.readString()
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case 0:
 		tok.Type = token.EOF
 		tok.Literal = ""
@@ -102,12 +136,32 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// skipWhiteSpace skips spaces and comments, keeping the comments for
+// Comments.
 func (l *Lexer) skipWhiteSpace() {
-	for l.ch == ' ' || l.ch == '\n' || l.ch == '\t' || l.ch == '\r' {
-		l.readChar()
+	for {
+		for l.ch == ' ' || l.ch == '\n' || l.ch == '\t' || l.ch == '\r' {
+			l.readChar()
+		}
+		if l.ch != '/' || l.peekchar() != '/' {
+			return
+		}
+		comment := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+		position := l.position
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		comment.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+		l.comments = append(l.comments, comment)
 	}
 }
 
+// Comments returns the // comments skipped so far, in order. The parser
+// never sees them; they are kept for tools that print source back out.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func newToken(tokenType token.TokenType, char byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(char)}
 }
@@ -153,7 +207,7 @@ func (l *Lexer) readString() string {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == '0' {
+		if l.ch == '"' || l.ch == 0 {
 			break
 		}
 	}
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/nishokbanand/interpreter/token"
@@ -160,3 +161,157 @@ func TestLexerAdvanced(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerStrings(t *testing.T) {
+	input := `"foo bar" "a0b" "unterminated`
+	tests := []struct {
+		Type    token.TokenType
+		Literal string
+	}{
+		{token.STRING, "foo bar"},
+		{token.STRING, "a0b"},
+		{token.STRING, "unterminated"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, test := range tests {
+		tok := l.NextToken()
+		if test.Type != tok.Type {
+			t.Errorf("expected tokenType %v, received tokenType %v", test.Type, tok.Type)
+		}
+		if test.Literal != tok.Literal {
+			t.Errorf("expected Literal %v, received Literal %v", test.Literal, tok.Literal)
+		}
+	}
+}
+
+func TestLexerPatterns(t *testing.T) {
+	input := `match x { [a, ...rest] => a, _ => 0 } enum a.b =>=`
+	tests := []struct {
+		Type    token.TokenType
+		Literal string
+	}{
+		{token.MATCH, "match"},
+		{token.IDENT, "x"},
+		{token.LBRACES, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "0"},
+		{token.RBRACES, "}"},
+		{token.ENUM, "enum"},
+		{token.IDENT, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
+		{token.ARROW, "=>"},
+		{token.ASSIGN, "="},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, test := range tests {
+		tok := l.NextToken()
+		if test.Type != tok.Type {
+			t.Errorf("expected tokenType %v, received tokenType %v", test.Type, tok.Type)
+		}
+		if test.Literal != tok.Literal {
+			t.Errorf("expected Literal %v, received Literal %v", test.Literal, tok.Literal)
+		}
+	}
+}
+
+func TestLexerPositions(t *testing.T) {
+	input := "let x = 5;\n  x == \"ab\"\n\tfn"
+	tests := []struct {
+		Literal string
+		Line    int
+		Column  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"==", 2, 5},
+		{"ab", 2, 8},
+		{"fn", 3, 2},
+		{"", 3, 4},
+	}
+	l := New(input)
+	for _, test := range tests {
+		tok := l.NextToken()
+		if tok.Literal != test.Literal || tok.Line != test.Line || tok.Column != test.Column {
+			t.Errorf("expected %q at %d:%d, got %q at %d:%d", test.Literal, test.Line, test.Column, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestLexerAnnotations(t *testing.T) {
+	input := `fn(a: int) -> [int] { a - -1 }`
+	tests := []struct {
+		Type    token.TokenType
+		Literal string
+	}{
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.RETURNS, "->"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "int"},
+		{token.RBRACKET, "]"},
+		{token.LBRACES, "{"},
+		{token.IDENT, "a"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.RBRACES, "}"},
+		{token.EOF, ""},
+	}
+	l := New(input)
+	for _, test := range tests {
+		tok := l.NextToken()
+		if test.Type != tok.Type {
+			t.Errorf("expected tokenType %v, received tokenType %v", test.Type, tok.Type)
+		}
+		if test.Literal != tok.Literal {
+			t.Errorf("expected Literal %v, received Literal %v", test.Literal, tok.Literal)
+		}
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	input := "// header\nlet x = 1; // one  \n\t// two\n10 / 2"
+	l := New(input)
+	literals := []string{}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		literals = append(literals, tok.Literal)
+	}
+	if got := strings.Join(literals, " "); got != "let x = 1 ; 10 / 2" {
+		t.Errorf("wrong tokens %q", got)
+	}
+	expected := []token.Token{
+		{Type: token.COMMENT, Literal: "// header", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// one", Line: 2, Column: 12},
+		{Type: token.COMMENT, Literal: "// two", Line: 3, Column: 2},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("expected %d comments, got %v", len(expected), comments)
+	}
+	for i, comment := range comments {
+		if comment != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], comment)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/nishokbanand/interpreter/lsp"
+)
+
+// lspCommand runs a language server on stdin and stdout until the editor
+// that started it exits.
+func lspCommand(args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+}
@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/resolver"
+	"github.com/nishokbanand/interpreter/token"
+	"github.com/nishokbanand/interpreter/typecheck"
+)
+
+// document is an open file and what is known about it. The program and
+// its index are those of the last version that parsed, so that navigation
+// keeps working while the user is in the middle of an edit.
+type document struct {
+	uri         string
+	text        string
+	lines       []string
+	diagnostics []Diagnostic
+	program     *ast.Program
+	resolution  *resolver.Resolution
+	index       *index
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri}
+	d.update(text)
+	return d
+}
+
+// update replaces the text of the document and analyses it again. The
+// text is often half typed, so a panic in the analysis is reported as a
+// diagnostic rather than taking the server down.
+func (d *document) update(text string) {
+	d.text = text
+	d.lines = strings.Split(text, "\n")
+	d.diagnostics = []Diagnostic{}
+	defer func() {
+		if r := recover(); r != nil {
+			d.diagnostics = []Diagnostic{{
+				Severity: SeverityError,
+				Source:   "interpreter",
+				Message:  fmt.Sprintf("internal error: %v", r),
+			}}
+		}
+	}()
+	d.analyse()
+}
+
+func (d *document) analyse() {
+	p := parser.New(lexer.New(d.text))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		toks := p.ErrorTokens()
+		for i, msg := range errs {
+			d.diagnostics = append(d.diagnostics, Diagnostic{
+				Range:    d.tokenRange(toks[i]),
+				Severity: SeverityError,
+				Source:   "interpreter",
+				Message:  msg,
+			})
+		}
+		return
+	}
+	resolution := resolver.Resolve(program, evaluate.IsBuiltin)
+	index := newIndex(program, resolution)
+	d.program, d.resolution, d.index = program, resolution, index
+	found := append([]resolver.Diagnostic{}, resolution.Diagnostics...)
+	found = append(found, typecheck.Check(program)...)
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i].Span.Start, found[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	for _, diag := range found {
+		severity := SeverityError
+		if diag.Severity == resolver.Warning {
+			severity = SeverityWarning
+		}
+		d.diagnostics = append(d.diagnostics, Diagnostic{
+			Range:    Range{Start: d.position(diag.Span.Start), End: d.position(diag.Span.End)},
+			Severity: severity,
+			Source:   "interpreter",
+			Message:  diag.Message,
+		})
+	}
+}
+
+// position converts a 1-based line and byte column to an LSP position.
+func (d *document) position(pos resolver.Position) Position {
+	line := pos.Line - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(d.lines) {
+		return Position{Line: line}
+	}
+	text := d.lines[line]
+	column := pos.Column - 1
+	if column > len(text) {
+		column = len(text)
+	}
+	if column < 0 {
+		column = 0
+	}
+	return Position{Line: line, Character: len(utf16.Encode([]rune(text[:column])))}
+}
+
+// offset converts an LSP position back to a 1-based line and byte column.
+func (d *document) offset(pos Position) resolver.Position {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return resolver.Position{Line: pos.Line + 1, Column: 1}
+	}
+	text := d.lines[pos.Line]
+	column, units := 0, 0
+	for column < len(text) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[column:])
+		units += len(utf16.Encode([]rune{r}))
+		column += size
+	}
+	return resolver.Position{Line: pos.Line + 1, Column: column + 1}
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	start := resolver.Position{Line: tok.Line, Column: tok.Column}
+	end := resolver.Position{Line: tok.Line, Column: tok.Column + len(tok.Literal)}
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// between is the range from the first byte of start to the last of end.
+func (d *document) between(start, end token.Token) Range {
+	return Range{Start: d.tokenRange(start).Start, End: d.tokenRange(end).End}
+}
+
+// wholeRange covers the entire text.
+func (d *document) wholeRange() Range {
+	last := len(d.lines) - 1
+	return Range{End: Position{Line: last, Character: len(utf16.Encode([]rune(d.lines[last])))}}
+}
+
+// definition returns the identifier that declares the one at pos, and the
+// one at pos itself.
+func (d *document) definition(pos Position) (def, ident *ast.Identifier) {
+	if d.index == nil {
+		return nil, nil
+	}
+	ident = d.index.identifierAt(d.offset(pos))
+	if ident == nil {
+		return nil, nil
+	}
+	if _, ok := d.index.owners[ident]; ok {
+		return ident, ident
+	}
+	return d.resolution.Definitions[ident], ident
+}
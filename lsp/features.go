@@ -0,0 +1,223 @@
+package lsp
+
+import (
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/format"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+// definitionLocation is where the name at pos is declared, or nil when
+// there is no name there or it is not declared in the document.
+func (d *document) definitionLocation(pos Position) *Location {
+	def, _ := d.definition(pos)
+	if def == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.tokenRange(def.Token)}
+}
+
+// hover describes the binding of the name at pos: the statement declaring
+// it, with the expression it is bound to, or the parameter list entry.
+func (d *document) hover(pos Position) *Hover {
+	def, ident := d.definition(pos)
+	if ident == nil {
+		return nil
+	}
+	text := ""
+	switch {
+	case def != nil:
+		text = d.describe(def)
+	case evaluate.IsBuiltin(ident.Value):
+		text = "(builtin) " + ident.Value
+	default:
+		return nil
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```\n" + text + "\n```"},
+		Range:    d.tokenRange(ident.Token),
+	}
+}
+
+func (d *document) describe(ident *ast.Identifier) string {
+	switch owner := d.index.owners[ident].(type) {
+	case *ast.LetStatement:
+		return format.Node(owner)
+	case *ast.ImportStatement:
+		return format.Node(owner)
+	case *ast.FunctionLiteral:
+		return "(parameter) " + parameter(owner, ident)
+	case *ast.StructStatement:
+		// the fields are enough to tell what the struct holds
+		return format.Node(&ast.StructStatement{Token: owner.Token, Name: owner.Name, Fields: owner.Fields})
+	case *ast.EnumStatement:
+		if ident == owner.Name {
+			return format.Node(owner)
+		}
+		for _, variant := range owner.Variants {
+			if variant.Name == ident {
+				return "(variant) " + owner.Name.Value + "." + variant.String()
+			}
+		}
+	case *ast.MatchExpression:
+		return "(match binding) " + ident.Value
+	case *ast.SelectExpression:
+		return "(select binding) " + ident.Value
+	}
+	return ident.Value
+}
+
+// parameter renders the declaration of param in the parameter list of fn.
+func parameter(fn *ast.FunctionLiteral, param *ast.Identifier) string {
+	if param == fn.Rest {
+		out := "..." + param.Value
+		if fn.RestType != nil {
+			out += ": " + fn.RestType.String()
+		}
+		return out
+	}
+	out := param.Value
+	for i, p := range fn.Parameters {
+		if p != param {
+			continue
+		}
+		if i < len(fn.Types) && fn.Types[i] != nil {
+			out += ": " + fn.Types[i].String()
+		}
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			out += " = " + format.Node(fn.Defaults[i])
+		}
+	}
+	return out
+}
+
+// completion offers the names in scope at pos, innermost first, followed
+// by the builtins.
+func (d *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+	if d.index != nil {
+		for _, ident := range d.index.visible(d.offset(pos)) {
+			if seen[ident.Value] {
+				continue
+			}
+			seen[ident.Value] = true
+			items = append(items, CompletionItem{Label: ident.Value, Kind: d.completionKind(ident)})
+		}
+	}
+	for _, name := range evaluate.Builtins() {
+		if !seen[name] {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: "builtin"})
+		}
+	}
+	return items
+}
+
+func (d *document) completionKind(ident *ast.Identifier) int {
+	switch owner := d.index.owners[ident].(type) {
+	case *ast.LetStatement:
+		if _, ok := owner.Value.(*ast.FunctionLiteral); ok && owner.Pattern == nil {
+			return CompletionFunction
+		}
+		if owner.Constant() {
+			return CompletionConstant
+		}
+	case *ast.ImportStatement:
+		return CompletionModule
+	case *ast.StructStatement:
+		return CompletionStruct
+	case *ast.EnumStatement:
+		if ident == owner.Name {
+			return CompletionEnum
+		}
+		return CompletionEnumMember
+	}
+	return CompletionVariable
+}
+
+// symbols lists the declarations at the top level of the document, with
+// the fields and methods of structs and the variants of enums beneath
+// them.
+func (d *document) symbols() []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	if d.program == nil {
+		return symbols
+	}
+	for _, stmt := range d.program.Statements {
+		symbols = append(symbols, d.statementSymbols(stmt)...)
+	}
+	return symbols
+}
+
+func (d *document) statementSymbols(stmt ast.StatmentNode) []DocumentSymbol {
+	switch stmt := stmt.(type) {
+	case *ast.ExportStatement:
+		return d.statementSymbols(stmt.Statement)
+	case *ast.LetStatement:
+		kind := SymbolVariable
+		if stmt.Constant() {
+			kind = SymbolConstant
+		}
+		if stmt.Pattern != nil {
+			symbols := []DocumentSymbol{}
+			for _, name := range stmt.Names() {
+				symbols = append(symbols, d.symbol(name, kind, stmt.Token, name.Token))
+			}
+			return symbols
+		}
+		if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			kind = SymbolFunction
+		}
+		return []DocumentSymbol{d.symbol(stmt.Name, kind, stmt.Token, endToken(stmt.Value, stmt.Name.Token))}
+	case *ast.ImportStatement:
+		return []DocumentSymbol{d.symbol(stmt.Name, SymbolModule, stmt.Token, stmt.Path.Token)}
+	case *ast.StructStatement:
+		symbol := d.symbol(stmt.Name, SymbolStruct, stmt.Token, stmt.Name.Token)
+		for _, field := range stmt.Fields {
+			symbol.Children = append(symbol.Children, d.symbol(field, SymbolField, field.Token, field.Token))
+		}
+		for _, method := range stmt.Methods {
+			symbol.Children = append(symbol.Children, d.symbol(method.Name, SymbolMethod, method.Name.Token, method.Function.Body.End))
+		}
+		if len(symbol.Children) > 0 {
+			symbol.Range.End = symbol.Children[len(symbol.Children)-1].Range.End
+		}
+		return []DocumentSymbol{symbol}
+	case *ast.EnumStatement:
+		symbol := d.symbol(stmt.Name, SymbolEnum, stmt.Token, stmt.Name.Token)
+		for _, variant := range stmt.Variants {
+			end := variant.Name.Token
+			if len(variant.Fields) > 0 {
+				end = variant.Fields[len(variant.Fields)-1].Token
+			}
+			symbol.Children = append(symbol.Children, d.symbol(variant.Name, SymbolEnumMember, variant.Name.Token, end))
+		}
+		if len(symbol.Children) > 0 {
+			symbol.Range.End = symbol.Children[len(symbol.Children)-1].Range.End
+		}
+		return []DocumentSymbol{symbol}
+	}
+	return nil
+}
+
+// symbol describes the declaration of name, which spans from start to end.
+func (d *document) symbol(name *ast.Identifier, kind int, start, end token.Token) DocumentSymbol {
+	return DocumentSymbol{
+		Name:           name.Value,
+		Kind:           kind,
+		Range:          d.between(start, end),
+		SelectionRange: d.tokenRange(name.Token),
+	}
+}
+
+// endToken is the last token of the values whose end the parser records,
+// and otherwise fallback.
+func endToken(exp ast.ExpressionNode, fallback token.Token) token.Token {
+	switch exp := exp.(type) {
+	case *ast.FunctionLiteral:
+		return exp.Body.End
+	case *ast.MatchExpression:
+		return exp.End
+	}
+	return fallback
+}
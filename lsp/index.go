@@ -0,0 +1,244 @@
+package lsp
+
+import (
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/resolver"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+// index records where the names of a program are written: every
+// identifier that names a variable, the node that declares each binding,
+// and the source ranges of the scopes completion looks in.
+type index struct {
+	res    *resolver.Resolution
+	idents []*ast.Identifier
+	owners map[*ast.Identifier]ast.Node //the let, function, match, ... declaring a name
+	global *scope
+}
+
+// scope is the part of the program a function, or an arm of a match,
+// spans, with the names declared in it. The global scope spans everything.
+type scope struct {
+	start, end resolver.Position
+	names      []*ast.Identifier
+	inner      []*scope
+}
+
+func newIndex(program *ast.Program, res *resolver.Resolution) *index {
+	ix := &index{res: res, owners: map[*ast.Identifier]ast.Node{}, global: &scope{}}
+	for _, stmt := range program.Statements {
+		ix.statement(stmt, ix.global)
+	}
+	return ix
+}
+
+func positionOf(tok token.Token) resolver.Position {
+	return resolver.Position{Line: tok.Line, Column: tok.Column}
+}
+
+func before(a, b resolver.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// identifierAt returns the identifier written at pos, including the
+// position just past its end, where the cursor is after typing it.
+func (ix *index) identifierAt(pos resolver.Position) *ast.Identifier {
+	for _, ident := range ix.idents {
+		start := positionOf(ident.Token)
+		end := resolver.Position{Line: start.Line, Column: start.Column + len(ident.Token.Literal)}
+		if !before(pos, start) && !before(end, pos) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// visible returns the names in scope at pos, innermost first. Local names
+// are only visible after their declaration; global ones everywhere, since
+// function bodies may use names declared after them.
+func (ix *index) visible(pos resolver.Position) []*ast.Identifier {
+	names := []*ast.Identifier{}
+	var visit func(s *scope)
+	visit = func(s *scope) {
+		for _, inner := range s.inner {
+			if !before(pos, inner.start) && !before(inner.end, pos) {
+				visit(inner)
+			}
+		}
+		for i := len(s.names) - 1; i >= 0; i-- {
+			if s == ix.global || before(positionOf(s.names[i].Token), pos) {
+				names = append(names, s.names[i])
+			}
+		}
+	}
+	visit(ix.global)
+	return names
+}
+
+func (ix *index) declare(ident *ast.Identifier, owner ast.Node, s *scope) {
+	ix.idents = append(ix.idents, ident)
+	ix.owners[ident] = owner
+	s.names = append(s.names, ident)
+}
+
+func (ix *index) use(ident *ast.Identifier) {
+	ix.idents = append(ix.idents, ident)
+}
+
+func (ix *index) statement(stmt ast.StatmentNode, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		ix.expression(stmt.Value, s)
+		if stmt.Pattern != nil {
+			ix.pattern(stmt.Pattern, stmt, s)
+			return
+		}
+		ix.declare(stmt.Name, stmt, s)
+	case *ast.ReturnStatement:
+		ix.expression(stmt.Value, s)
+	case *ast.ExpressionStatement:
+		ix.expression(stmt.Expression, s)
+	case *ast.ImportStatement:
+		ix.declare(stmt.Name, stmt, s)
+	case *ast.ExportStatement:
+		ix.statement(stmt.Statement, s)
+	case *ast.StructStatement:
+		ix.declare(stmt.Name, stmt, s)
+		for _, method := range stmt.Methods {
+			ix.function(method.Function, s)
+		}
+	case *ast.EnumStatement:
+		ix.declare(stmt.Name, stmt, s)
+		for _, variant := range stmt.Variants {
+			ix.declare(variant.Name, stmt, s)
+		}
+	}
+}
+
+func (ix *index) block(block *ast.BlockStatement, s *scope) {
+	for _, stmt := range block.Statements {
+		ix.statement(stmt, s)
+	}
+}
+
+func (ix *index) function(fn *ast.FunctionLiteral, s *scope) {
+	inner := &scope{start: positionOf(fn.Token), end: positionOf(fn.Body.End)}
+	s.inner = append(s.inner, inner)
+	for i, param := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			ix.expression(fn.Defaults[i], inner)
+		}
+		ix.declare(param, fn, inner)
+	}
+	if fn.Rest != nil {
+		ix.declare(fn.Rest, fn, inner)
+	}
+	ix.block(fn.Body, inner)
+}
+
+func (ix *index) expression(exp ast.ExpressionNode, s *scope) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		ix.use(exp)
+	case *ast.PrefixExpression:
+		ix.expression(exp.Right, s)
+	case *ast.InfixExpression:
+		ix.expression(exp.Left, s)
+		ix.expression(exp.Right, s)
+	case *ast.IfExpression:
+		ix.expression(exp.Condition, s)
+		ix.block(exp.Consequence, s)
+		if exp.Alternative != nil {
+			ix.block(exp.Alternative, s)
+		}
+	case *ast.FunctionLiteral:
+		ix.function(exp, s)
+	case *ast.CallExpression:
+		ix.expression(exp.Function, s)
+		for _, arg := range exp.Arguments {
+			ix.expression(arg, s)
+		}
+	case *ast.ArrayLiteral:
+		for _, ele := range exp.Elements {
+			ix.expression(ele, s)
+		}
+	case *ast.IndexExpression:
+		ix.expression(exp.Left, s)
+		ix.expression(exp.Index, s)
+	case *ast.HashLiteral:
+		for key, value := range exp.Pairs {
+			ix.expression(key, s)
+			ix.expression(value, s)
+		}
+	case *ast.SpawnExpression:
+		ix.expression(exp.Call, s)
+	case *ast.SelectExpression:
+		for _, sc := range exp.Cases {
+			if sc.Comm != nil {
+				ix.expression(sc.Comm, s)
+			}
+			if sc.Name != nil {
+				ix.declare(sc.Name, exp, s)
+			}
+			ix.block(sc.Body, s)
+		}
+	case *ast.MemberExpression:
+		ix.expression(exp.Object, s)
+	case *ast.AssignExpression:
+		ix.expression(exp.Target, s)
+		ix.expression(exp.Value, s)
+	case *ast.MatchExpression:
+		ix.expression(exp.Subject, s)
+		for i, arm := range exp.Arms {
+			// an arm reaches up to the next one, or to the closing brace
+			end := exp.End
+			if i+1 < len(exp.Arms) {
+				end = ast.PatternStartToken(exp.Arms[i+1].Pattern)
+			}
+			inner := &scope{start: positionOf(ast.PatternStartToken(arm.Pattern)), end: positionOf(end)}
+			s.inner = append(s.inner, inner)
+			ix.pattern(arm.Pattern, exp, inner)
+			if arm.Guard != nil {
+				ix.expression(arm.Guard, inner)
+			}
+			ix.block(arm.Body, inner)
+		}
+	case *ast.SpreadExpression:
+		ix.expression(exp.Value, s)
+	case *ast.KeywordArgument:
+		ix.expression(exp.Value, s)
+	}
+}
+
+// pattern declares the names pattern binds, owned by the let or match it
+// belongs to.
+func (ix *index) pattern(pattern ast.Pattern, owner ast.Node, s *scope) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		// a variant without fields is compared against, not bound
+		if _, ok := ix.res.Definitions[pattern.Name]; ok {
+			ix.use(pattern.Name)
+			return
+		}
+		ix.declare(pattern.Name, owner, s)
+	case *ast.DefaultPattern:
+		ix.expression(pattern.Default, s)
+		ix.pattern(pattern.Pattern, owner, s)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			ix.pattern(element, owner, s)
+		}
+		if pattern.Rest != nil {
+			ix.pattern(pattern.Rest, owner, s)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			ix.pattern(pair.Value, owner, s)
+		}
+	case *ast.ConstructorPattern:
+		ix.expression(pattern.Constructor, s)
+		for _, field := range pattern.Fields {
+			ix.pattern(field, owner, s)
+		}
+	}
+}
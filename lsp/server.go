@@ -0,0 +1,192 @@
+// Package lsp is a Language Server Protocol server for the language,
+// speaking JSON-RPC over a pair of streams. It keeps the text of each
+// open document, parses and resolves it on every change, and answers with
+// diagnostics, definitions, hovers, document symbols, completions and
+// formatting.
+//
+// Documents are synchronized in full: every change carries the whole new
+// text.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/nishokbanand/interpreter/format"
+)
+
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: map[string]*document{}}
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+// It fails if the client exits without asking the server to shut down
+// first.
+func (s *Server) Serve() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := s.respond(json.RawMessage("null"), nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		if msg.ID == nil {
+			if err := s.notification(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rpcErr := s.request(msg)
+		if err := s.respond(msg.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) respond(id json.RawMessage, result interface{}, rpcErr *rpcError) error {
+	msg := &message{ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		msg.Result = raw
+	}
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: raw})
+}
+
+func (s *Server) publishDiagnostics(doc *document) error {
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: doc.uri, Diagnostics: doc.diagnostics})
+}
+
+// notification handles a message that expects no response. Malformed
+// notifications, and those the server does not know, are ignored.
+func (s *Server) notification(msg *message) error {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		params := DidOpenTextDocumentParams{}
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		doc := newDocument(params.TextDocument.URI, params.TextDocument.Text)
+		s.docs[doc.uri] = doc
+		return s.publishDiagnostics(doc)
+	case "textDocument/didChange":
+		params := DidChangeTextDocumentParams{}
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil
+		}
+		doc.update(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		return s.publishDiagnostics(doc)
+	case "textDocument/didClose":
+		params := DidCloseTextDocumentParams{}
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	}
+	return nil
+}
+
+// request handles a message that expects a response, returning either its
+// result or an error.
+func (s *Server) request(msg *message) (interface{}, *rpcError) {
+	if s.shutdown {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "the server is shutting down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, //full
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"documentSymbolProvider":     true,
+				"completionProvider":         map[string]interface{}{},
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "interpreter"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition", "textDocument/hover", "textDocument/completion":
+		params := TextDocumentPositionParams{}
+		doc, rpcErr := s.document(msg.Params, &params, &params.TextDocument)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		switch msg.Method {
+		case "textDocument/definition":
+			return doc.definitionLocation(params.Position), nil
+		case "textDocument/hover":
+			return doc.hover(params.Position), nil
+		}
+		return doc.completion(params.Position), nil
+	case "textDocument/documentSymbol", "textDocument/formatting":
+		params := DocumentParams{}
+		doc, rpcErr := s.document(msg.Params, &params, &params.TextDocument)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if msg.Method == "textDocument/documentSymbol" {
+			return doc.symbols(), nil
+		}
+		formatted, err := format.Source([]byte(doc.text))
+		if err != nil {
+			// there is nothing to format until the document parses
+			return nil, nil
+		}
+		return []TextEdit{{Range: doc.wholeRange(), NewText: string(formatted)}}, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found " + msg.Method}
+}
+
+// document decodes params into v and returns the open document that id,
+// a field of v, names.
+func (s *Server) document(params json.RawMessage, v interface{}, id *TextDocumentIdentifier) (*document, *rpcError) {
+	if err := json.Unmarshal(params, v); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	doc, ok := s.docs[id.URI]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "document not open " + id.URI}
+	}
+	return doc, nil
+}
@@ -0,0 +1,390 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///test.mk"
+
+// client drives a server running in the same process over a pair of pipes,
+// the way an editor would over stdio.
+type client struct {
+	t             *testing.T
+	in            *bufio.Reader
+	out           io.WriteCloser
+	id            int
+	notifications []*message
+	done          chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, in: bufio.NewReader(clientIn), out: clientOut, done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+	c.call("initialize", map[string]interface{}{}, nil)
+	c.notify("initialized", map[string]interface{}{})
+	return c
+}
+
+func (c *client) send(msg *message) {
+	c.t.Helper()
+	if err := writeMessage(c.out, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.send(&message{Method: method, Params: raw})
+}
+
+// call sends a request and decodes the result of its response into
+// result, returning the error the server answered with, if any.
+// Notifications that arrive first are kept for later.
+func (c *client) call(method string, params, result interface{}) *rpcError {
+	c.t.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.id++
+	id := json.RawMessage(mustMarshal(c.t, c.id))
+	c.send(&message{ID: id, Method: method, Params: raw})
+	for {
+		msg, err := readMessage(c.in)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if string(msg.ID) != string(id) {
+			c.t.Fatalf("response to %s, expected %s", msg.ID, id)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return nil
+	}
+}
+
+// diagnostics returns the next diagnostics the server published.
+func (c *client) diagnostics() []Diagnostic {
+	c.t.Helper()
+	for {
+		var msg *message
+		if len(c.notifications) > 0 {
+			msg, c.notifications = c.notifications[0], c.notifications[1:]
+		} else {
+			var err error
+			if msg, err = readMessage(c.in); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		params := PublishDiagnosticsParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+		return params.Diagnostics
+	}
+}
+
+func (c *client) open(text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text},
+	})
+	return c.diagnostics()
+}
+
+func (c *client) change(text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": text}},
+	})
+	return c.diagnostics()
+}
+
+func (c *client) close() error {
+	c.t.Helper()
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	return <-c.done
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	diagnostics := c.open("let f = fn() { let unused = 1; 2 };\nlet x: int = \"s\";\nmissing")
+	expected := []struct {
+		severity int
+		start    Position
+		message  string
+	}{
+		{SeverityWarning, Position{0, 19}, "unused declared and not used"},
+		{SeverityError, Position{1, 13}, "cannot use string as int in let x"},
+		{SeverityError, Position{2, 0}, "identifier not found missing"},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(expected), diagnostics)
+	}
+	for i, want := range expected {
+		got := diagnostics[i]
+		if got.Severity != want.severity || got.Range.Start != want.start || got.Message != want.message {
+			t.Errorf("diagnostic %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	diagnostics = c.change("let x = 1;\nlet = 2;")
+	if len(diagnostics) == 0 || diagnostics[0].Range.Start != (Position{1, 4}) ||
+		!strings.Contains(diagnostics[0].Message, "expected next token IDENT") {
+		t.Errorf("expected a parse error at 1:4, got %+v", diagnostics)
+	}
+	if diagnostics = c.change("let x = 1;\nx"); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", diagnostics)
+	}
+	if err := c.close(); err != nil {
+		t.Error(err)
+	}
+}
+
+const program = `let add = fn(a, b = 1) { a + b };
+let x = add(1, 2);
+struct Point { x, y fn norm() { self.x } }
+enum Shape { Circle(r), Empty }
+let f = fn(n) {
+	let m = n;
+	len(m)
+};
+match x { 1 => x, k => k }`
+
+// TestIncompleteText analyses each prefix of the fixtures, as the server
+// sees them while they are typed.
+func TestIncompleteText(t *testing.T) {
+	fixtures := []string{program, "let f = fn() { let unused = 1; 2 };\nlet x: int = \"s\";\nmissing", "let v = match 1 { 1 = "}
+	for _, text := range fixtures {
+		for i := range text {
+			d := newDocument(uri, text[:i])
+			for _, diag := range d.diagnostics {
+				if strings.HasPrefix(diag.Message, "internal error") {
+					t.Fatalf("%q: %s", text[:i], diag.Message)
+				}
+			}
+		}
+	}
+	d := newDocument(uri, "let v = match 1 { 1 =")
+	if len(d.diagnostics) == 0 || d.diagnostics[0].Message != "expected next token => , got token =" {
+		t.Errorf("expected a parse error, got %+v", d.diagnostics)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	c.open(program)
+	tests := []struct {
+		position TextDocumentPositionParams
+		expected *Range
+	}{
+		{at(0, 25), &Range{Position{0, 13}, Position{0, 14}}}, //a in the body
+		{at(1, 9), &Range{Position{0, 4}, Position{0, 7}}},    //add
+		{at(1, 11), &Range{Position{0, 4}, Position{0, 7}}},   //just past add
+		{at(0, 13), &Range{Position{0, 13}, Position{0, 14}}}, //a, a declaration
+		{at(6, 5), &Range{Position{5, 5}, Position{5, 6}}},    //m
+		{at(8, 23), &Range{Position{8, 18}, Position{8, 19}}}, //k bound by the arm
+		{at(8, 6), &Range{Position{1, 4}, Position{1, 5}}},    //the subject x
+		{at(6, 2), nil}, //len, a builtin
+		{at(3, 0), nil}, //enum, not a name
+	}
+	for _, tt := range tests {
+		var location *Location
+		if err := c.call("textDocument/definition", tt.position, &location); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tt.expected == nil && location != nil:
+			t.Errorf("%+v: expected no definition, got %+v", tt.position.Position, location)
+		case tt.expected != nil && (location == nil || location.Range != *tt.expected || location.URI != uri):
+			t.Errorf("%+v: expected %+v, got %+v", tt.position.Position, tt.expected, location)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	c.open(program)
+	tests := []struct {
+		position TextDocumentPositionParams
+		expected string
+	}{
+		{at(1, 4), "let x = add(1, 2);"},
+		{at(8, 6), "let x = add(1, 2);"},
+		{at(0, 29), "(parameter) b = 1"},
+		{at(6, 2), "(builtin) len"},
+		{at(3, 14), "(variant) Shape.Circle(r)"},
+		{at(2, 8), "struct Point { x, y }"},
+		{at(8, 18), "(match binding) k"},
+	}
+	for _, tt := range tests {
+		var hover *Hover
+		if err := c.call("textDocument/hover", tt.position, &hover); err != nil {
+			t.Fatal(err)
+		}
+		if hover == nil {
+			t.Errorf("%+v: expected a hover", tt.position.Position)
+			continue
+		}
+		if expected := "```\n" + tt.expected + "\n```"; hover.Contents.Value != expected {
+			t.Errorf("%+v: expected %q, got %q", tt.position.Position, expected, hover.Contents.Value)
+		}
+	}
+	var hover *Hover
+	if err := c.call("textDocument/hover", at(0, 0), &hover); err != nil || hover != nil {
+		t.Errorf("expected no hover over let, got %+v, %v", hover, err)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := newClient(t)
+	c.open(program)
+	var symbols []DocumentSymbol
+	if err := c.call("textDocument/documentSymbol", DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &symbols); err != nil {
+		t.Fatal(err)
+	}
+	summary := []string{}
+	var walk func(prefix string, symbols []DocumentSymbol)
+	walk = func(prefix string, symbols []DocumentSymbol) {
+		for _, s := range symbols {
+			summary = append(summary, prefix+s.Name)
+			walk(prefix+s.Name+".", s.Children)
+		}
+	}
+	walk("", symbols)
+	expected := "add x Point Point.x Point.y Point.norm Shape Shape.Circle Shape.Empty f"
+	if got := strings.Join(summary, " "); got != expected {
+		t.Fatalf("expected symbols %q, got %q", expected, got)
+	}
+	if symbols[0].Kind != SymbolFunction || symbols[0].Range != (Range{Position{0, 0}, Position{0, 32}}) {
+		t.Errorf("wrong symbol for add: %+v", symbols[0])
+	}
+	if symbols[2].Kind != SymbolStruct || symbols[2].Children[2].Kind != SymbolMethod {
+		t.Errorf("wrong symbol for Point: %+v", symbols[2])
+	}
+	if symbols[4].Range != (Range{Position{4, 0}, Position{7, 1}}) {
+		t.Errorf("wrong range for f: %+v", symbols[4].Range)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	c.open(program)
+	var items []CompletionItem
+	if err := c.call("textDocument/completion", at(6, 1), &items); err != nil {
+		t.Fatal(err)
+	}
+	labels := []string{}
+	kinds := map[string]int{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+		kinds[item.Label] = item.Kind
+	}
+	got := strings.Join(labels, " ")
+	if !strings.HasPrefix(got, "m n f Empty Circle Shape Point x add ") {
+		t.Errorf("wrong names in scope: %s", got)
+	}
+	for _, name := range []string{"len", "print", "chan"} {
+		if kinds[name] != CompletionFunction {
+			t.Errorf("expected builtin %s, got %s", name, got)
+		}
+	}
+	for _, name := range []string{"a", "b", "k"} {
+		if _, ok := kinds[name]; ok {
+			t.Errorf("%s is not in scope: %s", name, got)
+		}
+	}
+	if kinds["add"] != CompletionFunction || kinds["Point"] != CompletionStruct || kinds["Empty"] != CompletionEnumMember {
+		t.Errorf("wrong kinds %v", kinds)
+	}
+
+	// m is declared after the start of the body
+	items = nil
+	if err := c.call("textDocument/completion", at(5, 1), &items); err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Label != "n" {
+		t.Errorf("expected n first, got %+v", items[0])
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t)
+	c.open("let x=1\n// done\nx")
+	params := DocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}}
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", params, &edits); err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].NewText != "let x = 1;\n// done\nx\n" ||
+		edits[0].Range != (Range{Position{0, 0}, Position{2, 1}}) {
+		t.Errorf("wrong edits %+v", edits)
+	}
+	c.change("let = 1")
+	edits = nil
+	if err := c.call("textDocument/formatting", params, &edits); err != nil || edits != nil {
+		t.Errorf("expected no edits for a document that does not parse, got %+v, %v", edits, err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	c := newClient(t)
+	if err := c.call("textDocument/rename", at(0, 0), nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+	if err := c.call("textDocument/hover", at(0, 0), nil); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("expected an error for a document that is not open, got %v", err)
+	}
+	if err := c.close(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	c = newClient(t)
+	c.notify("exit", nil)
+	if err := <-c.done; err == nil || err.Error() != "exit before shutdown" {
+		t.Errorf("expected exit before shutdown, got %v", err)
+	}
+}
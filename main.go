@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -8,6 +9,53 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bench":
+			exitOnError(benchCommand(os.Args[2:], os.Stdout))
+			return
+		case "check":
+			exitOnError(checkCommand(os.Args[2:], os.Stdout))
+			return
+		case "fmt":
+			exitOnError(fmtCommand(os.Args[2:], os.Stdout))
+			return
+		case "debug":
+			exitOnError(debugCommand(os.Args[2:], os.Stdin, os.Stdout))
+			return
+		case "lsp":
+			exitOnError(lspCommand(os.Args[2:]))
+			return
+		case "dap":
+			exitOnError(dapCommand(os.Args[2:]))
+			return
+		case "profile":
+			exitOnError(profileCommand(os.Args[2:], os.Stdout, os.Stderr))
+			return
+		}
+	}
+	engine := flag.String("engine", engineEval, "backend to run with: eval or vm, which runs the core language only")
+	warn := flag.Bool("warn", false, "print unused and shadowed names before running")
+	flag.Parse()
+	if err := checkEngine(*engine); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if flag.NArg() > 0 {
+		exitOnError(runFile(flag.Arg(0), *engine, *warn))
+		return
+	}
 	fmt.Println("REPL starting")
+	if *engine == engineVM {
+		repl.StartVM(os.Stdin, os.Stdout)
+		return
+	}
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,128 @@
+package object
+
+import "fmt"
+
+// Builtins lists the builtin functions in a fixed order, so that the
+// compiler can refer to them by index while the evaluator looks them up by
+// name.
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{
+		"len",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("Wrong Number of args, want 1, got %d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *String:
+				return &Integer{Value: int64(len(arg.Value))}
+			case *Array:
+				return &Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newError("argument to 'len' not supported, got %s", arg.Type())
+			}
+		}},
+	},
+	{
+		"first",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("Wrong Number of args, want 1, got %d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Array:
+				if len(arg.Elements) == 0 {
+					return Null
+				}
+				return arg.Elements[0]
+			default:
+				return newError("argument to 'first' not supported, got %s", arg.Type())
+			}
+		}},
+	},
+	{
+		"last",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("Wrong Number of args, want 1, got %d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Array:
+				if len(arg.Elements) == 0 {
+					return Null
+				}
+				return arg.Elements[len(arg.Elements)-1]
+			default:
+				return newError("argument to 'last' not supported, got %s", arg.Type())
+			}
+		}},
+	},
+	{
+		"rest",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("Wrong Number of args, want 1, got %d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Array:
+				length := len(arg.Elements)
+				if length == 0 {
+					return Null
+				}
+				newArr := make([]Object, length-1, length-1)
+				copy(newArr, arg.Elements[1:length])
+				return &Array{Elements: newArr}
+			default:
+				return newError("argument to 'rest' not supported, got %s", arg.Type())
+			}
+		}},
+	},
+	{
+		"push",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 2 {
+				return newError("Wrong Number of args, want 2, got %d", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *Array:
+				length := len(arg.Elements)
+				newArr := make([]Object, length+1, length+1)
+				copy(newArr, arg.Elements[:])
+				newArr[length] = args[1]
+				return &Array{Elements: newArr}
+			default:
+				return newError("argument to 'push' not supported, got %s", arg.Type())
+			}
+		}},
+	},
+	{"print", &Builtin{Fn: processStreams.print}},
+	{"eprint", &Builtin{Fn: processStreams.eprint}},
+	{"println", &Builtin{Fn: processStreams.println}},
+	{"printf", &Builtin{Fn: processStreams.printf}},
+	{"input", &Builtin{Fn: processStreams.input}},
+	{"read_line", &Builtin{Fn: processStreams.readLine}},
+	{
+		"type",
+		&Builtin{Fn: func(args ...Object) Object {
+			if len(args) != 1 {
+				return newError("Wrong Number of args, want 1, got %d", len(args))
+			}
+			return &String{Value: TypeName(args[0])}
+		}},
+	},
+}
+
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,411 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// TagName is the struct tag consulted when converting structs to and from
+// hashes. `interp:"name"` renames a field, `interp:"-"` skips it and
+// `interp:",omitempty"` leaves out zero values.
+const TagName = "interp"
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	anyType    = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+// FromGo converts a Go value into an Object. Integers, strings and booleans
+// map onto their counterparts, slices and arrays onto arrays, maps with
+// string, integer or boolean keys and structs onto hashes, and nil or nil
+// pointers onto null. Values that already are Objects are returned as is.
+// A value that contains itself cannot be converted.
+func FromGo(value any) (Object, error) {
+	if value == nil {
+		return Null, nil
+	}
+	return fromValue(reflect.ValueOf(value))
+}
+
+func fromValue(v reflect.Value) (Object, error) {
+	return fromVisiting(v, map[visit]bool{})
+}
+
+// visit is a pointer, map or slice being converted. A value found again
+// inside itself is cyclic: converting it would never end.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int //slices sharing an array only overlap with the same length
+}
+
+// fromVisiting converts v, which is inside the values in visiting.
+func fromVisiting(v reflect.Value, visiting map[visit]bool) (Object, error) {
+	if v.Type().Implements(objectType) {
+		if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return Null, nil
+			}
+		}
+		return v.Interface().(Object), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return True, nil
+		}
+		return False, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("cannot convert %d to INTEGER: out of range", v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return Null, nil
+		}
+		if v.Kind() == reflect.Pointer {
+			done, err := enter(v, visiting)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		return fromVisiting(v.Elem(), visiting)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return Null, nil
+			}
+			done, err := enter(v, visiting)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			ele, err := fromVisiting(v.Index(i), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = ele
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return Null, nil
+		}
+		done, err := enter(v, visiting)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		pairs := make(map[HashKey]HashPair, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromVisiting(iter.Key(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("cannot use %s as hash key", iter.Key().Type())
+			}
+			value, err := fromVisiting(iter.Value(), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			pairs[hashable.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return &Hash{Pairs: pairs}, nil
+	case reflect.Struct:
+		pairs := make(map[HashKey]HashPair)
+		for _, field := range structFields(v.Type()) {
+			fv := v.Field(field.index)
+			if field.omitEmpty && fv.IsZero() {
+				continue
+			}
+			value, err := fromVisiting(fv, visiting)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			key := &String{Value: field.name}
+			pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+		}
+		return &Hash{Pairs: pairs}, nil
+	}
+	return nil, fmt.Errorf("cannot convert Go value of type %s", v.Type())
+}
+
+// enter adds v, a non-nil pointer, map or slice, to visiting until done is
+// called, failing if it is there already.
+func enter(v reflect.Value, visiting map[visit]bool) (done func(), err error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if visiting[key] {
+		return nil, fmt.Errorf("cannot convert cyclic value of type %s", v.Type())
+	}
+	visiting[key] = true
+	return func() { delete(visiting, key) }, nil
+}
+
+// ToGo stores obj into the Go value target points to, converting it to the
+// target's type. Converting into an interface picks the natural Go type:
+// int64, string, bool, []any, map[string]any (or map[any]any when a hash
+// has non string keys) and nil for null.
+func ToGo(obj Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("ToGo target must be a non-nil pointer, got %T", target)
+	}
+	return toValue(obj, v.Elem())
+}
+
+func toValue(obj Object, v reflect.Value) error {
+	if obj == nil {
+		obj = Null
+	}
+	// targets such as Object or *Array take the object itself, only `any`
+	// asks for a conversion
+	if objValue := reflect.ValueOf(obj); v.Type() != anyType && objValue.Type().AssignableTo(v.Type()) {
+		v.Set(objValue)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		natural, err := naturalValue(obj)
+		if err != nil {
+			return err
+		}
+		if natural == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		nv := reflect.ValueOf(natural)
+		if !nv.Type().AssignableTo(v.Type()) {
+			return mismatch(obj, v.Type())
+		}
+		v.Set(nv)
+		return nil
+	case reflect.Pointer:
+		if obj == Null {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := toValue(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		v.SetBool(b.Value)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		if v.OverflowInt(i.Value) {
+			return fmt.Errorf("cannot convert %d to %s: out of range", i.Value, v.Type())
+		}
+		v.SetInt(i.Value)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return fmt.Errorf("cannot convert %d to %s: out of range", i.Value, v.Type())
+		}
+		v.SetUint(uint64(i.Value))
+		return nil
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		v.SetString(s.Value)
+		return nil
+	case reflect.Slice:
+		if obj == Null {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		arr, ok := obj.(*Array)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		slice := reflect.MakeSlice(v.Type(), len(arr.Elements), len(arr.Elements))
+		for i, ele := range arr.Elements {
+			if err := toValue(ele, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		arr, ok := obj.(*Array)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		if len(arr.Elements) != v.Len() {
+			return fmt.Errorf("cannot convert ARRAY of length %d to %s", len(arr.Elements), v.Type())
+		}
+		for i, ele := range arr.Elements {
+			if err := toValue(ele, v.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if obj == Null {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+		for _, pair := range hash.Pairs {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := toValue(pair.Key, key); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := toValue(pair.Value, value); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch(obj, v.Type())
+		}
+		// keys without a matching field are ignored, missing fields keep
+		// their zero value
+		for _, field := range structFields(v.Type()) {
+			key := &String{Value: field.name}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				continue
+			}
+			if err := toValue(pair.Value, v.Field(field.index)); err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot convert to Go value of type %s", v.Type())
+}
+
+// naturalValue is the Go value an object converts to when the target type
+// leaves the choice open.
+func naturalValue(obj Object) (any, error) {
+	switch obj := obj.(type) {
+	case *NULL:
+		return nil, nil
+	case *Integer:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Array:
+		values := make([]any, len(obj.Elements))
+		for i, ele := range obj.Elements {
+			value, err := naturalValue(ele)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			values[i] = value
+		}
+		return values, nil
+	case *Hash:
+		allStrings := true
+		for _, pair := range obj.Pairs {
+			if _, ok := pair.Key.(*String); !ok {
+				allStrings = false
+				break
+			}
+		}
+		if allStrings {
+			values := make(map[string]any, len(obj.Pairs))
+			for _, pair := range obj.Pairs {
+				value, err := naturalValue(pair.Value)
+				if err != nil {
+					return nil, err
+				}
+				values[pair.Key.(*String).Value] = value
+			}
+			return values, nil
+		}
+		values := make(map[any]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := naturalValue(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := naturalValue(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		return values, nil
+	default:
+		// functions and the like have no Go counterpart, hand them over as is
+		return obj, nil
+	}
+}
+
+func mismatch(obj Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+type structField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func structFields(t reflect.Type) []structField {
+	fields := []structField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field := structField{name: f.Name, index: i}
+		if tag, ok := f.Tag.Lookup(TagName); ok {
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				field.name = name
+			}
+			field.omitEmpty = opts == "omitempty"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,182 @@
+package object
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `interp:"city"`
+	Zip  int    `interp:"zip,omitempty"`
+}
+
+type person struct {
+	Name     string   `interp:"name"`
+	Age      int      `interp:"age"`
+	Admin    bool     `interp:"admin"`
+	Tags     []string `interp:"tags"`
+	Address  *address `interp:"address"`
+	Password string   `interp:"-"`
+	internal int
+}
+
+func TestFromGo(t *testing.T) {
+	var nilPointer *address
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{nilPointer, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{-3, "-3"},
+		{"hi", "hi"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1,2,3]"},
+		{[2]string{"a", "b"}, "[a,b]"},
+		{[]any{1, "two", false, nil}, "[1,two,false,null]"},
+		{map[string]int{"one": 1}, "{one:1}"},
+		{map[int]string{1: "one"}, "{1:one}"},
+		{&address{City: "Paris"}, "{city:Paris}"},
+		{&Integer{Value: 5}, "5"},
+	}
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error %v", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) = %s, want %s", tt.input, obj.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestFromGoErrors(t *testing.T) {
+	for _, input := range []any{1.5, map[float64]int{1: 1}, []any{func() {}}, uint64(1 << 63)} {
+		if _, err := FromGo(input); err == nil {
+			t.Errorf("FromGo(%#v) expected error", input)
+		}
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestFromGoCycles(t *testing.T) {
+	list := &node{Value: 1}
+	list.Next = list
+	hash := map[string]any{}
+	hash["self"] = hash
+	slice := []any{1, nil}
+	slice[1] = slice
+	for _, input := range []any{list, hash, slice} {
+		if _, err := FromGo(input); err == nil || !strings.Contains(err.Error(), "cyclic value") {
+			t.Errorf("FromGo(%T) expected a cyclic value error, got %v", input, err)
+		}
+	}
+	// the same value twice is not a cycle
+	shared := &node{Value: 2}
+	obj, err := FromGo([]*node{shared, shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arr, ok := obj.(*Array); !ok || len(arr.Elements) != 2 {
+		t.Errorf("expected an array of two hashes, got %s", obj.Inspect())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	original := person{
+		Name:     "Ada",
+		Age:      36,
+		Admin:    true,
+		Tags:     []string{"math", "engines"},
+		Address:  &address{City: "London", Zip: 12},
+		Password: "secret",
+		internal: 1,
+	}
+	obj, err := FromGo(original)
+	if err != nil {
+		t.Fatalf("FromGo returned error %v", err)
+	}
+	hash := obj.(*Hash)
+	if _, ok := hash.Pairs[(&String{Value: "Password"}).HashKey()]; ok {
+		t.Errorf("skipped field was converted")
+	}
+	var decoded person
+	if err := ToGo(obj, &decoded); err != nil {
+		t.Fatalf("ToGo returned error %v", err)
+	}
+	original.Password = ""
+	original.internal = 0
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("round trip mismatch\nwant %+v\ngot  %+v", original, decoded)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	var i int
+	if err := ToGo(&Integer{Value: 3}, &i); err != nil || i != 3 {
+		t.Errorf("int: got %d, %v", i, err)
+	}
+	var s string
+	if err := ToGo(&String{Value: "x"}, &s); err != nil || s != "x" {
+		t.Errorf("string: got %q, %v", s, err)
+	}
+	var p *int
+	if err := ToGo(Null, &p); err != nil || p != nil {
+		t.Errorf("nil pointer: got %v, %v", p, err)
+	}
+	if err := ToGo(&Integer{Value: 9}, &p); err != nil || p == nil || *p != 9 {
+		t.Errorf("pointer: got %v, %v", p, err)
+	}
+	arr, _ := FromGo([]any{1, "a", []int{2}})
+	var natural any
+	if err := ToGo(arr, &natural); err != nil {
+		t.Fatalf("any: %v", err)
+	}
+	if !reflect.DeepEqual(natural, []any{int64(1), "a", []any{int64(2)}}) {
+		t.Errorf("any: got %#v", natural)
+	}
+	hash, _ := FromGo(map[int]string{1: "one"})
+	var byInt map[int]string
+	if err := ToGo(hash, &byInt); err != nil || byInt[1] != "one" {
+		t.Errorf("map: got %v, %v", byInt, err)
+	}
+	var keep Object
+	if err := ToGo(arr, &keep); err != nil || keep != arr {
+		t.Errorf("Object target should receive the object itself, got %v, %v", keep, err)
+	}
+	var direct *Array
+	if err := ToGo(arr, &direct); err != nil || direct != arr {
+		t.Errorf("*Array target should receive the object itself, got %v, %v", direct, err)
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	var i int8
+	var u uint
+	var b bool
+	var pair [2]int
+	tests := []struct {
+		obj    Object
+		target any
+	}{
+		{&Integer{Value: 1000}, &i},
+		{&Integer{Value: -1}, &u},
+		{&String{Value: "x"}, &b},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &pair},
+		{&Integer{Value: 1}, i},
+		{&Integer{Value: 1}, nil},
+	}
+	for _, tt := range tests {
+		if err := ToGo(tt.obj, tt.target); err == nil {
+			t.Errorf("ToGo(%s, %T) expected error", tt.obj.Inspect(), tt.target)
+		}
+	}
+}
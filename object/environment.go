@@ -1,30 +1,224 @@
 package object
 
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Environment binds names to values. A plain environment is not safe for
+// concurrent use; one created with NewSyncEnvironment guards its bindings
+// with a lock, and so does every environment enclosed by it, so closures
+// created against shared globals stay safe when called from several
+// goroutines.
+//
+// Names declared with Declare may be constant, in which case Declare and
+// Assign refuse to rebind them. Set binds unconditionally and is meant for
+// parameters and other names the evaluator introduces itself.
+//
+// Each name takes the next slot of its environment the first time it is
+// bound there, so a name whose slot is known ahead, see GetAt, is read
+// without a map lookup.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]int //the slot of each name
+	slots  []slot
+	consts map[string]bool //nil until a constant is declared
+	outer  *Environment
+	mu     *sync.RWMutex //nil unless the environment is synchronized
+}
+
+type slot struct {
+	name  string
+	value Object
 }
 
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
+	s := make(map[string]int)
 	return &Environment{store: s, outer: nil}
 }
 
+// NewSyncEnvironment returns an environment that may be read and written by
+// several evaluations at once, for globals shared between interpreters.
+func NewSyncEnvironment() *Environment {
+	env := NewEnvironment()
+	env.mu = &sync.RWMutex{}
+	return env
+}
+
+// Synchronized reports whether the environment is safe for concurrent use.
+func (e *Environment) Synchronized() bool {
+	return e.mu != nil
+}
+
+// Share makes e and the environments enclosing it safe for concurrent use.
+// It must be called while e is still reachable from a single goroutine
+// only, before handing e (say, a closure over it) to another one.
+func (e *Environment) Share() {
+	// an environment enclosed by a synchronized one is synchronized itself,
+	// so the walk can stop at the first one that already is
+	for env := e; env != nil && env.mu == nil; env = env.outer {
+		env.mu = &sync.RWMutex{}
+	}
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
-	value, ok := e.store[name]
+	if e.mu != nil {
+		e.mu.RLock()
+	}
+	value, ok := e.lookup(name)
+	if e.mu != nil {
+		e.mu.RUnlock()
+	}
 	if !ok && e.outer != nil {
 		value, ok = e.outer.Get(name)
 	}
 	return value, ok
 }
 
+// GetAt looks name up in the environment depth levels out from e only,
+// for names whose binding was located before the program ran. index is the
+// slot the name is expected in; if the environment bound its names in
+// another order the name is looked up by name instead.
+func (e *Environment) GetAt(depth, index int, name string) (Object, bool) {
+	env := e
+	for ; depth > 0 && env != nil; depth-- {
+		env = env.outer
+	}
+	if env == nil {
+		return nil, false
+	}
+	if env.mu != nil {
+		env.mu.RLock()
+		defer env.mu.RUnlock()
+	}
+	if index < len(env.slots) && env.slots[index].name == name {
+		return env.slots[index].value, true
+	}
+	return env.lookup(name)
+}
+
+// lookup reads name from e itself. The caller holds the lock.
+func (e *Environment) lookup(name string) (Object, bool) {
+	index, ok := e.store[name]
+	if !ok {
+		return nil, false
+	}
+	return e.slots[index].value, true
+}
+
+// bind binds name in e itself, in the next slot if it is new there. The
+// caller holds the lock.
+func (e *Environment) bind(name string, obj Object) {
+	if index, ok := e.store[name]; ok {
+		e.slots[index].value = obj
+		return
+	}
+	e.store[name] = len(e.slots)
+	e.slots = append(e.slots, slot{name: name, value: obj})
+}
+
 func (e *Environment) Set(name string, obj Object) Object {
-	e.store[name] = obj
+	if e.mu != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	}
+	e.bind(name, obj)
 	return obj
 }
 
+// Declare binds name in e, as a constant if constant is set. Redeclaring a
+// name is allowed unless either the old or the new binding is a constant.
+func (e *Environment) Declare(name string, obj Object, constant bool) error {
+	if e.mu != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	}
+	if e.consts[name] {
+		return fmt.Errorf("cannot redeclare constant %s", name)
+	}
+	if _, ok := e.store[name]; ok && constant {
+		return fmt.Errorf("cannot redeclare %s as a constant", name)
+	}
+	e.bind(name, obj)
+	if constant {
+		if e.consts == nil {
+			e.consts = map[string]bool{}
+		}
+		e.consts[name] = true
+	}
+	return nil
+}
+
+// Assign rebinds name in the innermost environment that binds it, which
+// must not have declared it constant.
+func (e *Environment) Assign(name string, obj Object) error {
+	for env := e; env != nil; env = env.outer {
+		if ok, err := env.assign(name, obj); ok {
+			return err
+		}
+	}
+	return fmt.Errorf("identifier not found %s", name)
+}
+
+// assign rebinds name if e binds it, reporting whether it does.
+func (e *Environment) assign(name string, obj Object) (bool, error) {
+	if e.mu != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	}
+	if _, ok := e.store[name]; !ok {
+		return false, nil
+	}
+	if e.consts[name] {
+		return true, fmt.Errorf("cannot assign to constant %s", name)
+	}
+	e.bind(name, obj)
+	return true, nil
+}
+
+// Names returns the names bound in e itself, not in the environments
+// enclosing it, in order.
+func (e *Environment) Names() []string {
+	if e.mu != nil {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the environment enclosing e, or nil for an outermost one.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// IsConst reports whether name resolves to a constant.
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if env.mu != nil {
+			env.mu.RLock()
+		}
+		_, ok := env.store[name]
+		constant := env.consts[name]
+		if env.mu != nil {
+			env.mu.RUnlock()
+		}
+		if ok {
+			return constant
+		}
+	}
+	return false
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	if outer.mu != nil {
+		env.mu = &sync.RWMutex{}
+	}
 	return env
 }
@@ -0,0 +1,131 @@
+package object
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestEnclosedEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", &Integer{Value: 2})
+	if v, ok := inner.Get("a"); !ok || v.Inspect() != "1" {
+		t.Errorf("expected a from the outer environment, got %v", v)
+	}
+	if _, ok := outer.Get("b"); ok {
+		t.Errorf("inner binding leaked to the outer environment")
+	}
+	if inner.Synchronized() {
+		t.Errorf("environment enclosed by a plain one should not be synchronized")
+	}
+}
+
+// TestSyncEnvironment is meant to be run with -race.
+func TestSyncEnvironment(t *testing.T) {
+	globals := NewSyncEnvironment()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			local := NewEnclosedEnvironment(globals)
+			if !local.Synchronized() {
+				t.Errorf("environment enclosed by a synchronized one should be synchronized")
+				return
+			}
+			for i := 0; i < 1000; i++ {
+				globals.Set(fmt.Sprint("k", i%10), &Integer{Value: int64(i)})
+				local.Set("i", &Integer{Value: int64(i)})
+				local.Get("k5")
+			}
+		}(w)
+	}
+	wg.Wait()
+	if _, ok := globals.Get("k9"); !ok {
+		t.Errorf("expected k9 to be set")
+	}
+}
+
+func TestConstants(t *testing.T) {
+	outer := NewEnvironment()
+	if err := outer.Declare("c", &Integer{Value: 1}, true); err != nil {
+		t.Fatalf("declaring c: %s", err)
+	}
+	if err := outer.Declare("v", &Integer{Value: 1}, false); err != nil {
+		t.Fatalf("declaring v: %s", err)
+	}
+	inner := NewEnclosedEnvironment(outer)
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{outer.Declare("c", &Integer{Value: 2}, false), "cannot redeclare constant c"},
+		{outer.Declare("v", &Integer{Value: 2}, true), "cannot redeclare v as a constant"},
+		{inner.Assign("c", &Integer{Value: 2}), "cannot assign to constant c"},
+		{inner.Assign("missing", &Integer{Value: 2}), "identifier not found missing"},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || tt.err.Error() != tt.expected {
+			t.Errorf("expected error %q, got %v", tt.expected, tt.err)
+		}
+	}
+	if err := outer.Declare("v", &Integer{Value: 3}, false); err != nil {
+		t.Errorf("redeclaring a variable: %s", err)
+	}
+	if err := inner.Assign("v", &Integer{Value: 4}); err != nil {
+		t.Errorf("assigning v: %s", err)
+	}
+	if v, _ := outer.Get("v"); v.Inspect() != "4" {
+		t.Errorf("expected v to be assigned in the outer environment, got %s", v.Inspect())
+	}
+	// a constant of an outer scope may be shadowed
+	if err := inner.Declare("c", &Integer{Value: 5}, false); err != nil {
+		t.Errorf("shadowing c: %s", err)
+	}
+	if !outer.IsConst("c") || inner.IsConst("c") || inner.IsConst("v") {
+		t.Errorf("wrong IsConst results")
+	}
+}
+
+func TestGetAt(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(NewEnclosedEnvironment(outer))
+	if v, ok := inner.GetAt(2, 0, "a"); !ok || v.Inspect() != "1" {
+		t.Errorf("expected a two levels out, got %v", v)
+	}
+	if _, ok := inner.GetAt(1, 0, "a"); ok {
+		t.Errorf("expected no a one level out")
+	}
+	if _, ok := inner.GetAt(5, 0, "a"); ok {
+		t.Errorf("expected no a past the outermost environment")
+	}
+	outer.Set("b", &Integer{Value: 2})
+	outer.Set("a", &Integer{Value: 3})
+	if v, ok := inner.GetAt(2, 1, "b"); !ok || v.Inspect() != "2" {
+		t.Errorf("expected b in slot 1, got %v", v)
+	}
+	// a slot that holds another name falls back to the lookup by name
+	if v, ok := inner.GetAt(2, 1, "a"); !ok || v.Inspect() != "3" {
+		t.Errorf("expected a despite the wrong slot, got %v", v)
+	}
+}
+
+func TestNamesAndOuter(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", &Integer{Value: 3})
+	if got := fmt.Sprint(outer.Names()); got != "[a b]" {
+		t.Errorf("expected [a b], got %s", got)
+	}
+	if got := fmt.Sprint(inner.Names()); got != "[c]" {
+		t.Errorf("expected only the inner name, got %s", got)
+	}
+	if inner.Outer() != outer || outer.Outer() != nil {
+		t.Errorf("wrong enclosing environments")
+	}
+}
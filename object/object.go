@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/code"
 )
 
 type ObjectType string
@@ -22,6 +23,23 @@ const (
 	BUILTIN_OBJ  = "BUILTIN"
 	ARRAY_OBJ    = "ARRAY"
 	HASH_OBJ     = "HASH"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+
+	TASK_OBJ     = "TASK"
+	CHANNEL_OBJ  = "CHANNEL"
+	MODULE_OBJ   = "MODULE"
+	STRUCT_OBJ   = "STRUCT"
+	ENUM_OBJ     = "ENUM"
+	INSTANCE_OBJ = "INSTANCE"
+)
+
+// True, False and Null are shared by every backend, so that truthiness and
+// equality can be decided by pointer comparison.
+var (
+	True  = &Boolean{Value: true}
+	False = &Boolean{Value: false}
+	Null  = &NULL{}
 )
 
 type Object interface {
@@ -85,6 +103,8 @@ func (e *Error) Inspect() string {
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.ExpressionNode //evaluated at each call that leaves the parameter out
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -95,16 +115,10 @@ func (f *Function) Type() ObjectType {
 
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, param := range f.Parameters {
-		params = append(params, param.String())
-	}
-	out.WriteString("func")
-	out.WriteString("(")
-	out.WriteString(strings.Join(params, ","))
-	out.WriteString("){ \n")
+	out.WriteString("fn(")
+	out.WriteString(ast.ParameterList(f.Parameters, f.Defaults, f.Rest))
+	out.WriteString(") ")
 	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
 	return out.String()
 }
 
@@ -197,3 +211,39 @@ func (h *Hash) Inspect() string {
 type Hashable interface {
 	HashKey() HashKey
 }
+
+// CompiledFunction is a function lowered to bytecode by the compiler.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure pairs a CompiledFunction with the free variables it captured when
+// it was created. Every function value in the vm is a Closure, so it has the
+// type of a function in the evaluator.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Module is an imported file. Exports holds the bindings it declared with
+// export, as they were once the file finished evaluating.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(" + m.Name + ")" }
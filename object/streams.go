@@ -0,0 +1,167 @@
+package object
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Streams are the standard streams a script sees through print, println,
+// printf, eprint, input and read_line. A nil field falls back to the
+// matching process stream, looked up at call time.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	in     sync.Mutex    // serializes reads and writes, concurrent tasks may
+	out    sync.Mutex    // share one set of streams
+	reader *bufio.Reader // buffers Stdin across calls so no input is lost
+	source io.Reader     // the Stdin reader was built for
+}
+
+// processStreams backs the entries of Builtins.
+var processStreams = &Streams{}
+
+// Builtins returns the I/O builtins bound to s, keyed by name.
+func (s *Streams) Builtins() map[string]*Builtin {
+	return map[string]*Builtin{
+		"print":     {Fn: s.print},
+		"eprint":    {Fn: s.eprint},
+		"println":   {Fn: s.println},
+		"printf":    {Fn: s.printf},
+		"input":     {Fn: s.input},
+		"read_line": {Fn: s.readLine},
+	}
+}
+
+func (s *Streams) stdout() io.Writer {
+	if s.Stdout != nil {
+		return s.Stdout
+	}
+	return os.Stdout
+}
+
+func (s *Streams) stderr() io.Writer {
+	if s.Stderr != nil {
+		return s.Stderr
+	}
+	return os.Stderr
+}
+
+func (s *Streams) stdin() *bufio.Reader {
+	var in io.Reader = os.Stdin
+	if s.Stdin != nil {
+		in = s.Stdin
+	}
+	if s.reader == nil || s.source != in {
+		if br, ok := in.(*bufio.Reader); ok {
+			s.reader = br
+		} else {
+			s.reader = bufio.NewReader(in)
+		}
+		s.source = in
+	}
+	return s.reader
+}
+
+// print writes each argument on a line of its own.
+func (s *Streams) print(args ...Object) Object {
+	s.out.Lock()
+	defer s.out.Unlock()
+	return printTo(s.stdout(), args)
+}
+
+func (s *Streams) eprint(args ...Object) Object {
+	s.out.Lock()
+	defer s.out.Unlock()
+	return printTo(s.stderr(), args)
+}
+
+// println writes its arguments on one line, separated by spaces.
+func (s *Streams) println(args ...Object) Object {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.Inspect()
+	}
+	s.out.Lock()
+	defer s.out.Unlock()
+	fmt.Fprintln(s.stdout(), strings.Join(values, " "))
+	return Null
+}
+
+// printf formats like fmt.Printf. Integers, strings and booleans are passed
+// as their Go values so verbs such as %d and %q work, anything else as its
+// Inspect string.
+func (s *Streams) printf(args ...Object) Object {
+	if len(args) < 1 {
+		return newError("Wrong Number of args, want at least 1, got %d", len(args))
+	}
+	format, ok := args[0].(*String)
+	if !ok {
+		return newError("argument to 'printf' must be STRING, got %s", args[0].Type())
+	}
+	values := make([]any, len(args)-1)
+	for i, arg := range args[1:] {
+		switch arg := arg.(type) {
+		case *Integer:
+			values[i] = arg.Value
+		case *String:
+			values[i] = arg.Value
+		case *Boolean:
+			values[i] = arg.Value
+		default:
+			values[i] = arg.Inspect()
+		}
+	}
+	s.out.Lock()
+	defer s.out.Unlock()
+	fmt.Fprintf(s.stdout(), format.Value, values...)
+	return Null
+}
+
+// input writes an optional prompt and reads a line.
+func (s *Streams) input(args ...Object) Object {
+	if len(args) > 1 {
+		return newError("Wrong Number of args, want 0 or 1, got %d", len(args))
+	}
+	if len(args) == 1 {
+		prompt, ok := args[0].(*String)
+		if !ok {
+			return newError("argument to 'input' must be STRING, got %s", args[0].Type())
+		}
+		s.out.Lock()
+		io.WriteString(s.stdout(), prompt.Value)
+		s.out.Unlock()
+	}
+	return s.readLine()
+}
+
+// readLine returns the next line of input without its line ending, or null
+// once the input is exhausted.
+func (s *Streams) readLine(args ...Object) Object {
+	if len(args) != 0 {
+		return newError("Wrong Number of args, want 0, got %d", len(args))
+	}
+	s.in.Lock()
+	line, err := s.stdin().ReadString('\n')
+	s.in.Unlock()
+	if err == io.EOF && line == "" {
+		return Null
+	}
+	if err != nil && err != io.EOF {
+		return newError("read_line: %s", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return &String{Value: strings.TrimSuffix(line, "\r")}
+}
+
+func printTo(out io.Writer, args []Object) Object {
+	for _, arg := range args {
+		fmt.Fprintln(out, arg.Inspect())
+	}
+	return Null
+}
@@ -0,0 +1,60 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	streams := &Streams{Stdin: strings.NewReader("first\r\nsecond\nlast"), Stdout: &stdout, Stderr: &stderr}
+	builtins := streams.Builtins()
+	call := func(name string, args ...Object) Object {
+		return builtins[name].Fn(args...)
+	}
+	call("print", &Integer{Value: 1}, &String{Value: "a"})
+	call("println", &Integer{Value: 1}, &String{Value: "a"}, True)
+	call("printf", &String{Value: "%d %q %v %s\n"}, &Integer{Value: 2}, &String{Value: "b"}, False,
+		&Array{Elements: []Object{&Integer{Value: 3}}})
+	call("eprint", &String{Value: "oops"})
+
+	expected := "1\na\n1 a true\n2 \"b\" false [3]\n"
+	if stdout.String() != expected {
+		t.Errorf("wrong stdout %q, want %q", stdout.String(), expected)
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("wrong stderr %q", stderr.String())
+	}
+
+	stdout.Reset()
+	lines := []Object{call("input", &String{Value: "? "}), call("read_line"), call("read_line"), call("read_line")}
+	for i, want := range []string{"first", "second", "last", "null"} {
+		if lines[i].Inspect() != want {
+			t.Errorf("line %d: expected %q, got %q", i, want, lines[i].Inspect())
+		}
+	}
+	if stdout.String() != "? " {
+		t.Errorf("prompt not written, stdout %q", stdout.String())
+	}
+}
+
+func TestStreamsErrors(t *testing.T) {
+	builtins := (&Streams{}).Builtins()
+	tests := []struct {
+		name     string
+		args     []Object
+		expected string
+	}{
+		{"printf", nil, "Wrong Number of args, want at least 1, got 0"},
+		{"printf", []Object{&Integer{Value: 1}}, "argument to 'printf' must be STRING, got INTEGER"},
+		{"input", []Object{&Integer{Value: 1}}, "argument to 'input' must be STRING, got INTEGER"},
+		{"read_line", []Object{Null}, "Wrong Number of args, want 0, got 1"},
+	}
+	for _, tt := range tests {
+		errObj, ok := builtins[tt.name].Fn(tt.args...).(*Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.expected, errObj)
+		}
+	}
+}
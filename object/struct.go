@@ -0,0 +1,126 @@
+package object
+
+import (
+	"strings"
+	"sync"
+)
+
+// Struct is the type declared by `struct Name { fields }`. Calling it with
+// one value per field constructs an Instance. The variants of an enum are
+// structs too, with Enum set.
+type Struct struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function
+	Enum    *Enum //nil for a struct declaration
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	if s.Enum != nil {
+		return s.Enum.Name + "." + s.Name
+	}
+	return "struct " + s.Name
+}
+
+// FieldIndex returns the position of the field called name.
+func (s *Struct) FieldIndex(name string) (int, bool) {
+	for i, field := range s.Fields {
+		if field == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Instance is a value of a struct type. Its Type is INSTANCE_OBJ for every
+// struct, so that no declaration can pass for a built-in type; TypeName
+// tells them apart.
+// Fields may be assigned, possibly from several tasks at once, so they are
+// only reached through Get and Set.
+type Instance struct {
+	Struct *Struct
+
+	mu     sync.RWMutex
+	values []Object //in the order of Struct.Fields
+}
+
+// NewInstance returns an instance of s with one value per field.
+func NewInstance(s *Struct, values []Object) *Instance {
+	return &Instance{Struct: s, values: values}
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+
+// TypeName is the name of the struct, or of the enum for a variant.
+func (i *Instance) TypeName() string {
+	if i.Struct.Enum != nil {
+		return i.Struct.Enum.Name
+	}
+	return i.Struct.Name
+}
+func (i *Instance) Inspect() string {
+	if i.Struct.Enum != nil {
+		if i.Struct.Fields == nil {
+			return i.Struct.Name
+		}
+		values := []string{}
+		for _, value := range i.Values() {
+			values = append(values, value.Inspect())
+		}
+		return i.Struct.Name + "(" + strings.Join(values, ",") + ")"
+	}
+	fields := make([]string, len(i.Struct.Fields))
+	for idx, value := range i.Values() {
+		fields[idx] = i.Struct.Fields[idx] + ":" + value.Inspect()
+	}
+	return i.Struct.Name + "{" + strings.Join(fields, ",") + "}"
+}
+
+func (i *Instance) Get(name string) (Object, bool) {
+	idx, ok := i.Struct.FieldIndex(name)
+	if !ok {
+		return nil, false
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.values[idx], true
+}
+
+// Set assigns the field called name, reporting false if there is none.
+func (i *Instance) Set(name string, value Object) bool {
+	idx, ok := i.Struct.FieldIndex(name)
+	if !ok {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.values[idx] = value
+	return true
+}
+
+// Values returns a copy of the field values, in declaration order.
+func (i *Instance) Values() []Object {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Object(nil), i.values...)
+}
+
+// Enum is the type declared by `enum Name { variants }`. Each variant is
+// either a constructor, a *Struct, or a value, an *Instance without fields.
+type Enum struct {
+	Name     string
+	Variants map[string]Object
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string  { return "enum " + e.Name }
+
+// TypeName is what type() reports for obj: the name of its struct or enum
+// for an instance, its Type otherwise.
+func TypeName(obj Object) string {
+	if instance, ok := obj.(*Instance); ok {
+		return instance.TypeName()
+	}
+	return string(obj.Type())
+}
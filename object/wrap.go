@@ -0,0 +1,78 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// WrapFunc turns an arbitrary Go function into a builtin. Arguments are
+// converted with ToGo into the function's parameter types and the result
+// with FromGo. A function may return nothing, one value, an error, or a
+// value and an error; a non-nil error becomes an *Error carrying its
+// message. Parameters typed Object receive the interpreter values as is.
+func WrapFunc(name string, fn any) (*Builtin, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("builtin %s: expected a function, got %T", name, fn)
+	}
+	ft := fv.Type()
+	returnsErr := false
+	switch ft.NumOut() {
+	case 0:
+	case 1:
+		returnsErr = ft.Out(0) == errorType
+	case 2:
+		if ft.Out(1) != errorType {
+			return nil, fmt.Errorf("builtin %s: second result must be error, got %s", name, ft.Out(1))
+		}
+		returnsErr = true
+	default:
+		return nil, fmt.Errorf("builtin %s: too many results in %s", name, ft)
+	}
+	fixed := ft.NumIn()
+	if ft.IsVariadic() {
+		fixed--
+	}
+	return &Builtin{Fn: func(args ...Object) (result Object) {
+		if len(args) < fixed || (!ft.IsVariadic() && len(args) != fixed) {
+			return newError("wrong number of arguments to '%s': want=%d, got=%d", name, fixed, len(args))
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var t reflect.Type
+			if i < fixed {
+				t = ft.In(i)
+			} else {
+				t = ft.In(fixed).Elem()
+			}
+			value := reflect.New(t).Elem()
+			if err := toValue(arg, value); err != nil {
+				return newError("argument %d to '%s': %s", i+1, name, err)
+			}
+			in[i] = value
+		}
+		// a panicking host function must not take the interpreter down with it
+		defer func() {
+			if r := recover(); r != nil {
+				result = newError("builtin %s panicked: %v", name, r)
+			}
+		}()
+		out := fv.Call(in)
+		if returnsErr {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return newError("%s", err.Error())
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return Null
+		}
+		obj, err := fromValue(out[0])
+		if err != nil {
+			return newError("result of '%s': %s", name, err)
+		}
+		return obj
+	}}, nil
+}
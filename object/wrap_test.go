@@ -0,0 +1,60 @@
+package object
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWrapFunc(t *testing.T) {
+	tests := []struct {
+		fn       any
+		args     []Object
+		expected string
+	}{
+		{func(a, b int) int { return a + b }, []Object{&Integer{Value: 1}, &Integer{Value: 2}}, "3"},
+		{strings.ToUpper, []Object{&String{Value: "abc"}}, "ABC"},
+		{func() {}, nil, "null"},
+		{func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+			[]Object{&String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"}}, "a-b"},
+		{func(xs []int) map[string]int { return map[string]int{"n": len(xs)} },
+			[]Object{&Array{Elements: []Object{&Integer{Value: 1}}}}, "{n:1}"},
+		{func(obj Object) Object { return obj }, []Object{True}, "true"},
+		{func(n int) (int, error) { return n * 2, nil }, []Object{&Integer{Value: 4}}, "8"},
+		{func(n int) (int, error) { return 0, errors.New("bad input") }, []Object{&Integer{Value: 4}}, "bad input"},
+		{func() error { return nil }, nil, "null"},
+		{func(a, b int) int { return a + b }, []Object{&Integer{Value: 1}},
+			"wrong number of arguments to 'f': want=2, got=1"},
+		{func(s string) string { return s }, []Object{&Integer{Value: 1}},
+			"argument 1 to 'f': cannot convert INTEGER to string"},
+		{func() int { panic("boom") }, nil, "builtin f panicked: boom"},
+	}
+	for _, tt := range tests {
+		builtin, err := WrapFunc("f", tt.fn)
+		if err != nil {
+			t.Fatalf("WrapFunc(%T) returned error %v", tt.fn, err)
+		}
+		result := builtin.Fn(tt.args...)
+		got := result.Inspect()
+		if errObj, ok := result.(*Error); ok {
+			got = errObj.Message
+		}
+		if got != tt.expected {
+			t.Errorf("%T: expected %q, got %q", tt.fn, tt.expected, got)
+		}
+	}
+}
+
+func TestWrapFuncInvalid(t *testing.T) {
+	invalid := []any{
+		42,
+		(func())(nil),
+		func() (int, int) { return 0, 0 },
+		func() (int, int, error) { return 0, 0, nil },
+	}
+	for _, fn := range invalid {
+		if _, err := WrapFunc("f", fn); err == nil {
+			t.Errorf("WrapFunc(%T) expected error", fn)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x: int = 5;`, `let x: int = 5;`},
+		{`const names: [string] = [];`, `const names: [string] = [];`},
+		{`let ages: {string: int} = {};`, `let ages: {string: int} = {};`},
+		{`fn(a: string, b: [int]) -> bool { true }`, `fn(a: string,b: [int]) -> bool {true}`},
+		{`fn(a, b: int = 1, ...rest: [int]) { a }`, `fn(a,b: int = 1,...rest: [int]){a}`},
+		{`let apply: fn(fn(int) -> int, int) -> int = fn(f, x) { f(x) };`, `let apply: fn(fn(int) -> int, int) -> int = fn(f,x){f(x)};`},
+		{`let p: Point = Point(1, 2);`, `let p: Point = Point(1,2);`},
+		{`a - -1`, `(a - (-1))`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParameterTypes(t *testing.T) {
+	p := New(lexer.New(`fn(x, y: int) -> int { y }`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fn.Types) != 2 || fn.Types[0] != nil || fn.Types[1].String() != "int" {
+		t.Errorf("wrong parameter types %v", fn.Types)
+	}
+	if fn.ReturnType == nil || fn.ReturnType.String() != "int" {
+		t.Errorf("wrong return type %v", fn.ReturnType)
+	}
+	p = New(lexer.New(`fn(x, y) { y }`))
+	program = p.ParseProgram()
+	checkParseErrors(t, p)
+	fn = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fn.Types != nil || fn.ReturnType != nil {
+		t.Errorf("expected no annotations, got %v and %v", fn.Types, fn.ReturnType)
+	}
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x: 5 = 5;`, "invalid type 5"},
+		{`let x: [int = 5;`, "expected next token ] , got token ="},
+		{`fn(a: {string}) { a }`, "expected next token : , got token }"},
+		{`fn(a) -> 1 { a }`, "invalid type 1"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
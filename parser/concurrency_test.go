@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestSpawnAndSelectParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn f(1, 2)", "spawn f(1,2)"},
+		{"spawn fn() { x }", "spawn fn(){x}"},
+		{"await(spawn f) + 1", "(await(spawn f) + 1)"},
+		{
+			"select { case v = recv(a) { v } case send(b, 1 + 2) { 0 } default { 1 } }",
+			"select {case v = recv(a) {v}case send(b,(1 + 2)) {0}default {1}}",
+		},
+		{"select {}", "select {}"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestSelectCases(t *testing.T) {
+	p := New(lexer.New("select { case v = recv(a) { v } case recv(b) { 2 } default { 3 } }"))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	sel, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SelectExpression)
+	if !ok {
+		t.Fatalf("expected SelectExpression, got %T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+	if len(sel.Cases) != 3 {
+		t.Fatalf("expected 3 cases, got %d", len(sel.Cases))
+	}
+	if sel.Cases[0].Name == nil || sel.Cases[0].Name.Value != "v" {
+		t.Errorf("expected first case to bind v, got %v", sel.Cases[0].Name)
+	}
+	if sel.Cases[1].Name != nil || sel.Cases[1].Comm == nil {
+		t.Errorf("expected second case to receive without binding")
+	}
+	if sel.Cases[2].Comm != nil {
+		t.Errorf("expected third case to be the default")
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"select { case f(a) { 1 } }", "invalid select case f(a)"},
+		{"select { case v = send(a, 1) { 1 } }", "invalid select case send(a,1)"},
+		{"select { case recv(a, b) { 1 } }", "invalid select case recv(a,b)"},
+		{"select { case 1 { 1 } }", "select case must be a recv or send call"},
+		{"select { default { 1 } default { 2 } }", "multiple defaults in select"},
+		{"select { 1 }", "expected case or default in select, got INT instead"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
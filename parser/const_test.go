@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestConstParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		constant bool
+	}{
+		{`const x = 5;`, `const x = 5;`, true},
+		{`const [a, b] = xs;`, `const [a,b] = xs;`, true},
+		{`let x = 5;`, `let x = 5;`, false},
+		{`export const x = 5;`, `export const x = 5;`, true},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+		let, ok := program.Statements[0].(*ast.LetStatement)
+		if export, isExport := program.Statements[0].(*ast.ExportStatement); isExport {
+			let, ok = export.Statement, true
+		}
+		if !ok || let.Constant() != tt.constant {
+			t.Errorf("%s: expected constant=%t", tt.input, tt.constant)
+		}
+	}
+}
+
+func TestAssignmentParsing(t *testing.T) {
+	tests := []string{
+		`let x = 1; x = 2`,
+		`const x = 1; let f = fn(x) { x = 2 }`,
+		`const x = 1; let f = fn() { let x = 0; x = 2 }`,
+		`const x = 1; match 1 { x => { x = 2 } }`,
+		`let x = 1; let x = 2;`,
+		`const x = 1; let f = fn() { const x = 2; x }`,
+		`const x = 1; x`,
+	}
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		checkParseErrors(t, p)
+	}
+}
+
+func TestConstErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const x = 1; x = 2`, "cannot assign to constant x"},
+		{`const x = 1; let f = fn() { x = 2 }`, "cannot assign to constant x"},
+		{`const x = 1; if (true) { x = 2 }`, "cannot assign to constant x"},
+		{`const x = 1; let x = 2;`, "cannot redeclare constant x"},
+		{`const x = 1; const x = 2;`, "cannot redeclare constant x"},
+		{`let x = 1; const x = 2;`, "cannot redeclare x as a constant"},
+		{`const [a, b] = xs; b = 1`, "cannot assign to constant b"},
+		{`const P = 1; struct P { x }`, "cannot redeclare constant P"},
+		{`let f = fn() { const y = 1; y = 2 }`, "cannot assign to constant y"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+// An infix parse function may be handed the nil a failed prefix left.
+func TestAssignToNothing(t *testing.T) {
+	p := New(lexer.New("= 1"))
+	if exp := p.parseAssignExpression(nil); exp != nil {
+		t.Errorf("expected nothing to assign to, got %s", exp.String())
+	}
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestDestructuringLetParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		names    []string
+	}{
+		{`let [a, b] = pair;`, `let [a,b] = pair;`, []string{"a", "b"}},
+		{`let [a, b = 2, ...rest] = xs;`, `let [a,b = 2,...rest] = xs;`, []string{"a", "b", "rest"}},
+		{`let [_, [x, y]] = xs;`, `let [_,[x,y]] = xs;`, []string{"x", "y"}},
+		{`let {name, age: years} = h;`, `let {"name":name,"age":years} = h;`, []string{"name", "years"}},
+		{`let {name = "anon", "age": years = 0} = h;`, `let {"name":name = anon,"age":years = 0} = h;`, []string{"name", "years"}},
+		{`let {pos: [x, y]} = h;`, `let {"pos":[x,y]} = h;`, []string{"x", "y"}},
+		{`let x = 1;`, `let x = 1;`, []string{"x"}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+		let, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("expected LetStatement, got %T", program.Statements[0])
+		}
+		names := let.Names()
+		if len(names) != len(tt.names) {
+			t.Fatalf("%s: expected names %v, got %d", tt.input, tt.names, len(names))
+		}
+		for i, name := range names {
+			if name.Value != tt.names[i] {
+				t.Errorf("%s: expected name %s, got %s", tt.input, tt.names[i], name.Value)
+			}
+		}
+	}
+}
+
+func TestDestructuringLetErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, ...b, c] = xs;`, "...rest must be the last element of an array pattern"},
+		{`let {1} = h;`, "invalid hash pattern key 1"},
+		{`let [a] == xs;`, "expected next token = , got token =="},
+		{`let [a = ] = xs;`, "no prefix func found for ]"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestMatchParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { 1 => "one", _ => "many" }`, `match x {1 => {one}, _ => {many}}`},
+		{`match x { -1 => a, "s" => b, true => c, }`, `match x {(-1) => {a}, "s" => {b}, true => {c}}`},
+		{`match f(x) { n if n > 0 => { n } }`, `match f(x) {n if (n > 0) => {n}}`},
+		{`match xs { [] => 0, [a, ...rest] => a, [_, ..._] => 1 }`, `match xs {[] => {0}, [a,...rest] => {a}, [_,..._] => {1}}`},
+		{`match h { {"kind": "circle", r} => r, {} => 0 }`, `match h {{"kind":"circle","r":r} => {r}, {} => {0}}`},
+		{`match s { Circle(r) => r, Shape.Rect(w, _) => w, Empty() => 0 }`, `match s {Circle(r) => {r}, (Shape.Rect)(w,_) => {w}, Empty() => {0}}`},
+		{`match s { Shape.Empty => 0, Empty => 1 }`, `match s {(Shape.Empty) => {0}, Empty => {1}}`},
+		{`enum Shape { Circle(r), Rect(w, h), Empty }`, `enum Shape {Circle(r),Rect(w,h),Empty}`},
+		{`enum Unit { A, }`, `enum Unit {A}`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestMatchArms(t *testing.T) {
+	p := New(lexer.New(`match v { [a, b] if a > b => a, {x} => x, _ => 0 }`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("expected MatchExpression, got %T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+	if len(exp.Arms) != 3 {
+		t.Fatalf("expected 3 arms, got %d", len(exp.Arms))
+	}
+	if _, ok := exp.Arms[0].Pattern.(*ast.ArrayPattern); !ok || exp.Arms[0].Guard == nil {
+		t.Errorf("wrong first arm %s", exp.Arms[0].String())
+	}
+	hash, ok := exp.Arms[1].Pattern.(*ast.HashPattern)
+	if !ok || hash.Pairs[0].Key.Value != "x" {
+		t.Errorf("wrong second arm %s", exp.Arms[1].String())
+	}
+	if _, ok := exp.Arms[2].Pattern.(*ast.WildcardPattern); !ok {
+		t.Errorf("wrong third arm %s", exp.Arms[2].String())
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { 1 + 2 => 3 }`, "expected next token => , got token +"},
+		{`match x { fn() {} => 3 }`, "invalid pattern fn"},
+		{`match x { [...rest, a] => a }`, "...rest must be the last element of an array pattern"},
+		{`match x { [...1] => a }`, "expected a name after ..., got 1"},
+		{`match x { {1: a} => a }`, "invalid hash pattern key 1"},
+		{`match x { {"a"} => a }`, "expected next token : , got token }"},
+		{`match x { 1 }`, "expected next token => , got token }"},
+		{`enum E { A, A }`, "duplicate variant A in enum E"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestImportExportParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math_utils"`, `import "lib/math_utils" as math_utils;`},
+		{`import "lib/math.mk" as m;`, `import "lib/math.mk" as m;`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`m.f(1)`, `(m.f)(1)`},
+		{`m.xs[0]`, `((m.xs)[0])`},
+		{`-m.x * 2`, `((-(m.x)) * 2)`},
+		{`a.b.c`, `((a.b).c)`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestImportName(t *testing.T) {
+	p := New(lexer.New(`import "a/b/shapes.mk";`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("expected ImportStatement, got %T", program.Statements[0])
+	}
+	if stmt.Path.Value != "a/b/shapes.mk" || stmt.Name.Value != "shapes" {
+		t.Errorf("wrong import path %q or name %q", stmt.Path.Value, stmt.Name.Value)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn() { import "x" }`, "import is only allowed at the top level"},
+		{`if (true) { export let x = 1; }`, "export is only allowed at the top level"},
+		{`import "lib/v2"`, `cannot name the module imported from "lib/v2", use import ... as name`},
+		{`import x`, "expected next token STRING , got token IDENT"},
+		{`export 1`, "expected next token LET , got token INT"},
+		{`m.1`, "expected next token IDENT , got token INT"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
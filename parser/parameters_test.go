@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(x, y = 10) { x + y }`, `fn(x,y = 10){(x + y)}`},
+		{`fn(first, ...others) { others }`, `fn(first,...others){others}`},
+		{`fn(...all) { all }`, `fn(...all){all}`},
+		{`fn(a = 1, b = a * 2) { b }`, `fn(a = 1,b = (a * 2)){b}`},
+		{`f(...arr)`, `f(...arr)`},
+		{`f(1, ...xs, 2)`, `f(1,...xs,2)`},
+		{`f(y: 2, x: 1 + 1)`, `f(y: 2,x: (1 + 1))`},
+		{`f(1, y: 2)`, `f(1,y: 2)`},
+		{`f({"a": 1})`, `f({a:1})`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestParameterDefaults(t *testing.T) {
+	p := New(lexer.New(`fn(x, y = 10, ...rest) { x }`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fn.Parameters) != 2 || len(fn.Defaults) != 2 {
+		t.Fatalf("expected 2 parameters and defaults, got %d and %d", len(fn.Parameters), len(fn.Defaults))
+	}
+	if fn.Defaults[0] != nil || fn.Defaults[1].String() != "10" {
+		t.Errorf("wrong defaults %v", fn.Defaults)
+	}
+	if fn.Rest == nil || fn.Rest.Value != "rest" {
+		t.Errorf("wrong rest parameter %v", fn.Rest)
+	}
+}
+
+func TestParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(x = 1, y) { x }`, "parameter y without a default follows one with a default"},
+		{`fn(...xs, y) { y }`, "...xs must be the last parameter"},
+		{`fn(x, x) { x }`, "duplicate parameter x"},
+		{`fn(1) { 1 }`, "invalid parameter 1"},
+		{`f(x: 1, 2)`, "positional argument follows keyword argument"},
+		{`f(x: 1, ...xs)`, "positional argument follows keyword argument"},
+		{`f(x: 1, x: 2)`, "duplicate keyword argument x"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
@@ -3,7 +3,9 @@ package parser
 import (
 	"fmt"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/nishokbanand/interpreter/ast"
 	"github.com/nishokbanand/interpreter/lexer"
@@ -11,6 +13,7 @@ import (
 )
 
 var precedences = map[token.TokenType]int{
+	token.ASSIGN:      ASSIGN,
 	token.EQ:          EQUALS,
 	token.NOT_EQ:      EQUALS,
 	token.LESSTHAN:    LESSGREATER,
@@ -21,11 +24,13 @@ var precedences = map[token.TokenType]int{
 	token.ASTERISK:    PRODUCT,
 	token.LPAREN:      CALL,
 	token.LBRACKET:    INDEX,
+	token.DOT:         INDEX,
 }
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN
 	EQUALS
 	LESSGREATER
 	SUM
@@ -43,10 +48,13 @@ type (
 type Parser struct {
 	l         *lexer.Lexer
 	errros    []string
+	errTokens []token.Token //the token each error was reported at
 	currToken token.Token
 	peekToken token.Token
 	prefixfns map[token.TokenType]PrefixFns
 	infixfns  map[token.TokenType]InfixFns
+	depth     int               //nesting of block statements, 0 at the top level
+	scopes    []map[string]bool //names declared by each enclosing function, true for constants
 }
 
 func (p *Parser) registerPrefixFns(tokType token.TokenType, preFn PrefixFns) {
@@ -57,7 +65,7 @@ func (p *Parser) registerInfixFns(tokType token.TokenType, infFn InfixFns) {
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errros: []string{}}
+	p := &Parser{l: l, errros: []string{}, scopes: []map[string]bool{{}}}
 	p.nextToken()
 	p.nextToken()
 	p.prefixfns = make(map[token.TokenType]PrefixFns)
@@ -73,6 +81,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefixFns(token.STRING, p.parseString)
 	p.registerPrefixFns(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefixFns(token.LBRACES, p.parseHashLiteral)
+	p.registerPrefixFns(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefixFns(token.SELECT, p.parseSelectExpression)
+	p.registerPrefixFns(token.MATCH, p.parseMatchExpression)
 	//infix
 	p.infixfns = make(map[token.TokenType]InfixFns)
 	p.registerInfixFns(token.SUM, p.parseInfixExpression)
@@ -85,6 +96,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixFns(token.GREATERTHAN, p.parseInfixExpression)
 	p.registerInfixFns(token.LPAREN, p.parseCallExpression)
 	p.registerInfixFns(token.LBRACKET, p.parseArrayIndexExpression)
+	p.registerInfixFns(token.DOT, p.parseMemberExpression)
+	p.registerInfixFns(token.ASSIGN, p.parseAssignExpression)
 	return p
 }
 
@@ -92,9 +105,24 @@ func (p *Parser) Errors() []string {
 	return p.errros
 }
 
+// ErrorTokens returns, for each of Errors, the token the parser was at
+// when it found the error.
+func (p *Parser) ErrorTokens() []token.Token {
+	return p.errTokens
+}
+
+func (p *Parser) addError(msg string) {
+	p.addErrorAt(p.currToken, msg)
+}
+
+func (p *Parser) addErrorAt(tok token.Token, msg string) {
+	p.errros = append(p.errros, msg)
+	p.errTokens = append(p.errTokens, tok)
+}
+
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token %v , got token %v", t, p.peekToken.Type)
-	p.errros = append(p.errros, msg)
+	p.addErrorAt(p.peekToken, msg)
 }
 
 func (p *Parser) nextToken() {
@@ -117,10 +145,18 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.StatmentNode {
 	switch p.currToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -130,12 +166,27 @@ func (p *Parser) parseLetStatement() ast.StatmentNode {
 	stmt := &ast.LetStatement{
 		Token: p.currToken,
 	}
-	if !p.expectPeek(token.IDENT) {
-		return nil
-	}
-	stmt.Name = &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
+	if p.peekToken.Type == token.LBRACKET || p.peekToken.Type == token.LBRACES {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
+		if p.peekToken.Type == token.COLON {
+			p.nextToken()
+			p.nextToken()
+			if stmt.Type = p.parseType(); stmt.Type == nil {
+				return nil
+			}
+		}
 	}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -145,6 +196,9 @@ func (p *Parser) parseLetStatement() ast.StatmentNode {
 	if p.peekToken.Type == token.SEMICOLON {
 		p.nextToken()
 	}
+	for _, name := range stmt.Names() {
+		p.declare(name.Value, stmt.Constant())
+	}
 	return stmt
 }
 
@@ -184,11 +238,12 @@ func (p *Parser) parseExpression(precedent int) ast.ExpressionNode {
 	prefix := p.prefixfns[p.currToken.Type]
 	if prefix == nil {
 		msg := fmt.Sprintf("no prefix func found for %v", p.currToken.Type)
-		p.errros = append(p.errros, msg)
+		p.addError(msg)
 		return nil
 	}
 	leftExp := prefix()
-	for p.peekToken.Type != token.SEMICOLON && precedent < p.peekPrecedence() {
+	// a failed prefix leaves nothing for an infix to apply to
+	for leftExp != nil && p.peekToken.Type != token.SEMICOLON && precedent < p.peekPrecedence() {
 		infix := p.infixfns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -210,7 +265,7 @@ func (p *Parser) parseIntergerExpression() ast.ExpressionNode {
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "cannot convert %s to integer", p.currToken.Literal)
-		p.errros = append(p.errros, err.Error())
+		p.addError(err.Error())
 	}
 	stmt.Value = value
 	return stmt
@@ -219,7 +274,9 @@ func (p *Parser) parseIntergerExpression() ast.ExpressionNode {
 func (p *Parser) parsePrefixExpression() ast.ExpressionNode {
 	stmt := &ast.PrefixExpression{Token: p.currToken, Operator: p.currToken.Literal}
 	p.nextToken()
-	stmt.Right = p.parseExpression(PREFIX)
+	if stmt.Right = p.parseExpression(PREFIX); stmt.Right == nil {
+		return nil
+	}
 	return stmt
 }
 
@@ -227,7 +284,9 @@ func (p *Parser) parseInfixExpression(left ast.ExpressionNode) ast.ExpressionNod
 	stmt := &ast.InfixExpression{Token: p.currToken, Operator: p.currToken.Literal, Left: left}
 	precedence := p.currPrecendence()
 	p.nextToken()
-	stmt.Right = p.parseExpression(precedence)
+	if stmt.Right = p.parseExpression(precedence); stmt.Right == nil {
+		return nil
+	}
 	return stmt
 }
 
@@ -284,6 +343,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		Token: p.currToken,
 	}
 	block.Statements = []ast.StatmentNode{}
+	p.depth++
+	defer func() { p.depth-- }()
 	p.nextToken()
 	for p.currToken.Type != token.RBRACES && p.currToken.Type != token.EOF {
 		stmt := p.parseStatement()
@@ -292,6 +353,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.End = p.currToken
 	return block
 }
 
@@ -302,14 +364,155 @@ func (p *Parser) parseFunctionExpression() ast.ExpressionNode {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	fnLiteral.Parameters = p.parseFunctionParameters()
+	if !p.parseParameters(fnLiteral) {
+		return nil
+	}
 	if !p.expectPeek(token.LBRACES) {
 		return nil
 	}
+	p.pushScope(fnLiteral.Parameters, fnLiteral.Rest)
 	fnLiteral.Body = p.parseBlockStatement()
+	p.popScope()
 	return fnLiteral
 }
 
+// parseParameters parses the parameters of fn: names, each of which may
+// have a type annotation and, once an earlier one has, a default, then an
+// optional ...rest, then the return type if there is one.
+func (p *Parser) parseParameters(fn *ast.FunctionLiteral) bool {
+	fn.Parameters = []*ast.Identifier{}
+	seen := map[string]bool{}
+	hasDefaults, annotated := false, false
+	for p.peekToken.Type != token.RPAREN {
+		p.nextToken()
+		rest := p.currToken.Type == token.ELLIPSIS
+		if rest {
+			p.nextToken()
+		}
+		if p.currToken.Type != token.IDENT {
+			p.addError(fmt.Sprintf("invalid parameter %s", p.currToken.Literal))
+			return false
+		}
+		param := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[param.Value] {
+			p.addError(fmt.Sprintf("duplicate parameter %s", param.Value))
+			return false
+		}
+		seen[param.Value] = true
+		var annotation ast.TypeNode
+		if p.peekToken.Type == token.COLON {
+			p.nextToken()
+			p.nextToken()
+			if annotation = p.parseType(); annotation == nil {
+				return false
+			}
+		}
+		if rest {
+			fn.RestType = annotation
+			if p.peekToken.Type != token.RPAREN {
+				p.addError(fmt.Sprintf("...%s must be the last parameter", param.Value))
+				return false
+			}
+			fn.Rest = param
+			break
+		}
+		var value ast.ExpressionNode
+		if p.peekToken.Type == token.ASSIGN {
+			p.nextToken()
+			p.nextToken()
+			if value = p.parseExpression(ASSIGN); value == nil {
+				return false
+			}
+			hasDefaults = true
+		} else if hasDefaults {
+			p.addError(fmt.Sprintf("parameter %s without a default follows one with a default", param.Value))
+			return false
+		}
+		fn.Parameters = append(fn.Parameters, param)
+		fn.Defaults = append(fn.Defaults, value)
+		fn.Types = append(fn.Types, annotation)
+		annotated = annotated || annotation != nil
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !hasDefaults {
+		fn.Defaults = nil
+	}
+	if !annotated {
+		fn.Types = nil
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return false
+	}
+	if p.peekToken.Type == token.RETURNS {
+		p.nextToken()
+		p.nextToken()
+		if fn.ReturnType = p.parseType(); fn.ReturnType == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// parseType parses the type annotation starting at the current token: a
+// name, [element], {key: value} or fn(parameters) -> result.
+func (p *Parser) parseType() ast.TypeNode {
+	switch p.currToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.currToken, Name: p.currToken.Literal}
+	case token.LBRACKET:
+		array := &ast.ArrayType{Token: p.currToken}
+		p.nextToken()
+		if array.Element = p.parseType(); array.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return array
+	case token.LBRACES:
+		hash := &ast.HashType{Token: p.currToken}
+		p.nextToken()
+		if hash.Key = p.parseType(); hash.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		if hash.Value = p.parseType(); hash.Value == nil || !p.expectPeek(token.RBRACES) {
+			return nil
+		}
+		return hash
+	case token.FUNCTION:
+		fn := &ast.FunctionType{Token: p.currToken, Parameters: []ast.TypeNode{}}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for p.peekToken.Type != token.RPAREN {
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			fn.Parameters = append(fn.Parameters, param)
+			if p.peekToken.Type != token.COMMA {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if p.peekToken.Type == token.RETURNS {
+			p.nextToken()
+			p.nextToken()
+			if fn.Return = p.parseType(); fn.Return == nil {
+				return nil
+			}
+		}
+		return fn
+	}
+	p.addError(fmt.Sprintf("invalid type %s", p.currToken.Literal))
+	return nil
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 	if p.peekToken.Type == token.RPAREN {
@@ -339,29 +542,60 @@ func (p *Parser) parseCallExpression(function ast.ExpressionNode) ast.Expression
 		Token:    p.currToken,
 		Function: function,
 	}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments = p.parseCallArguments(); exp.Arguments == nil {
+		return nil
+	}
 	return exp
 }
 
-// func (p *Parser) parseCallArguments() []ast.ExpressionNode {
-// 	args := []ast.ExpressionNode{}
-// 	if p.peekToken.Type == token.RPAREN {
-// 		p.nextToken()
-// 		return args
-// 	}
-// 	p.nextToken()
-// 	args = append(args, p.parseExpression(LOWEST))
-// 	for p.peekToken.Type == token.COMMA {
-// 		p.nextToken()
-// 		p.nextToken()
-// 		args = append(args, p.parseExpression(LOWEST))
-// 	}
-// 	if !p.expectPeek(token.RPAREN) {
-// 		return nil
-// 	}
-// 	return args
-//
-// }
+// parseCallArguments parses the arguments of a call. Unlike the elements of
+// an array they may be spread with ... or passed by name, after all the
+// positional ones.
+func (p *Parser) parseCallArguments() []ast.ExpressionNode {
+	args := []ast.ExpressionNode{}
+	named := map[string]bool{}
+	for p.peekToken.Type != token.RPAREN {
+		p.nextToken()
+		var arg ast.ExpressionNode
+		switch {
+		case p.currToken.Type == token.IDENT && p.peekToken.Type == token.COLON:
+			kw := &ast.KeywordArgument{Token: p.currToken, Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+			if named[kw.Name.Value] {
+				p.addError(fmt.Sprintf("duplicate keyword argument %s", kw.Name.Value))
+				return nil
+			}
+			named[kw.Name.Value] = true
+			p.nextToken()
+			p.nextToken()
+			if kw.Value = p.parseExpression(LOWEST); kw.Value != nil {
+				arg = kw
+			}
+		case len(named) > 0:
+			p.addError("positional argument follows keyword argument")
+			return nil
+		case p.currToken.Type == token.ELLIPSIS:
+			spread := &ast.SpreadExpression{Token: p.currToken}
+			p.nextToken()
+			if spread.Value = p.parseExpression(LOWEST); spread.Value != nil {
+				arg = spread
+			}
+		default:
+			arg = p.parseExpression(LOWEST)
+		}
+		if arg == nil {
+			return nil
+		}
+		args = append(args, arg)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return args
+}
 
 func (p *Parser) parseBoolean() ast.ExpressionNode {
 	return &ast.Boolean{
@@ -381,22 +615,31 @@ func (p *Parser) parseArrayLiteral() ast.ExpressionNode {
 	arr := &ast.ArrayLiteral{
 		Token: p.currToken,
 	}
-	arr.Elements = p.parseExpressionList(token.RBRACKET)
+	if arr.Elements = p.parseExpressionList(token.RBRACKET); arr.Elements == nil {
+		return nil
+	}
 	return arr
 }
 
+// parseExpressionList returns nil if an element fails to parse, and an empty
+// list for [].
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.ExpressionNode {
-	var elements []ast.ExpressionNode
+	elements := []ast.ExpressionNode{}
 	if p.peekToken.Type == end {
 		p.nextToken()
 		return elements
 	}
-	p.nextToken()
-	elements = append(elements, p.parseExpression(LOWEST))
-	for p.peekToken.Type == token.COMMA {
+	for {
 		p.nextToken()
+		element := p.parseExpression(LOWEST)
+		if element == nil {
+			return nil
+		}
+		elements = append(elements, element)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
 		p.nextToken()
-		elements = append(elements, p.parseExpression(LOWEST))
 	}
 	if !p.expectPeek(end) {
 		return nil
@@ -410,7 +653,9 @@ func (p *Parser) parseArrayIndexExpression(left ast.ExpressionNode) ast.Expressi
 		Left:  left,
 	}
 	p.nextToken()
-	ie.Index = p.parseExpression(LOWEST)
+	if ie.Index = p.parseExpression(LOWEST); ie.Index == nil {
+		return nil
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -425,11 +670,14 @@ func (p *Parser) parseHashLiteral() ast.ExpressionNode {
 	for p.peekToken.Type != token.RBRACES {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
-		if !p.expectPeek(token.COLON) {
+		if key == nil || !p.expectPeek(token.COLON) {
 			return nil
 		}
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
 		hash.Pairs[key] = value
 		if p.peekToken.Type != token.RBRACES && !p.expectPeek(token.COMMA) {
 			return nil
@@ -440,3 +688,531 @@ func (p *Parser) parseHashLiteral() ast.ExpressionNode {
 	}
 	return hash
 }
+
+func (p *Parser) parseSpawnExpression() ast.ExpressionNode {
+	exp := &ast.SpawnExpression{
+		Token: p.currToken,
+	}
+	p.nextToken()
+	exp.Call = p.parseExpression(PREFIX)
+	if exp.Call == nil {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseSelectExpression() ast.ExpressionNode {
+	exp := &ast.SelectExpression{
+		Token: p.currToken,
+	}
+	if !p.expectPeek(token.LBRACES) {
+		return nil
+	}
+	hasDefault := false
+	for p.peekToken.Type != token.RBRACES {
+		p.nextToken()
+		sc := &ast.SelectCase{Token: p.currToken}
+		switch p.currToken.Type {
+		case token.CASE:
+			p.nextToken()
+			if p.currToken.Type == token.IDENT && p.peekToken.Type == token.ASSIGN {
+				sc.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+				p.declare(sc.Name.Value, false)
+				p.nextToken()
+				p.nextToken()
+			}
+			sc.Comm = p.parseSelectComm(sc.Name != nil)
+			if sc.Comm == nil {
+				return nil
+			}
+		case token.DEFAULT:
+			if hasDefault {
+				p.addError("multiple defaults in select")
+				return nil
+			}
+			hasDefault = true
+		default:
+			msg := fmt.Sprintf("expected case or default in select, got %s instead", p.currToken.Type)
+			p.addError(msg)
+			return nil
+		}
+		if !p.expectPeek(token.LBRACES) {
+			return nil
+		}
+		sc.Body = p.parseBlockStatement()
+		exp.Cases = append(exp.Cases, sc)
+	}
+	p.nextToken()
+	return exp
+}
+
+// parseSelectComm parses the recv(ch) or send(ch, value) call of a select
+// case.
+func (p *Parser) parseSelectComm(binds bool) *ast.CallExpression {
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	if !ok {
+		p.addError("select case must be a recv or send call")
+		return nil
+	}
+	name, _ := call.Function.(*ast.Identifier)
+	switch {
+	case name != nil && name.Value == "recv" && len(call.Arguments) == 1:
+	case name != nil && name.Value == "send" && len(call.Arguments) == 2 && !binds:
+	default:
+		p.addError(fmt.Sprintf("invalid select case %s", call.String()))
+		return nil
+	}
+	return call
+}
+
+func (p *Parser) parseImportStatement() ast.StatmentNode {
+	stmt := &ast.ImportStatement{Token: p.currToken}
+	if p.depth > 0 {
+		p.addError("import is only allowed at the top level")
+		return nil
+	}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.String{Token: p.currToken, Value: p.currToken.Literal}
+	if p.peekToken.Type == token.AS {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	} else {
+		name := strings.TrimSuffix(path.Base(stmt.Path.Value), path.Ext(stmt.Path.Value))
+		if tok := lexer.New(name).NextToken(); tok.Type != token.IDENT || tok.Literal != name {
+			msg := fmt.Sprintf("cannot name the module imported from %q, use import ... as name", stmt.Path.Value)
+			p.addError(msg)
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+	p.declare(stmt.Name.Value, false)
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseExportStatement() ast.StatmentNode {
+	stmt := &ast.ExportStatement{Token: p.currToken}
+	if p.depth > 0 {
+		p.addError("export is only allowed at the top level")
+		return nil
+	}
+	if p.peekToken.Type == token.CONST {
+		p.nextToken()
+	} else if !p.expectPeek(token.LET) {
+		return nil
+	}
+	let, ok := p.parseLetStatement().(*ast.LetStatement)
+	if !ok {
+		return nil
+	}
+	stmt.Statement = let
+	return stmt
+}
+
+func (p *Parser) parseMemberExpression(left ast.ExpressionNode) ast.ExpressionNode {
+	exp := &ast.MemberExpression{
+		Token:  p.currToken,
+		Object: left,
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	return exp
+}
+
+// parseAssignExpression is right associative, so a.x = b.x = 1 assigns both.
+func (p *Parser) parseAssignExpression(left ast.ExpressionNode) ast.ExpressionNode {
+	if left == nil {
+		return nil
+	}
+	exp := &ast.AssignExpression{Token: p.currToken, Target: left}
+	switch left := left.(type) {
+	case *ast.Identifier:
+		if p.isConst(left.Value) {
+			p.addError(fmt.Sprintf("cannot assign to constant %s", left.Value))
+			return nil
+		}
+	case *ast.MemberExpression:
+	default:
+		// a target with an error inside it may be missing nodes, and the
+		// error has been reported already
+		if len(p.errros) > 0 {
+			return nil
+		}
+		p.addError(fmt.Sprintf("cannot assign to %s", left.String()))
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseStructStatement parses the fields, separated by commas, followed by
+// the methods.
+func (p *Parser) parseStructStatement() ast.StatmentNode {
+	stmt := &ast.StructStatement{Token: p.currToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	p.declare(stmt.Name.Value, false)
+	if !p.expectPeek(token.LBRACES) {
+		return nil
+	}
+	seen := map[string]bool{}
+	for p.peekToken.Type == token.IDENT {
+		p.nextToken()
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[field.Value] {
+			p.addError(fmt.Sprintf("duplicate field %s in struct %s", field.Value, stmt.Name.Value))
+			return nil
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	for p.peekToken.Type == token.FUNCTION {
+		p.nextToken()
+		method := &ast.StructMethod{}
+		fn := &ast.FunctionLiteral{Token: p.currToken}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		method.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[method.Name.Value] {
+			p.addError(fmt.Sprintf("duplicate field %s in struct %s", method.Name.Value, stmt.Name.Value))
+			return nil
+		}
+		seen[method.Name.Value] = true
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.parseParameters(fn) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACES) {
+			return nil
+		}
+		p.pushScope(fn.Parameters, fn.Rest, &ast.Identifier{Value: "self"})
+		fn.Body = p.parseBlockStatement()
+		p.popScope()
+		method.Function = fn
+		stmt.Methods = append(stmt.Methods, method)
+	}
+	if !p.expectPeek(token.RBRACES) {
+		return nil
+	}
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseEnumStatement parses variants separated by commas. A variant with a
+// parenthesized field list is a constructor, one without is a value.
+func (p *Parser) parseEnumStatement() ast.StatmentNode {
+	stmt := &ast.EnumStatement{Token: p.currToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	p.declare(stmt.Name.Value, false)
+	if !p.expectPeek(token.LBRACES) {
+		return nil
+	}
+	seen := map[string]bool{}
+	for p.peekToken.Type == token.IDENT {
+		p.nextToken()
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+		if seen[variant.Name.Value] {
+			p.addError(fmt.Sprintf("duplicate variant %s in enum %s", variant.Name.Value, stmt.Name.Value))
+			return nil
+		}
+		seen[variant.Name.Value] = true
+		p.declare(variant.Name.Value, false)
+		if p.peekToken.Type == token.LPAREN {
+			p.nextToken()
+			variant.Fields = p.parseFunctionParameters()
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACES) {
+		return nil
+	}
+	if p.peekToken.Type == token.SEMICOLON {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseMatchExpression parses arms separated by commas. An arm's result is
+// either a block or a single expression.
+func (p *Parser) parseMatchExpression() ast.ExpressionNode {
+	exp := &ast.MatchExpression{Token: p.currToken}
+	// an arm that fails to parse returns with its scope still pushed
+	scopes := len(p.scopes)
+	defer func() { p.scopes = p.scopes[:scopes] }()
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.LBRACES) {
+		return nil
+	}
+	for p.peekToken.Type != token.RBRACES {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+		p.pushScope(ast.Bindings(arm.Pattern))
+		if p.peekToken.Type == token.IF {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(LOWEST)
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		if p.currToken.Type == token.LBRACES {
+			arm.Body = p.parseBlockStatement()
+		} else {
+			arm.Body = &ast.BlockStatement{
+				Token:      p.currToken,
+				Statements: []ast.StatmentNode{&ast.ExpressionStatement{Token: p.currToken, Expression: p.parseExpression(LOWEST)}},
+			}
+		}
+		exp.Arms = append(exp.Arms, arm)
+		p.popScope()
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACES) {
+		return nil
+	}
+	exp.End = p.currToken
+	return exp
+}
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.currToken.Type {
+	case token.IDENT:
+		if p.currToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.currToken}
+		}
+		if p.peekToken.Type == token.LPAREN || p.peekToken.Type == token.DOT {
+			return p.parseConstructorPattern()
+		}
+		return &ast.BindingPattern{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+	case token.INT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.prefixfns[p.currToken.Type]()}
+	case token.MINUS:
+		if p.peekToken.Type != token.INT {
+			break
+		}
+		return &ast.LiteralPattern{Value: p.parsePrefixExpression()}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACES:
+		return p.parseHashPattern()
+	}
+	p.addError(fmt.Sprintf("invalid pattern %s", p.currToken.Literal))
+	return nil
+}
+
+func (p *Parser) parseConstructorPattern() ast.Pattern {
+	pattern := &ast.ConstructorPattern{Token: p.currToken}
+	pattern.Constructor = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	for p.peekToken.Type == token.DOT {
+		p.nextToken()
+		member := &ast.MemberExpression{Token: p.currToken, Object: pattern.Constructor}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		member.Property = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		pattern.Constructor = member
+	}
+	// Shape.Empty, a variant without fields
+	if _, ok := pattern.Constructor.(*ast.MemberExpression); ok && p.peekToken.Type != token.LPAREN {
+		return pattern
+	}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	pattern.Fields = []ast.Pattern{}
+	if p.peekToken.Type == token.RPAREN {
+		p.nextToken()
+		return pattern
+	}
+	for {
+		p.nextToken()
+		field := p.parsePattern()
+		if field == nil {
+			return nil
+		}
+		pattern.Fields = append(pattern.Fields, field)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return pattern
+}
+
+// parseArrayPattern parses [a, b = 2, ...rest], where ...rest may only come
+// last.
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.currToken}
+	for p.peekToken.Type != token.RBRACKET {
+		p.nextToken()
+		if p.currToken.Type == token.ELLIPSIS {
+			p.nextToken()
+			if p.currToken.Type != token.IDENT {
+				p.addError(fmt.Sprintf("expected a name after ..., got %s", p.currToken.Literal))
+				return nil
+			}
+			pattern.Rest = p.parsePattern()
+			if p.peekToken.Type == token.COMMA {
+				p.addError("...rest must be the last element of an array pattern")
+				return nil
+			}
+			break
+		}
+		element := p.parseDefault(p.parsePattern())
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
+
+// parseHashPattern parses {"key": pattern, name}, where a key may be a
+// string or a name and a bare name binds the field of that name. Either
+// form may be followed by a default.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.currToken}
+	for p.peekToken.Type != token.RBRACES {
+		p.nextToken()
+		if p.currToken.Type != token.IDENT && p.currToken.Type != token.STRING {
+			p.addError(fmt.Sprintf("invalid hash pattern key %s", p.currToken.Literal))
+			return nil
+		}
+		pair := &ast.HashPatternPair{Key: &ast.String{Token: p.currToken, Value: p.currToken.Literal}}
+		if p.peekToken.Type == token.COLON {
+			p.nextToken()
+			p.nextToken()
+			pair.Value = p.parseDefault(p.parsePattern())
+			if pair.Value == nil {
+				return nil
+			}
+		} else if p.currToken.Type == token.IDENT {
+			name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			pair.Value = p.parseDefault(&ast.BindingPattern{Name: name})
+			if pair.Value == nil {
+				return nil
+			}
+		} else {
+			p.peekError(token.COLON)
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+		if p.peekToken.Type != token.COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACES) {
+		return nil
+	}
+	return pattern
+}
+
+// parseDefault wraps pattern, an element of an array or hash pattern, with
+// the default following it, if any.
+func (p *Parser) parseDefault(pattern ast.Pattern) ast.Pattern {
+	if pattern == nil || p.peekToken.Type != token.ASSIGN {
+		return pattern
+	}
+	p.nextToken()
+	p.nextToken()
+	value := p.parseExpression(ASSIGN)
+	if value == nil {
+		return nil
+	}
+	return &ast.DefaultPattern{Pattern: pattern, Default: value}
+}
+
+// pushScope enters the body of a function, or of a match arm, in which
+// names are declared.
+func (p *Parser) pushScope(names []*ast.Identifier, more ...*ast.Identifier) {
+	scope := map[string]bool{}
+	for _, name := range append(names, more...) {
+		if name != nil {
+			scope[name.Value] = false
+		}
+	}
+	p.scopes = append(p.scopes, scope)
+}
+
+func (p *Parser) popScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records a declaration in the innermost scope. Redeclaring a
+// constant, or redeclaring a name as one, is an error, as it is when the
+// program runs.
+func (p *Parser) declare(name string, constant bool) {
+	scope := p.scopes[len(p.scopes)-1]
+	if existing, ok := scope[name]; ok {
+		if existing {
+			p.addError(fmt.Sprintf("cannot redeclare constant %s", name))
+			return
+		}
+		if constant {
+			p.addError(fmt.Sprintf("cannot redeclare %s as a constant", name))
+			return
+		}
+	}
+	scope[name] = constant
+}
+
+// isConst reports whether name refers to a constant declared so far.
+func (p *Parser) isConst(name string) bool {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if constant, ok := p.scopes[i][name]; ok {
+			return constant
+		}
+	}
+	return false
+}
@@ -191,6 +191,50 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 
 }
+func TestErrorTokens(t *testing.T) {
+	p := New(lexer.New("let x = 1;\nlet = 2;\nlet y = );"))
+	p.ParseProgram()
+	errs, toks := p.Errors(), p.ErrorTokens()
+	if len(errs) == 0 || len(errs) != len(toks) {
+		t.Fatalf("expected one token per error, got %d errors and %d tokens", len(errs), len(toks))
+	}
+	if toks[0].Line != 2 || toks[0].Column != 5 {
+		t.Errorf("expected the first error at 2:5, got %d:%d", toks[0].Line, toks[0].Column)
+	}
+	if last := toks[len(toks)-1]; last.Line != 3 {
+		t.Errorf("expected the last error on line 3, got %d:%d", last.Line, last.Column)
+	}
+}
+
+// truncatedSnippet is cut after each of its bytes, as an editor sends it
+// while it is typed: every prefix must parse, with errors, without a panic.
+const truncatedSnippet = `struct P { x, y fn m() { self.x = self.y } };
+enum E { A, B(v) };
+const c = 1;
+let f = fn(a, b = 2, ...r) { let [h, ...t] = r; a = b };
+let v = match E.B(1) { A => 0, E.A => 0, E.B(x) if x > 0 => x, [a, ...r] => a, {k} => k, _ => f(1, b: 2) };
+let ch = channel(); spawn f(1); select { case x = recv(ch) { x } default { 0 } };
+import "m" as m;
+export let w = m.g(...[1, 2]);
+`
+
+func TestTruncatedInput(t *testing.T) {
+	p := New(lexer.New(truncatedSnippet))
+	p.ParseProgram()
+	checkParseErrors(t, p)
+	for i := range truncatedSnippet {
+		p := New(lexer.New(truncatedSnippet[:i]))
+		p.ParseProgram()
+	}
+	for _, input := range []string{`let f = fn(x) = 1`, `match v { 1 =`, `(1 = 2`,
+		`-; = 1`, `!) = 1`, `(-) = 1`, `-] = 1`, `[1, ...] = 1`, `let g: fn(int) -,> int = 1`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected errors", input)
+		}
+	}
+}
 func testBooleanLiteral(t *testing.T, exp ast.ExpressionNode, value bool) bool {
 	bo, ok := exp.(*ast.Boolean)
 	if !ok {
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+)
+
+func TestStructParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y }`, `struct Point {x,y}`},
+		{`struct Empty {}`, `struct Empty {}`},
+		{`struct P { x fn get() { self.x } fn set(v) { self.x = v } }`, `struct P {x fn get(){(self.x)} fn set(v){((self.x) = v)}}`},
+		{`p.x = 1 + 2`, `((p.x) = (1 + 2))`},
+		{`a.x = b.y = 3`, `((a.x) = ((b.y) = 3))`},
+		{`p.x == 1`, `((p.x) == 1)`},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	p := New(lexer.New(`struct Point { x, y fn norm() { self.x * self.x + self.y * self.y } }`))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("expected StructStatement, got %T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Point" || len(stmt.Fields) != 2 || len(stmt.Methods) != 1 {
+		t.Fatalf("wrong struct %s", stmt.String())
+	}
+	if stmt.Methods[0].Name.Value != "norm" || len(stmt.Methods[0].Function.Parameters) != 0 {
+		t.Errorf("wrong method %s", stmt.Methods[0].String())
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct P { x, x }`, "duplicate field x in struct P"},
+		{`struct P { x fn x() { 1 } }`, "duplicate field x in struct P"},
+		{`struct { x }`, "expected next token IDENT , got token {"},
+		{`struct P { x; }`, "expected next token } , got token ;"},
+		{`1 = 2`, "cannot assign to 1"},
+		{`f() = 1`, "cannot assign to f()"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
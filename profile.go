@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/profiler"
+)
+
+// profileCommand runs a script with the profiler attached and reports to
+// report which functions it spent its time in. The script writes to the
+// process streams, as it does when run directly.
+func profileCommand(args []string, out, report io.Writer) error {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	output := fs.String("o", "", "also write a profile for go tool pprof to this file")
+	trace := fs.Bool("trace", false, "log every call, with its arguments and value")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: interpreter profile [-o file.pb.gz] [-trace] file.mk")
+	}
+	path := fs.Arg(0)
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	program, err := parseSource(string(source))
+	if err != nil {
+		return err
+	}
+	res, err := check(program, nil)
+	if err != nil {
+		return err
+	}
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	c.SetModules(evaluate.NewModules(filepath.Dir(path)))
+	c.SetResolution(res)
+	p := profiler.New(program, c)
+	if *trace {
+		p.SetTrace(report)
+	}
+	c.SetHook(p)
+	p.Start()
+	result := c.Eval(program, object.NewEnvironment())
+	p.Stop()
+	// a script that fails is still worth a report
+	if err := p.WriteReport(report); err != nil {
+		return err
+	}
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := p.WriteProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+	if result != nil && result != object.Null {
+		fmt.Fprintln(out, result.Inspect())
+	}
+	return nil
+}
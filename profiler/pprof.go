@@ -0,0 +1,155 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// WriteProfile writes the call paths in the profile.proto format go tool
+// pprof reads, gzipped. Each call path is a sample with three values: the
+// calls made along it, and the time and allocations of their own code.
+// Time is the default, so that pprof draws flame graphs of it.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	strs := &stringTable{index: map[string]int64{"": 0}, list: []string{""}}
+	prof := &buffer{}
+	valueType := func(field int, typ, unit string) {
+		prof.message(field, func(b *buffer) {
+			b.int64(1, strs.id(typ))
+			b.int64(2, strs.id(unit))
+		})
+	}
+	valueType(1, "calls", "count")
+	valueType(1, "time", "nanoseconds")
+	valueType(1, "allocations", "count")
+
+	ids := map[*Function]uint64{}
+	funcs := []*Function{}
+	var walk func(n *node, path []uint64)
+	walk = func(n *node, path []uint64) {
+		id, ok := ids[n.fn]
+		if !ok {
+			funcs = append(funcs, n.fn)
+			id = uint64(len(funcs))
+			ids[n.fn] = id
+		}
+		// locations go from the innermost call out
+		path = append([]uint64{id}, path...)
+		prof.message(2, func(b *buffer) {
+			b.packed(1, path)
+			b.packed(2, []uint64{uint64(n.calls), uint64(n.self), uint64(n.allocs)})
+		})
+		for _, child := range n.order {
+			walk(child, path)
+		}
+	}
+	walk(p.root, nil)
+	// a function and its one location share their ID
+	for i, fn := range funcs {
+		id := uint64(i + 1)
+		prof.message(4, func(b *buffer) {
+			b.uint64(1, id)
+			b.message(4, func(b *buffer) {
+				b.uint64(1, id)
+				b.int64(2, int64(fn.Line))
+			})
+		})
+		prof.message(5, func(b *buffer) {
+			b.uint64(1, id)
+			b.int64(2, strs.id(fn.Name))
+			b.int64(3, strs.id(fn.Name))
+			b.int64(5, int64(fn.Line))
+		})
+	}
+	prof.int64(9, p.started.UnixNano())
+	prof.int64(10, int64(p.duration))
+	valueType(11, "time", "nanoseconds")
+	prof.int64(12, 1)
+	prof.int64(14, strs.id("time"))
+	for _, s := range strs.list {
+		prof.string(6, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(prof.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// stringTable is the string table of a profile, which the other messages
+// refer to by index. The empty string comes first.
+type stringTable struct {
+	index map[string]int64
+	list  []string
+}
+
+func (s *stringTable) id(str string) int64 {
+	if id, ok := s.index[str]; ok {
+		return id
+	}
+	id := int64(len(s.list))
+	s.index[str] = id
+	s.list = append(s.list, str)
+	return id
+}
+
+// buffer encodes a protocol buffer message, one field at a time. Fields
+// holding zero are left out, as they are by default.
+type buffer struct {
+	data []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *buffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *buffer) key(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *buffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *buffer) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *buffer) bytes(field int, data []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// string writes s even when it is empty, as the string table needs.
+func (b *buffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *buffer) message(field int, encode func(b *buffer)) {
+	m := &buffer{}
+	encode(m)
+	b.bytes(field, m.data)
+}
+
+// packed writes a repeated integer field in one run.
+func (b *buffer) packed(field int, xs []uint64) {
+	m := &buffer{}
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.bytes(field, m.data)
+}
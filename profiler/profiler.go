@@ -0,0 +1,206 @@
+// Package profiler measures where an evaluation spends its time. A
+// Profiler is attached to an evaluation as its evaluate.Hook and records
+// every call of a user function or builtin in a tree of call paths, with
+// the number of calls, the time they took and the objects they allocated.
+// From the tree it reports per function, as text, or per call path, as a
+// profile go tool pprof reads. It can also trace every call as it
+// happens.
+//
+// Only the calls made by the evaluation itself are seen: tasks it spawns
+// run without the hook. A function that ends by calling another in tail
+// position is measured until that call, which is counted as one made by
+// its caller.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/resolver"
+)
+
+// Function is a user function or a builtin, as the profile names it.
+type Function struct {
+	Name    string
+	Builtin bool
+	Line    int //where a user function's body starts, 0 for the others
+}
+
+// node is a call path: the calls of one function made from the same chain
+// of callers. Its counts leave out the calls it made itself.
+type node struct {
+	fn       *Function
+	parent   *node
+	children map[*Function]*node
+	order    []*node //children in the order they were first called
+	calls    int64
+	self     time.Duration
+	allocs   int64
+}
+
+func (n *node) child(fn *Function) *node {
+	if c, ok := n.children[fn]; ok {
+		return c
+	}
+	c := &node{fn: fn, parent: n, children: map[*Function]*node{}}
+	n.children[fn] = c
+	n.order = append(n.order, c)
+	return c
+}
+
+// frame is a call in progress.
+type frame struct {
+	node        *node
+	start       time.Time
+	allocs      int64         //allocated when the call began
+	childTime   time.Duration //spent in the calls it made
+	childAllocs int64
+	call        string //how the trace showed the call
+}
+
+// Profiler is an evaluate.Hook that profiles the evaluation running with
+// c. Start it just before the evaluation and stop it just after.
+type Profiler struct {
+	c        *evaluate.Context
+	names    map[*ast.BlockStatement]string
+	funcs    map[interface{}]*Function //by function body or builtin name
+	root     *node
+	stack    []*frame
+	trace    io.Writer
+	now      func() time.Time
+	started  time.Time
+	duration time.Duration
+}
+
+// New returns a profiler for program running with c. The top level of
+// program is profiled as a function called (main), a name pprof keeps as
+// it is and no identifier can take.
+func New(program *ast.Program, c *evaluate.Context) *Profiler {
+	main := &Function{Name: "(main)"}
+	return &Profiler{
+		c:     c,
+		names: ast.FunctionNames(program),
+		funcs: map[interface{}]*Function{},
+		root:  &node{fn: main, children: map[*Function]*node{}},
+		now:   time.Now,
+	}
+}
+
+// SetTrace writes a line to w when each call begins, with its arguments,
+// and when it returns, with its value.
+func (p *Profiler) SetTrace(w io.Writer) { p.trace = w }
+
+// Start begins measuring the top level of the program.
+func (p *Profiler) Start() {
+	p.started = p.now()
+	p.stack = []*frame{{node: p.root, start: p.started, allocs: p.c.Allocs()}}
+}
+
+// Stop ends the measures. Calls the evaluation left in progress, as when
+// it was stopped, end there too.
+func (p *Profiler) Stop() {
+	for len(p.stack) > 1 {
+		p.pop()
+	}
+	if len(p.stack) == 1 {
+		_, p.duration = p.pop()
+	}
+}
+
+// function describes fn, a user function or a builtin, once per function.
+// Methods are builtins too, named after the method.
+func (p *Profiler) function(fn object.Object) *Function {
+	if fn, ok := fn.(*object.Function); ok {
+		if f, ok := p.funcs[fn.Body]; ok {
+			return f
+		}
+		name, ok := p.names[fn.Body]
+		if !ok {
+			name = fmt.Sprintf("fn@%d", fn.Body.Token.Line)
+		}
+		f := &Function{Name: name, Line: fn.Body.Token.Line}
+		p.funcs[fn.Body] = f
+		return f
+	}
+	if fn.Type() != object.BUILTIN_OBJ {
+		return nil
+	}
+	name, ok := p.c.BuiltinName(fn)
+	if !ok {
+		name = "(builtin)"
+	}
+	if f, ok := p.funcs[name]; ok {
+		return f
+	}
+	f := &Function{Name: name, Builtin: true}
+	p.funcs[name] = f
+	return f
+}
+
+func (p *Profiler) Node(node ast.Node, pos resolver.Position, env *object.Environment) error {
+	return nil
+}
+
+func (p *Profiler) Call(fn object.Object, args []object.Object, env *object.Environment) {
+	f := p.function(fn)
+	if f == nil || len(p.stack) == 0 {
+		return
+	}
+	top := p.stack[len(p.stack)-1]
+	fr := &frame{node: top.node.child(f)}
+	if p.trace != nil {
+		inspected := make([]string, len(args))
+		for i, arg := range args {
+			inspected[i] = arg.Inspect()
+		}
+		fr.call = f.Name + "(" + strings.Join(inspected, ", ") + ")"
+		fmt.Fprintf(p.trace, "%s-> %s\n", p.indent(), fr.call)
+	}
+	p.stack = append(p.stack, fr)
+	// measure from here, leaving out the time taken by the trace
+	fr.start, fr.allocs = p.now(), p.c.Allocs()
+}
+
+func (p *Profiler) Return(fn object.Object, result object.Object) {
+	if p.function(fn) == nil || len(p.stack) < 2 {
+		return
+	}
+	fr, _ := p.pop()
+	if p.trace == nil {
+		return
+	}
+	switch result := result.(type) {
+	case nil:
+		fmt.Fprintf(p.trace, "%s<- %s tail call\n", p.indent(), fr.call)
+	case *object.Error:
+		fmt.Fprintf(p.trace, "%s<- %s error: %s\n", p.indent(), fr.call, result.Message)
+	default:
+		fmt.Fprintf(p.trace, "%s<- %s = %s\n", p.indent(), fr.call, result.Inspect())
+	}
+}
+
+// pop ends the innermost call in progress, returning how long it took.
+func (p *Profiler) pop() (*frame, time.Duration) {
+	fr := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	elapsed := p.now().Sub(fr.start)
+	allocs := p.c.Allocs() - fr.allocs
+	fr.node.calls++
+	fr.node.self += elapsed - fr.childTime
+	fr.node.allocs += allocs - fr.childAllocs
+	if len(p.stack) > 0 {
+		parent := p.stack[len(p.stack)-1]
+		parent.childTime += elapsed
+		parent.childAllocs += allocs
+	}
+	return fr, elapsed
+}
+
+func (p *Profiler) indent() string {
+	return strings.Repeat("  ", len(p.stack)-1)
+}
@@ -0,0 +1,218 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+// f recurses twice, and its last call hands over to len in tail position.
+const script = `let f = fn(n) {
+	if (n == 0) { len("ab") } else { 1 + f(n - 1) }
+};
+f(2)`
+
+// profile runs input with a profiler whose clock moves on by a millisecond
+// each time it is read.
+func profile(t *testing.T, input string, trace io.Writer) (*Profiler, *evaluate.Context) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	prof := New(program, c)
+	clock := time.Unix(0, 0)
+	prof.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	if trace != nil {
+		prof.SetTrace(trace)
+	}
+	c.SetHook(prof)
+	prof.Start()
+	c.Eval(program, object.NewEnvironment())
+	prof.Stop()
+	return prof, c
+}
+
+func TestStats(t *testing.T) {
+	prof, c := profile(t, script, nil)
+	expected := []struct {
+		name          string
+		calls         int64
+		time, cumTime time.Duration
+	}{
+		// the outermost call of f takes 7ms, 5 of them in its calls: the
+		// recursive ones are not counted again in its cumulative time
+		{"f", 3, 6 * time.Millisecond, 7 * time.Millisecond},
+		{"(main)", 1, 2 * time.Millisecond, 9 * time.Millisecond},
+		{"len", 1, time.Millisecond, time.Millisecond},
+	}
+	stats := prof.Stats()
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %d functions, got %d", len(expected), len(stats))
+	}
+	allocs := int64(0)
+	for i, tt := range expected {
+		stat := stats[i]
+		if stat.Function.Name != tt.name || stat.Calls != tt.calls || stat.Time != tt.time || stat.CumTime != tt.cumTime {
+			t.Errorf("expected %s: %d calls, %v, %v cumulative, got %s: %d calls, %v, %v cumulative",
+				tt.name, tt.calls, tt.time, tt.cumTime, stat.Function.Name, stat.Calls, stat.Time, stat.CumTime)
+		}
+		if stat.Allocs < 0 || stat.CumAllocs < stat.Allocs {
+			t.Errorf("%s: wrong allocations %d, %d cumulative", tt.name, stat.Allocs, stat.CumAllocs)
+		}
+		allocs += stat.Allocs
+	}
+	if !stats[2].Function.Builtin || stats[0].Function.Builtin || stats[0].Function.Line != 1 {
+		t.Errorf("wrong functions %+v, %+v", stats[0].Function, stats[2].Function)
+	}
+	if stats[1].CumAllocs != c.Allocs() || allocs != c.Allocs() {
+		t.Errorf("expected the %d allocations of the evaluation, got %d cumulative in (main) and %d in all", c.Allocs(), stats[1].CumAllocs, allocs)
+	}
+}
+
+func TestReport(t *testing.T) {
+	prof, _ := profile(t, "let f = fn() { [1, 2] };\nf();\nf()", nil)
+	out := &bytes.Buffer{}
+	if err := prof.WriteReport(out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `5ms in 2 calls, 13 allocations
+     calls         time     cum time     allocs cum allocs  function
+         1          3ms          5ms          3         13  (main)
+         2          2ms          2ms         10         10  f (line 1)
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestTrace(t *testing.T) {
+	out := &bytes.Buffer{}
+	profile(t, script+";\nlen(1)", out)
+	expected := `-> f(2)
+  -> f(1)
+    -> f(0)
+    <- f(0) tail call
+    -> len(ab)
+    <- len(ab) = 2
+  <- f(1) = 3
+<- f(2) = 4
+-> len(1)
+<- len(1) error: argument to 'len' not supported, got INTEGER
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+// fields decodes a protocol buffer message into the values of its fields:
+// a uint64 for a varint and a []byte for the others, all length-delimited.
+func fields(t *testing.T, data []byte) map[int][]interface{} {
+	t.Helper()
+	out := map[int][]interface{}{}
+	for len(data) > 0 {
+		key := varint(t, &data)
+		field := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			out[field] = append(out[field], varint(t, &data))
+		case wireBytes:
+			n := varint(t, &data)
+			out[field] = append(out[field], data[:n])
+			data = data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return out
+}
+
+// varint decodes the varint data starts with and moves past it.
+func varint(t *testing.T, data *[]byte) uint64 {
+	t.Helper()
+	x, shift := uint64(0), uint(0)
+	for {
+		if len(*data) == 0 {
+			t.Fatal("truncated varint")
+		}
+		b := (*data)[0]
+		*data = (*data)[1:]
+		x |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return x
+		}
+		shift += 7
+	}
+}
+
+func TestProfile(t *testing.T) {
+	prof, _ := profile(t, script, nil)
+	out := &bytes.Buffer{}
+	if err := prof.WriteProfile(out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := fields(t, data)
+	strs := []string{}
+	for _, s := range profile[6] {
+		strs = append(strs, string(s.([]byte)))
+	}
+	if strs[0] != "" {
+		t.Errorf("the string table must start with the empty string, got %q", strs[0])
+	}
+	str := func(v interface{}) string { return strs[v.(uint64)] }
+	sampleTypes := []string{}
+	for _, st := range profile[1] {
+		vt := fields(t, st.([]byte))
+		sampleTypes = append(sampleTypes, str(vt[1][0])+"/"+str(vt[2][0]))
+	}
+	if got := strings.Join(sampleTypes, " "); got != "calls/count time/nanoseconds allocations/count" {
+		t.Errorf("wrong sample types %s", got)
+	}
+	if str(profile[14][0]) != "time" || profile[10][0].(uint64) != uint64(9*time.Millisecond) {
+		t.Errorf("wrong default sample type or duration")
+	}
+	names := map[uint64]string{}
+	for _, fn := range profile[5] {
+		f := fields(t, fn.([]byte))
+		names[f[1][0].(uint64)] = str(f[2][0])
+	}
+	if len(names) != 3 || len(profile[4]) != 3 {
+		t.Fatalf("expected three functions and locations, got %v and %d", names, len(profile[4]))
+	}
+	// one sample per call path, the innermost call first
+	samples := []string{}
+	for _, s := range profile[2] {
+		ids := fields(t, s.([]byte))[1][0].([]byte)
+		path := []string{}
+		for len(ids) > 0 {
+			path = append(path, names[varint(t, &ids)])
+		}
+		samples = append(samples, strings.Join(path, "<"))
+	}
+	expected := "(main) f<(main) f<f<(main) f<f<f<(main) len<f<f<(main)"
+	if got := strings.Join(samples, " "); got != expected {
+		t.Errorf("expected samples\n%s\ngot\n%s", expected, got)
+	}
+}
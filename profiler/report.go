@@ -0,0 +1,101 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Stat is what the calls of one function took. The time and allocations
+// are those of its own code; the cumulative ones add those of the calls
+// it made, counting a recursive call once.
+type Stat struct {
+	Function  *Function
+	Calls     int64
+	Time      time.Duration
+	CumTime   time.Duration
+	Allocs    int64
+	CumAllocs int64
+}
+
+// Stats sums the call paths of each function, the one that took the most
+// time of its own first. The profiler must be stopped.
+func (p *Profiler) Stats() []Stat {
+	stats := map[*Function]*Stat{}
+	active := map[*Function]int{}
+	var walk func(n *node) (time.Duration, int64)
+	walk = func(n *node) (time.Duration, int64) {
+		stat, ok := stats[n.fn]
+		if !ok {
+			stat = &Stat{Function: n.fn}
+			stats[n.fn] = stat
+		}
+		active[n.fn]++
+		cumTime, cumAllocs := n.self, n.allocs
+		for _, child := range n.order {
+			t, a := walk(child)
+			cumTime += t
+			cumAllocs += a
+		}
+		active[n.fn]--
+		stat.Calls += n.calls
+		stat.Time += n.self
+		stat.Allocs += n.allocs
+		if active[n.fn] == 0 {
+			stat.CumTime += cumTime
+			stat.CumAllocs += cumAllocs
+		}
+		return cumTime, cumAllocs
+	}
+	walk(p.root)
+	out := make([]Stat, 0, len(stats))
+	for _, stat := range stats {
+		out = append(out, *stat)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Time != out[j].Time {
+			return out[i].Time > out[j].Time
+		}
+		return out[i].Function.Name < out[j].Function.Name
+	})
+	return out
+}
+
+// WriteReport writes the Stats as a table, after a line of totals.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	stats := p.Stats()
+	calls, allocs := int64(0), int64(0)
+	for _, stat := range stats {
+		if stat.Function != p.root.fn {
+			calls += stat.Calls
+		}
+		allocs += stat.Allocs
+	}
+	if _, err := fmt.Fprintf(w, "%s in %d calls, %d allocations\n", round(p.duration), calls, allocs); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%10s %12s %12s %10s %10s  %s\n", "calls", "time", "cum time", "allocs", "cum allocs", "function"); err != nil {
+		return err
+	}
+	for _, stat := range stats {
+		name := stat.Function.Name
+		if stat.Function.Builtin {
+			name += " (builtin)"
+		} else if stat.Function.Line > 0 {
+			name += fmt.Sprintf(" (line %d)", stat.Function.Line)
+		}
+		_, err := fmt.Fprintf(w, "%10d %12s %12s %10d %10d  %s\n",
+			stat.Calls, round(stat.Time), round(stat.CumTime), stat.Allocs, stat.CumAllocs, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// round drops the digits of d below a microsecond, which measure the
+// profiler more than the program.
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Microsecond)
+}
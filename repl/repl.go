@@ -2,25 +2,32 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
+	"context"
 	"io"
+	"strings"
 
+	"github.com/nishokbanand/interpreter/compiler"
 	"github.com/nishokbanand/interpreter/evaluate"
 	"github.com/nishokbanand/interpreter/lexer"
 	"github.com/nishokbanand/interpreter/object"
 	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/vm"
 )
 
+const PROMPT = ">>"
+
+// Start reads lines from in and evaluates them, writing prompts, results and
+// anything the script prints to out. Scripts calling input or read_line read
+// from in as well, picking up where the REPL left off.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	reader := bufio.NewReader(in)
+	streams := &object.Streams{Stdin: reader, Stdout: out, Stderr: out}
 	env := object.NewEnvironment()
 	for {
-		fmt.Printf(">>")
-		scanned := scanner.Scan()
-		if !scanned {
+		input, ok := readLine(reader, out)
+		if !ok {
 			return
 		}
-		input := scanner.Text()
 		lexer := lexer.New(input)
 		parser := parser.New(lexer)
 		program := parser.ParseProgram()
@@ -28,7 +35,9 @@ func Start(in io.Reader, out io.Writer) {
 			printParseErrors(out, parser.Errors())
 			continue
 		}
-		evaluated := evaluate.Eval(program, env)
+		c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+		c.SetStreams(streams)
+		evaluated := c.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -36,6 +45,60 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
+// StartVM is Start backed by the compiler and vm. The symbol table, constant
+// pool and globals are carried from one line to the next.
+func StartVM(in io.Reader, out io.Writer) {
+	reader := bufio.NewReader(in)
+	streams := &object.Streams{Stdin: reader, Stdout: out, Stderr: out}
+	constants := []object.Object{}
+	globals := make([]object.Object, vm.GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+	for idx, def := range object.Builtins {
+		symbolTable.DefineBuiltin(idx, def.Name)
+	}
+	for {
+		input, ok := readLine(reader, out)
+		if !ok {
+			return
+		}
+		lexer := lexer.New(input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		if len(parser.Errors()) != 0 {
+			printParseErrors(out, parser.Errors())
+			continue
+		}
+		comp := compiler.NewWithState(symbolTable, constants)
+		if err := comp.Compile(program); err != nil {
+			io.WriteString(out, err.Error()+"\n")
+			continue
+		}
+		bytecode := comp.Bytecode()
+		constants = bytecode.Constants
+		machine := vm.NewWithGlobalsStore(bytecode, globals)
+		machine.SetStreams(streams)
+		if err := machine.Run(); err != nil {
+			io.WriteString(out, err.Error()+"\n")
+			continue
+		}
+		if result := machine.Result(); result != nil {
+			io.WriteString(out, result.Inspect())
+			io.WriteString(out, "\n")
+		}
+	}
+}
+
+// readLine writes the prompt and reads the next line, reporting false once
+// the input is exhausted.
+func readLine(reader *bufio.Reader, out io.Writer) (string, bool) {
+	io.WriteString(out, PROMPT)
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimRight(line, "\r\n"), true
+}
+
 func printParseErrors(out io.Writer, errors []string) {
 	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
@@ -0,0 +1,22 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	input := "let name = read_line();\nhello\nprintln(\"hi\", name)\n1 + 2\n"
+	expected := ">>>>hi hello\nnull\n>>3\n>>"
+	for name, start := range map[string]func(in *strings.Reader, out *bytes.Buffer){
+		"eval": func(in *strings.Reader, out *bytes.Buffer) { Start(in, out) },
+		"vm":   func(in *strings.Reader, out *bytes.Buffer) { StartVM(in, out) },
+	} {
+		var out bytes.Buffer
+		start(strings.NewReader(input), &out)
+		if out.String() != expected {
+			t.Errorf("%s: wrong output\nwant %q\ngot  %q", name, expected, out.String())
+		}
+	}
+}
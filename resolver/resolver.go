@@ -0,0 +1,441 @@
+// Package resolver checks the names a program uses before it runs. It
+// reports identifiers that are never declared, local variables that are
+// never used and declarations that shadow others, and records where each
+// identifier is bound so that the evaluator can go straight to the
+// environment holding it.
+//
+// Scopes follow the environments the evaluator creates: one for the
+// program, one per function call (with one more holding self for methods),
+// one per match arm and a short-lived one for the defaults of a
+// destructuring let. Blocks do not open scopes. Function bodies are
+// resolved once the scope they are written in is complete, since they may
+// call functions declared after them.
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Column) }
+
+// Span is the source range of a token, from its first byte to just past
+// its last.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func spanOf(tok token.Token) Span {
+	start := Position{Line: tok.Line, Column: tok.Column}
+	return Span{Start: start, End: Position{Line: tok.Line, Column: tok.Column + len(tok.Literal)}}
+}
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Span     Span
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Span.Start, d.Severity, d.Message)
+}
+
+// Address locates the binding an identifier refers to. Depth counts the
+// environments to go outward from the one the identifier is evaluated in,
+// and Slot numbers the declarations of that environment in order.
+type Address struct {
+	Depth int
+	Slot  int
+}
+
+type Resolution struct {
+	// Addresses holds the identifiers bound by the program. Names bound
+	// outside it, such as builtins, have no address.
+	Addresses map[*ast.Identifier]Address
+	// Definitions maps each identifier with an address to the identifier
+	// that declares it.
+	Definitions map[*ast.Identifier]*ast.Identifier
+	Diagnostics []Diagnostic //ordered by position
+}
+
+// Errors returns the diagnostics that are errors rather than warnings.
+func (r *Resolution) Errors() []Diagnostic {
+	errs := []Diagnostic{}
+	for _, d := range r.Diagnostics {
+		if d.Severity == Error {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+type kind int
+
+const (
+	variable kind = iota
+	constant
+	parameter
+	enumValue //a variant without fields, which patterns compare against
+	declaration
+)
+
+type binding struct {
+	name *ast.Identifier
+	slot int
+	kind kind
+	used bool
+}
+
+type scope struct {
+	names     map[string]*binding
+	slots     int
+	global    bool
+	temporary bool     //the scope of a destructuring let
+	pending   []func() //function bodies to resolve when the scope is complete
+}
+
+type resolver struct {
+	scopes  []*scope
+	defined func(name string) bool
+	result  *Resolution
+}
+
+// Resolve resolves the names used by program. defined reports whether a
+// name is bound outside the program, as builtins are; it may be nil.
+func Resolve(program *ast.Program, defined func(name string) bool) *Resolution {
+	r := &resolver{
+		defined: defined,
+		result: &Resolution{
+			Addresses:   map[*ast.Identifier]Address{},
+			Definitions: map[*ast.Identifier]*ast.Identifier{},
+		},
+	}
+	r.push(&scope{global: true})
+	for _, stmt := range program.Statements {
+		r.statement(stmt)
+	}
+	r.pop()
+	sort.SliceStable(r.result.Diagnostics, func(i, j int) bool {
+		a, b := r.result.Diagnostics[i].Span.Start, r.result.Diagnostics[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return r.result
+}
+
+func (r *resolver) report(severity Severity, tok token.Token, format string, a ...interface{}) {
+	r.result.Diagnostics = append(r.result.Diagnostics, Diagnostic{
+		Severity: severity,
+		Span:     spanOf(tok),
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (r *resolver) current() *scope { return r.scopes[len(r.scopes)-1] }
+
+func (r *resolver) push(s *scope) {
+	if s.names == nil {
+		s.names = map[string]*binding{}
+	}
+	r.scopes = append(r.scopes, s)
+}
+
+// pop resolves the function bodies written in the current scope, which
+// can now see all of its declarations, and closes it.
+func (r *resolver) pop() {
+	s := r.current()
+	for len(s.pending) > 0 {
+		resolve := s.pending[0]
+		s.pending = s.pending[1:]
+		resolve()
+	}
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	if s.global || s.temporary {
+		return
+	}
+	unused := []*binding{}
+	for name, b := range s.names {
+		if !b.used && (b.kind == variable || b.kind == constant) && name[0] != '_' {
+			unused = append(unused, b)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].slot < unused[j].slot })
+	for _, b := range unused {
+		r.report(Warning, b.name.Token, "%s declared and not used", b.name.Value)
+	}
+}
+
+// later runs resolve once the current scope is complete, with the scopes
+// open now.
+func (r *resolver) later(resolve func()) {
+	stack := append([]*scope(nil), r.scopes...)
+	s := r.current()
+	s.pending = append(s.pending, func() {
+		saved := r.scopes
+		r.scopes = stack
+		resolve()
+		r.scopes = saved
+	})
+}
+
+func (r *resolver) lookup(name string) (*binding, int) {
+	for depth := 0; depth < len(r.scopes); depth++ {
+		if b, ok := r.scopes[len(r.scopes)-1-depth].names[name]; ok {
+			return b, depth
+		}
+	}
+	return nil, -1
+}
+
+func (r *resolver) declare(ident *ast.Identifier, k kind) {
+	s := r.current()
+	if b, ok := s.names[ident.Value]; ok {
+		// a redeclaration rebinds the same slot
+		b.name, b.kind = ident, k
+		return
+	}
+	if (k == variable || k == constant) && !s.temporary {
+		for i := len(r.scopes) - 2; i >= 0; i-- {
+			outer := r.scopes[i]
+			if b, ok := outer.names[ident.Value]; ok && !outer.temporary {
+				r.report(Warning, ident.Token, "%s shadows the declaration at %s", ident.Value, spanOf(b.name.Token).Start)
+				break
+			}
+		}
+	}
+	s.names[ident.Value] = &binding{name: ident, slot: s.slots, kind: k}
+	s.slots++
+}
+
+// use resolves an identifier that is read. assign is set for the target
+// of an assignment, which does not count as a use.
+func (r *resolver) use(ident *ast.Identifier, assign bool) {
+	if b, depth := r.lookup(ident.Value); b != nil {
+		if !assign {
+			b.used = true
+		}
+		r.result.Addresses[ident] = Address{Depth: depth, Slot: b.slot}
+		r.result.Definitions[ident] = b.name
+		return
+	}
+	if r.defined != nil && r.defined(ident.Value) {
+		return
+	}
+	r.report(Error, ident.Token, "identifier not found %s", ident.Value)
+}
+
+func letKind(let *ast.LetStatement) kind {
+	if let.Constant() {
+		return constant
+	}
+	return variable
+}
+
+func (r *resolver) statement(stmt ast.StatmentNode) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		r.expression(stmt.Value)
+		if stmt.Pattern == nil {
+			r.declare(stmt.Name, letKind(stmt))
+			return
+		}
+		// the pattern is matched in a scope of its own, which its defaults
+		// see, before the names are declared
+		r.push(&scope{temporary: true})
+		r.pattern(stmt.Pattern, letKind(stmt))
+		r.pop()
+		for _, name := range stmt.Names() {
+			r.declare(name, letKind(stmt))
+		}
+	case *ast.ReturnStatement:
+		r.expression(stmt.Value)
+	case *ast.ExpressionStatement:
+		r.expression(stmt.Expression)
+	case *ast.ImportStatement:
+		r.declare(stmt.Name, declaration)
+	case *ast.ExportStatement:
+		r.statement(stmt.Statement)
+	case *ast.StructStatement:
+		for _, method := range stmt.Methods {
+			self := &ast.Identifier{Token: method.Name.Token, Value: "self"}
+			r.function(method.Function, self)
+		}
+		r.declare(stmt.Name, declaration)
+	case *ast.EnumStatement:
+		for _, variant := range stmt.Variants {
+			if variant.Fields == nil {
+				r.declare(variant.Name, enumValue)
+			} else {
+				r.declare(variant.Name, declaration)
+			}
+		}
+		r.declare(stmt.Name, declaration)
+	}
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+	for _, stmt := range block.Statements {
+		r.statement(stmt)
+	}
+}
+
+// function resolves the body of fn once the current scope is complete.
+// self is bound in a scope of its own around the parameters of a method.
+func (r *resolver) function(fn *ast.FunctionLiteral, self *ast.Identifier) {
+	r.later(func() {
+		if self != nil {
+			r.push(&scope{})
+			r.declare(self, parameter)
+		}
+		r.push(&scope{})
+		for i, param := range fn.Parameters {
+			if i < len(fn.Defaults) {
+				r.expression(fn.Defaults[i])
+			}
+			r.declare(param, parameter)
+		}
+		if fn.Rest != nil {
+			r.declare(fn.Rest, parameter)
+		}
+		r.block(fn.Body)
+		r.pop()
+		if self != nil {
+			r.pop()
+		}
+	})
+}
+
+func (r *resolver) expression(exp ast.ExpressionNode) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		r.use(exp, false)
+	case *ast.PrefixExpression:
+		r.expression(exp.Right)
+	case *ast.InfixExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Right)
+	case *ast.IfExpression:
+		r.expression(exp.Condition)
+		r.block(exp.Consequence)
+		if exp.Alternative != nil {
+			r.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		r.function(exp, nil)
+	case *ast.CallExpression:
+		r.expression(exp.Function)
+		for _, arg := range exp.Arguments {
+			r.expression(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, ele := range exp.Elements {
+			r.expression(ele)
+		}
+	case *ast.IndexExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Index)
+	case *ast.HashLiteral:
+		for key, value := range exp.Pairs {
+			r.expression(key)
+			r.expression(value)
+		}
+	case *ast.SpawnExpression:
+		r.expression(exp.Call)
+	case *ast.SelectExpression:
+		for _, sc := range exp.Cases {
+			if sc.Comm != nil {
+				r.expression(sc.Comm)
+			}
+			if sc.Name != nil {
+				r.declare(sc.Name, declaration)
+			}
+			r.block(sc.Body)
+		}
+	case *ast.MemberExpression:
+		r.expression(exp.Object)
+	case *ast.AssignExpression:
+		if ident, ok := exp.Target.(*ast.Identifier); ok {
+			r.expression(exp.Value)
+			r.use(ident, true)
+			return
+		}
+		r.expression(exp.Target)
+		r.expression(exp.Value)
+	case *ast.MatchExpression:
+		r.expression(exp.Subject)
+		for _, arm := range exp.Arms {
+			r.push(&scope{})
+			r.pattern(arm.Pattern, declaration)
+			if arm.Guard != nil {
+				r.expression(arm.Guard)
+			}
+			r.block(arm.Body)
+			r.pop()
+		}
+	case *ast.SpreadExpression:
+		r.expression(exp.Value)
+	case *ast.KeywordArgument:
+		r.expression(exp.Value)
+	}
+}
+
+// pattern declares the names a pattern binds, in the order the evaluator
+// binds them, resolving defaults and constructors along the way.
+func (r *resolver) pattern(pattern ast.Pattern, k kind) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		// in a match arm a variant without fields is compared against, not
+		// bound; a let always binds
+		if b, _ := r.lookup(pattern.Name.Value); k == declaration && b != nil && b.kind == enumValue {
+			r.use(pattern.Name, false)
+			return
+		}
+		// most likely a misspelt variant, which would match anything
+		if name := pattern.Name.Value; k == declaration && unicode.IsUpper(rune(name[0])) {
+			r.report(Warning, pattern.Name.Token, "%s is not a variant without fields, it matches any value", name)
+		}
+		r.declare(pattern.Name, k)
+	case *ast.DefaultPattern:
+		r.expression(pattern.Default)
+		r.pattern(pattern.Pattern, k)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			r.pattern(element, k)
+		}
+		if pattern.Rest != nil {
+			r.pattern(pattern.Rest, k)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			r.pattern(pair.Value, k)
+		}
+	case *ast.ConstructorPattern:
+		r.expression(pattern.Constructor)
+		for _, field := range pattern.Fields {
+			r.pattern(field, k)
+		}
+	}
+}
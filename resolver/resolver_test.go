@@ -0,0 +1,115 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func builtin(name string) bool { return name == "len" || name == "puts" }
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x + y", []string{"1:16: error: identifier not found y"}},
+		{"len([1]) + puts(1)", nil},
+		{"let f = fn(a) { if (a) { typo } else { a } }; f(1)", []string{"1:26: error: identifier not found typo"}},
+		// functions may call ones declared after them
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };\nlet odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(4)", nil},
+		{"let f = fn() { let a = 1; let _b = 2; 3 }; f()", []string{"1:20: warning: a declared and not used"}},
+		{"let x = 1; let f = fn() { let x = 2; x }; f() + x", []string{"1:31: warning: x shadows the declaration at 1:5"}},
+		// parameters shadow silently and need not be used
+		{"let x = 1; let f = fn(x, y) { x }; f(1, 2) + x", nil},
+		{"let f = fn() { let a = 1; a = 2 }; f()", []string{"1:20: warning: a declared and not used"}},
+		{"let f = fn(a, b = a, ...rest) { b + len(rest) }; f(1)", nil},
+		{"let f = fn(a = b, b = 1) { a + b }; f()", []string{"1:16: error: identifier not found b"}},
+		{"let [a, b = a, ...c] = [1]; a + b + len(c)", nil},
+		{"let {x: p, y: q = z} = {\"x\": 1}; p + q", []string{"1:19: error: identifier not found z"}},
+		{"struct P { x, fn norm() { self.x } }; P(1).norm()", nil},
+		{"enum Shape { Circle(r), Empty }; let area = fn(s) { match (s) { Circle(r) if r > 0 => r, Empty => 0, other => bad } }; area(Empty)",
+			[]string{"1:111: error: identifier not found bad"}},
+		{"let f = fn(s) { match (s) { [a, b] => a, _ => b } }; f(1)", []string{"1:47: error: identifier not found b"}},
+		{"enum Color { Red, Green }; match Red { Gren => 1, Color.Green => 2, Red => 3 }",
+			[]string{"1:40: warning: Gren is not a variant without fields, it matches any value"}},
+		// a let binds a name even when it refers to a variant
+		{"enum Color { Red }; let f = fn(v) { let [Red] = [v]; 1 }; f(1)", []string{"1:42: warning: Red shadows the declaration at 1:14", "1:42: warning: Red declared and not used"}},
+	}
+	for _, tt := range tests {
+		res := Resolve(parse(t, tt.input), builtin)
+		if len(res.Diagnostics) != len(tt.expected) {
+			t.Errorf("%q: expected %d diagnostics, got %v", tt.input, len(tt.expected), res.Diagnostics)
+			continue
+		}
+		for i, d := range res.Diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("%q: expected %q, got %q", tt.input, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	program := parse(t, "let a = 1; let f = fn(b) { let c = 2; fn() { a + b + c } }; len")
+	res := Resolve(program, builtin)
+	if errs := res.Errors(); len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	expected := map[string]Address{
+		"a": {Depth: 2, Slot: 0},
+		"b": {Depth: 1, Slot: 0},
+		"c": {Depth: 1, Slot: 1},
+	}
+	found := 0
+	for ident, addr := range res.Addresses {
+		want, ok := expected[ident.Value]
+		if !ok {
+			t.Errorf("unexpected address for %s", ident.Value)
+			continue
+		}
+		found++
+		if addr != want {
+			t.Errorf("%s: expected %+v, got %+v", ident.Value, want, addr)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d addresses, got %d", len(expected), found)
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	program := parse(t, "let a = 1;\nlet f = fn(a) { a };\nlet a = a + 1;\nf(a)")
+	res := Resolve(program, builtin)
+	expected := map[int]int{ // line of the use to line of the declaration
+		2: 2,
+		3: 1,
+		4: 3,
+	}
+	found := 0
+	for use, def := range res.Definitions {
+		if use.Value != "a" {
+			continue
+		}
+		found++
+		if want := expected[use.Token.Line]; def.Token.Line != want {
+			t.Errorf("a at %d:%d: expected the declaration on line %d, got %d:%d",
+				use.Token.Line, use.Token.Column, want, def.Token.Line, def.Token.Column)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d definitions of a, got %d", len(expected), found)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/compiler"
+	"github.com/nishokbanand/interpreter/evaluate"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+	"github.com/nishokbanand/interpreter/resolver"
+	"github.com/nishokbanand/interpreter/vm"
+)
+
+const (
+	engineEval = "eval"
+	engineVM   = "vm"
+)
+
+func checkEngine(engine string) error {
+	if engine != engineEval && engine != engineVM {
+		return fmt.Errorf("unknown engine %q", engine)
+	}
+	return nil
+}
+
+func parseSource(source string) (*ast.Program, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	return program, nil
+}
+
+// check resolves the names program uses. Warnings are written to warnings,
+// unless it is nil; names that are not defined anywhere make it fail.
+func check(program *ast.Program, warnings io.Writer) (*resolver.Resolution, error) {
+	res := resolver.Resolve(program, evaluate.IsBuiltin)
+	msgs := []string{}
+	for _, d := range res.Diagnostics {
+		if d.Severity == resolver.Warning {
+			if warnings != nil {
+				fmt.Fprintln(warnings, d)
+			}
+			continue
+		}
+		msgs = append(msgs, d.String())
+	}
+	if len(msgs) != 0 {
+		return nil, errors.New(strings.Join(msgs, "\n"))
+	}
+	return res, nil
+}
+
+// execute runs program on the chosen backend and returns its value. Imports
+// are resolved relative to dir.
+func execute(program *ast.Program, res *resolver.Resolution, engine string, dir string) (object.Object, error) {
+	if engine == engineVM {
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			return nil, err
+		}
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			return nil, err
+		}
+		return machine.Result(), nil
+	}
+	c := evaluate.NewContext(context.Background(), evaluate.Limits{})
+	c.SetModules(evaluate.NewModules(dir))
+	c.SetResolution(res)
+	result := c.Eval(program, object.NewEnvironment())
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, errors.New(errObj.Message)
+	}
+	return result, nil
+}
+
+// runFile runs the script at path. The resolver's warnings are printed to
+// stderr first if warn is set.
+func runFile(path string, engine string, warn bool) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	program, err := parseSource(string(source))
+	if err != nil {
+		return err
+	}
+	var warnings io.Writer
+	if warn {
+		warnings = os.Stderr
+	}
+	res, err := check(program, warnings)
+	if err != nil {
+		return err
+	}
+	result, err := execute(program, res, engine, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if result != nil && result != object.Null {
+		fmt.Println(result.Inspect())
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ type TokenType string //
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int //where the token starts, counted from 1; 0 for tokens the parser makes up
+	Column  int //in bytes, counted from 1
 }
 
 const (
@@ -13,12 +15,23 @@ const (
 	STRING = "STRING"
 	//keywords
 	LET      = "LET"
+	CONST    = "CONST"
 	FUNCTION = "FUNCTION"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
+	CASE     = "CASE"
+	DEFAULT  = "DEFAULT"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	STRUCT   = "STRUCT"
+	MATCH    = "MATCH"
+	ENUM     = "ENUM"
 	//SYMBOLS
 	LPAREN    = "("
 	RPAREN    = ")"
@@ -29,6 +42,10 @@ const (
 	LBRACKET  = "["
 	RBRACKET  = "]"
 	COLON     = ":"
+	DOT       = "."
+	ELLIPSIS  = "..."
+	ARROW     = "=>"
+	RETURNS   = "->" //between the parameters and the return type of a function
 	//OPERATORS
 	SUM         = "+"
 	MINUS       = "-"
@@ -43,14 +60,26 @@ const (
 	//MICEL
 	EOF     = "EOF"
 	ILLEGAL = "ILLEGAL"
+	COMMENT = "COMMENT" //only ever returned by Lexer.Comments
 )
 
 var Keywords = map[string]TokenType{ // maps cannot be created as const
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"const":   CONST,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"true":    TRUE,
+	"false":   FALSE,
+	"spawn":   SPAWN,
+	"select":  SELECT,
+	"case":    CASE,
+	"default": DEFAULT,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
+	"struct":  STRUCT,
+	"match":   MATCH,
+	"enum":    ENUM,
 }
@@ -0,0 +1,41 @@
+package typecheck
+
+// builtins gives the type each builtin returns for arguments of the given
+// types. The builtins check their own arguments when they run.
+var builtins = map[string]func(args []Type) Type{
+	"len":       returns(Int),
+	"first":     element,
+	"last":      element,
+	"rest":      sameAsFirst,
+	"push":      sameAsFirst,
+	"print":     returns(Null),
+	"eprint":    returns(Null),
+	"println":   returns(Null),
+	"printf":    returns(Null),
+	"input":     returns(Any),
+	"read_line": returns(Any),
+	"type":      returns(String),
+}
+
+func returns(typ Type) func([]Type) Type {
+	return func([]Type) Type { return typ }
+}
+
+// element is the type of an element of the array passed first.
+func element(args []Type) Type {
+	if len(args) > 0 {
+		if array, ok := args[0].(*Array); ok {
+			return array.Element
+		}
+	}
+	return Any
+}
+
+func sameAsFirst(args []Type) Type {
+	if len(args) > 0 {
+		if array, ok := args[0].(*Array); ok {
+			return array
+		}
+	}
+	return Any
+}
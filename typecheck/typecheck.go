@@ -0,0 +1,579 @@
+// Package typecheck infers the types of expressions before a program runs
+// and reports the operations that would fail because of them, such as
+// adding a string to an int or passing an array where a parameter is
+// annotated as int.
+//
+// Annotations are optional. A name without one takes the type of the value
+// it is bound to, and a value whose type cannot be known, such as a
+// parameter without an annotation, has type Any and is accepted anywhere.
+// Unannotated code is therefore only checked where the types are evident
+// from the literals in it.
+package typecheck
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/resolver"
+	"github.com/nishokbanand/interpreter/token"
+)
+
+type variable struct {
+	typ       Type
+	annotated bool //assignments must keep to typ
+}
+
+type scope struct {
+	names map[string]*variable
+	outer *scope
+}
+
+func (s *scope) lookup(name string) *variable {
+	for ; s != nil; s = s.outer {
+		if v, ok := s.names[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// function is the function literal whose body is being checked.
+type function struct {
+	result  Type //the annotated return type, or nil
+	returns Type //what the return statements return, joined
+}
+
+type checker struct {
+	scope       *scope
+	types       map[string]bool //the structs and enums declared so far
+	annotations map[ast.TypeNode]Type
+	functions   []*function
+	diagnostics []resolver.Diagnostic
+}
+
+// Check infers the types in program and returns the mismatches it finds,
+// ordered by position.
+func Check(program *ast.Program) []resolver.Diagnostic {
+	c := &checker{
+		scope: &scope{names: map[string]*variable{}},
+		types: map[string]bool{},
+
+		annotations: map[ast.TypeNode]Type{},
+	}
+	// annotations may name the types declared further down
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.StructStatement:
+			c.types[stmt.Name.Value] = true
+		case *ast.EnumStatement:
+			c.types[stmt.Name.Value] = true
+		}
+	}
+	for _, stmt := range program.Statements {
+		c.statement(stmt)
+	}
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i].Span.Start, c.diagnostics[j].Span.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+func (c *checker) report(tok token.Token, format string, a ...interface{}) {
+	start := resolver.Position{Line: tok.Line, Column: tok.Column}
+	c.diagnostics = append(c.diagnostics, resolver.Diagnostic{
+		Severity: resolver.Error,
+		Span:     resolver.Span{Start: start, End: resolver.Position{Line: tok.Line, Column: tok.Column + len(tok.Literal)}},
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *checker) push() {
+	c.scope = &scope{names: map[string]*variable{}, outer: c.scope}
+}
+
+func (c *checker) pop() {
+	c.scope = c.scope.outer
+}
+
+func (c *checker) declare(name string, typ Type, annotated bool) {
+	c.scope.names[name] = &variable{typ: typ, annotated: annotated}
+}
+
+// typeOf turns an annotation into a type, reporting the names that are
+// not types the first time it is asked about them.
+func (c *checker) typeOf(node ast.TypeNode) Type {
+	if typ, ok := c.annotations[node]; ok {
+		return typ
+	}
+	typ := c.annotation(node)
+	c.annotations[node] = typ
+	return typ
+}
+
+func (c *checker) annotation(node ast.TypeNode) Type {
+	switch node := node.(type) {
+	case *ast.NamedType:
+		switch node.Name {
+		case "int":
+			return Int
+		case "string":
+			return String
+		case "bool":
+			return Bool
+		case "null":
+			return Null
+		case "any":
+			return Any
+		}
+		if c.types[node.Name] {
+			return &Named{Name: node.Name}
+		}
+		c.report(node.Token, "unknown type %s", node.Name)
+	case *ast.ArrayType:
+		return &Array{Element: c.typeOf(node.Element)}
+	case *ast.HashType:
+		return &Hash{Key: c.typeOf(node.Key), Value: c.typeOf(node.Value)}
+	case *ast.FunctionType:
+		fn := &Function{Required: len(node.Parameters), Return: Any}
+		for _, param := range node.Parameters {
+			fn.Params = append(fn.Params, c.typeOf(param))
+		}
+		if node.Return != nil {
+			fn.Return = c.typeOf(node.Return)
+		}
+		return fn
+	}
+	return Any
+}
+
+func (c *checker) statement(stmt ast.StatmentNode) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt)
+	case *ast.ReturnStatement:
+		typ := c.expression(stmt.Value)
+		if len(c.functions) == 0 {
+			return typ
+		}
+		fn := c.functions[len(c.functions)-1]
+		if fn.result != nil && !assignable(fn.result, typ) {
+			c.report(ast.StartToken(stmt.Value), "cannot use %s as %s in return", typ, fn.result)
+		}
+		fn.returns = join(fn.returns, typ)
+		return typ
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expression)
+	case *ast.ImportStatement:
+		c.declare(stmt.Name.Value, Any, false)
+	case *ast.ExportStatement:
+		c.let(stmt.Statement)
+	case *ast.StructStatement:
+		c.types[stmt.Name.Value] = true
+		instance := &Named{Name: stmt.Name.Value}
+		constructor := &Function{Required: len(stmt.Fields), Return: instance}
+		for range stmt.Fields {
+			constructor.Params = append(constructor.Params, Any)
+		}
+		c.declare(stmt.Name.Value, constructor, false)
+		for _, method := range stmt.Methods {
+			c.push()
+			c.declare("self", instance, false)
+			c.function(method.Function)
+			c.pop()
+		}
+	case *ast.EnumStatement:
+		c.types[stmt.Name.Value] = true
+		variant := &Named{Name: stmt.Name.Value}
+		for _, v := range stmt.Variants {
+			if v.Fields == nil {
+				c.declare(v.Name.Value, variant, false)
+				continue
+			}
+			constructor := &Function{Required: len(v.Fields), Return: variant}
+			for range v.Fields {
+				constructor.Params = append(constructor.Params, Any)
+			}
+			c.declare(v.Name.Value, constructor, false)
+		}
+		c.declare(stmt.Name.Value, Any, false)
+	}
+	return Any
+}
+
+func (c *checker) let(let *ast.LetStatement) {
+	if let.Pattern != nil {
+		c.expression(let.Value)
+		c.push()
+		c.pattern(let.Pattern)
+		c.pop()
+		for _, name := range let.Names() {
+			c.declare(name.Value, Any, false)
+		}
+		return
+	}
+	if let.Type == nil {
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			// the function may call itself
+			c.declare(let.Name.Value, c.signature(fn), false)
+		}
+		c.declare(let.Name.Value, c.expression(let.Value), false)
+		return
+	}
+	annotation := c.typeOf(let.Type)
+	c.declare(let.Name.Value, annotation, true)
+	if typ := c.expression(let.Value); !assignable(annotation, typ) {
+		c.report(ast.StartToken(let.Value), "cannot use %s as %s in let %s", typ, annotation, let.Name.Value)
+	}
+}
+
+// signature is the type of fn as far as its annotations tell.
+func (c *checker) signature(fn *ast.FunctionLiteral) *Function {
+	typ := &Function{Required: len(fn.Parameters), Return: Any}
+	for i := range fn.Parameters {
+		param := Type(Any)
+		if i < len(fn.Types) && fn.Types[i] != nil {
+			param = c.typeOf(fn.Types[i])
+		}
+		typ.Params = append(typ.Params, param)
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil && typ.Required == len(fn.Parameters) {
+			typ.Required = i
+		}
+	}
+	if fn.Rest != nil {
+		typ.Rest = Any
+		if rest, ok := c.typeOf(fn.RestType).(*Array); ok {
+			typ.Rest = rest.Element
+		}
+	}
+	if fn.ReturnType != nil {
+		typ.Return = c.typeOf(fn.ReturnType)
+	}
+	return typ
+}
+
+// function checks the body of fn and returns its type, with the return
+// type inferred from the body when it is not annotated.
+func (c *checker) function(fn *ast.FunctionLiteral) Type {
+	typ := c.signature(fn)
+	if fn.RestType != nil {
+		if rest := c.typeOf(fn.RestType); rest != Any {
+			if _, ok := rest.(*Array); !ok {
+				c.report(fn.Rest.Token, "rest parameter %s must be an array, not %s", fn.Rest.Value, rest)
+			}
+		}
+	}
+	c.push()
+	for i, param := range fn.Parameters {
+		if i < len(fn.Defaults) && fn.Defaults[i] != nil {
+			if def := c.expression(fn.Defaults[i]); !assignable(typ.Params[i], def) {
+				c.report(ast.StartToken(fn.Defaults[i]), "cannot use %s as %s in default of %s", def, typ.Params[i], param.Value)
+			}
+		}
+		c.declare(param.Value, typ.Params[i], i < len(fn.Types) && fn.Types[i] != nil)
+	}
+	if fn.Rest != nil {
+		c.declare(fn.Rest.Value, &Array{Element: typ.Rest}, fn.RestType != nil)
+	}
+	current := &function{}
+	if fn.ReturnType != nil {
+		current.result = typ.Return
+	}
+	c.functions = append(c.functions, current)
+	result := c.block(fn.Body)
+	c.functions = c.functions[:len(c.functions)-1]
+	c.pop()
+	if n := len(fn.Body.Statements); n == 0 || !isReturn(fn.Body.Statements[n-1]) {
+		if current.result != nil && !assignable(current.result, result) {
+			tok := fn.Body.Token
+			if n > 0 {
+				tok = ast.Start(fn.Body.Statements[n-1])
+			}
+			c.report(tok, "cannot use %s as %s in return", result, current.result)
+		}
+		current.returns = join(current.returns, result)
+	}
+	if current.result == nil {
+		typ.Return = current.returns
+	}
+	return typ
+}
+
+func isReturn(stmt ast.StatmentNode) bool {
+	_, ok := stmt.(*ast.ReturnStatement)
+	return ok
+}
+
+// block checks the statements of block and returns the type of its value,
+// which is that of the last one.
+func (c *checker) block(block *ast.BlockStatement) Type {
+	var typ Type = Null
+	for _, stmt := range block.Statements {
+		typ = c.statement(stmt)
+	}
+	return typ
+}
+
+func (c *checker) expression(exp ast.ExpressionNode) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.String:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		if v := c.scope.lookup(exp.Value); v != nil {
+			return v.typ
+		}
+		return Any
+	case *ast.PrefixExpression:
+		right := c.expression(exp.Right)
+		if exp.Operator == "!" {
+			return Bool
+		}
+		if right != Any && right != Int {
+			c.report(exp.Token, "unknown operator: %s%s", exp.Operator, right)
+		}
+		return Int
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.expression(exp.Condition)
+		consequence := c.block(exp.Consequence)
+		if exp.Alternative == nil {
+			return join(consequence, Null)
+		}
+		return join(consequence, c.block(exp.Alternative))
+	case *ast.FunctionLiteral:
+		return c.function(exp)
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.ArrayLiteral:
+		var element Type
+		for _, e := range exp.Elements {
+			element = join(element, c.expression(e))
+		}
+		if element == nil {
+			element = Any
+		}
+		return &Array{Element: element}
+	case *ast.IndexExpression:
+		return c.index(exp)
+	case *ast.HashLiteral:
+		var key, value Type
+		for k, v := range exp.Pairs {
+			typ := c.expression(k)
+			if typ != Any && typ != Int && typ != String && typ != Bool {
+				c.report(ast.StartToken(k), "cannot use %s as a hash key", typ)
+			}
+			key = join(key, typ)
+			value = join(value, c.expression(v))
+		}
+		if key == nil {
+			key, value = Any, Any
+		}
+		return &Hash{Key: key, Value: value}
+	case *ast.SpawnExpression:
+		c.expression(exp.Call)
+	case *ast.SelectExpression:
+		var typ Type
+		for _, sc := range exp.Cases {
+			if sc.Comm != nil {
+				c.expression(sc.Comm)
+			}
+			if sc.Name != nil {
+				c.declare(sc.Name.Value, Any, false)
+			}
+			typ = join(typ, c.block(sc.Body))
+		}
+		if typ != nil {
+			return typ
+		}
+	case *ast.MemberExpression:
+		c.expression(exp.Object)
+	case *ast.AssignExpression:
+		return c.assign(exp)
+	case *ast.MatchExpression:
+		c.expression(exp.Subject)
+		var typ Type
+		for _, arm := range exp.Arms {
+			c.push()
+			c.pattern(arm.Pattern)
+			if arm.Guard != nil {
+				c.expression(arm.Guard)
+			}
+			typ = join(typ, c.block(arm.Body))
+			c.pop()
+		}
+		if typ != nil {
+			return typ
+		}
+	case *ast.SpreadExpression:
+		c.expression(exp.Value)
+	case *ast.KeywordArgument:
+		c.expression(exp.Value)
+	}
+	return Any
+}
+
+// pattern declares the names pattern binds, whose types are not known.
+func (c *checker) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.DefaultPattern:
+		c.expression(pattern.Default)
+		c.pattern(pattern.Pattern)
+	case *ast.ConstructorPattern:
+		for _, field := range pattern.Fields {
+			c.pattern(field)
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			c.pattern(element)
+		}
+		if pattern.Rest != nil {
+			c.pattern(pattern.Rest)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			c.pattern(pair.Value)
+		}
+	case *ast.BindingPattern:
+		if v := c.scope.lookup(pattern.Name.Value); v != nil {
+			if _, ok := v.typ.(*Named); ok {
+				return //a variant compared against
+			}
+		}
+		c.declare(pattern.Name.Value, Any, false)
+	}
+}
+
+func (c *checker) infix(exp *ast.InfixExpression) Type {
+	left, right := c.expression(exp.Left), c.expression(exp.Right)
+	comparison := exp.Operator == "<" || exp.Operator == ">" || exp.Operator == "==" || exp.Operator == "!="
+	if left == Any || right == Any {
+		known := left
+		if known == Any {
+			known = right
+		}
+		switch {
+		case comparison:
+			return Bool
+		case known == Int:
+			return Int
+		case known == String && exp.Operator == "+":
+			return String
+		}
+		return Any
+	}
+	if kind(left) != kind(right) {
+		c.report(exp.Token, "operands are not of the same type: %s %s %s", left, exp.Operator, right)
+		return Any
+	}
+	switch {
+	case left == Int && comparison:
+		return Bool
+	case left == Int:
+		return Int
+	case left == String && exp.Operator == "+":
+		return String
+	case exp.Operator == "==" || exp.Operator == "!=":
+		return Bool
+	}
+	c.report(exp.Token, "unknown operator: %s %s %s", left, exp.Operator, right)
+	return Any
+}
+
+func (c *checker) index(exp *ast.IndexExpression) Type {
+	left, index := c.expression(exp.Left), c.expression(exp.Index)
+	switch left := left.(type) {
+	case *Array:
+		if !assignable(Int, index) {
+			c.report(ast.StartToken(exp.Index), "cannot index %s with %s", left, index)
+		}
+		return left.Element
+	case *Hash:
+		if !assignable(left.Key, index) {
+			c.report(ast.StartToken(exp.Index), "cannot index %s with %s", left, index)
+		}
+		return left.Value
+	}
+	if left != Any {
+		c.report(exp.Token, "index operator not supported: %s", left)
+	}
+	return Any
+}
+
+func (c *checker) assign(exp *ast.AssignExpression) Type {
+	value := c.expression(exp.Value)
+	ident, ok := exp.Target.(*ast.Identifier)
+	if !ok {
+		c.expression(exp.Target)
+		return value
+	}
+	v := c.scope.lookup(ident.Value)
+	switch {
+	case v == nil:
+	case v.annotated && !assignable(v.typ, value):
+		c.report(ast.StartToken(exp.Value), "cannot use %s as %s in assignment to %s", value, v.typ, ident.Value)
+	case !v.annotated && v.typ.String() != value.String():
+		// without an annotation the variable may hold anything
+		v.typ = Any
+	}
+	return value
+}
+
+func (c *checker) call(exp *ast.CallExpression) Type {
+	args := []Type{}
+	dynamic := false //the arguments cannot be matched to the parameters
+	for _, arg := range exp.Arguments {
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.KeywordArgument:
+			dynamic = true
+		}
+		args = append(args, c.expression(arg))
+	}
+	if ident, ok := exp.Function.(*ast.Identifier); ok && c.scope.lookup(ident.Value) == nil {
+		if builtin, ok := builtins[ident.Value]; ok {
+			return builtin(args)
+		}
+	}
+	callee := c.expression(exp.Function)
+	fn, ok := callee.(*Function)
+	if !ok {
+		if callee != Any {
+			c.report(ast.StartToken(exp.Function), "%s is not a function", callee)
+		}
+		return Any
+	}
+	if dynamic {
+		return fn.Return
+	}
+	if len(args) < fn.Required || len(args) > len(fn.Params) && fn.Rest == nil {
+		c.report(exp.Token, "%s", arityMessage(fn, len(args)))
+		return fn.Return
+	}
+	for i, arg := range args {
+		param := fn.Rest
+		if i < len(fn.Params) {
+			param = fn.Params[i]
+		}
+		if !assignable(param, arg) {
+			c.report(ast.StartToken(exp.Arguments[i]), "cannot use %s as %s in argument %d to %s", arg, param, i+1, exp.Function)
+		}
+	}
+	return fn.Return
+}
+
+// arityMessage words a wrong number of arguments the way the evaluator
+// does.
+func arityMessage(fn *Function, got int) string {
+	switch {
+	case fn.Rest != nil:
+		return fmt.Sprintf("wrong number of arguments: want at least %d, got=%d", fn.Required, got)
+	case fn.Required != len(fn.Params):
+		return fmt.Sprintf("wrong number of arguments: want=%d to %d, got=%d", fn.Required, len(fn.Params), got)
+	}
+	return fmt.Sprintf("wrong number of arguments: want=%d, got=%d", len(fn.Params), got)
+}
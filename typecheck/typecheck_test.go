@@ -0,0 +1,106 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/ast"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x: int = 5; x + 1`, nil},
+		{`let x: int = "five";`, []string{"1:14: error: cannot use string as int in let x"}},
+		{`let n = 5; n + "s"`, []string{"1:14: error: operands are not of the same type: int + string"}},
+		{`"a" - "b"`, []string{"1:5: error: unknown operator: string - string"}},
+		{`-"a"`, []string{"1:1: error: unknown operator: -string"}},
+		{`true == false; [1] == [2]`, nil},
+		// unannotated parameters may be anything
+		{`let f = fn(a, b) { a + b }; f(1, "x") + f(true, [])`, nil},
+		{`let add = fn(a: int, b: int) -> int { a + b }; add(1, "2")`,
+			[]string{"1:55: error: cannot use string as int in argument 2 to add"}},
+		{`let add = fn(a: int, b: int) -> int { a + b }; add(1) + add(1, 2, 3)`,
+			[]string{"1:51: error: wrong number of arguments: want=2, got=1", "1:60: error: wrong number of arguments: want=2, got=3"}},
+		{`let f = fn(a, b = 1) { a }; f()`, []string{"1:30: error: wrong number of arguments: want=1 to 2, got=0"}},
+		{`let f = fn(a: int, ...rest: [string]) { a }; f(1, "a", 2)`,
+			[]string{"1:56: error: cannot use int as string in argument 3 to f"}},
+		{`let f = fn(...rest: int) { rest }`, []string{"1:15: error: rest parameter rest must be an array, not int"}},
+		{`let f = fn() -> bool { 1 }`, []string{"1:24: error: cannot use int as bool in return"}},
+		{`let f = fn(x) -> bool { if (x) { return "yes" } false }`, []string{"1:41: error: cannot use string as bool in return"}},
+		{`let f = fn(x: string) -> bool { return x == "a"; }; let b: bool = f("a")`, nil},
+		// the return type is inferred when it is not annotated
+		{`let f = fn() { "s" }; let n: int = f()`, []string{"1:36: error: cannot use string as int in let n"}},
+		{`let fact = fn(n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact("5")`,
+			[]string{"1:82: error: cannot use string as int in argument 1 to fact"}},
+		{`let xs: [int] = [1, 2]; xs["a"]`, []string{"1:28: error: cannot index [int] with string"}},
+		{`let xs: [int] = [1, "a"]`, nil},
+		{`let xs: [string] = [1, 2]`, []string{"1:20: error: cannot use [int] as [string] in let xs"}},
+		{`let ages: {string: int} = {"ann": 1}; ages["bob"] + 1`, nil},
+		{`let s = "abc"; s[0]`, []string{"1:17: error: index operator not supported: string"}},
+		{`{[1]: 2}`, []string{"1:2: error: cannot use [int] as a hash key"}},
+		{`let x = 5; x()`, []string{"1:12: error: int is not a function"}},
+		{`let x: int = 1; x = "a"`, []string{"1:21: error: cannot use string as int in assignment to x"}},
+		// an unannotated variable may change its type
+		{`let x = 1; x = "a"; x + "b"`, nil},
+		{`let x: Point = 1`, []string{"1:8: error: unknown type Point"}},
+		{`let p: Point = Point(1, 2); struct Point { x, y }`, nil},
+		{`struct Point { x, y }; let p: Point = 5`, []string{"1:39: error: cannot use int as Point in let p"}},
+		{`enum Shape { Circle(r), Empty }; let s: Shape = Empty; let t: Shape = Circle(1)`, nil},
+		{`let apply: fn(fn(int) -> int, int) -> int = fn(f, x) { f(x) }; apply(fn(x: int) -> int { x }, 1)`, nil},
+		{`let apply: fn(fn(int) -> int) -> int = fn(f) { f(1) }; apply(fn(s: string) { s })`,
+			[]string{"1:62: error: cannot use fn(string) -> string as fn(int) -> int in argument 1 to apply"}},
+		{`len([1]) + 1; let xs: [int] = push([1], 2); let x: int = first(xs)`, nil},
+		{`let s: string = len("abc")`, []string{"1:17: error: cannot use int as string in let s"}},
+	}
+	for _, tt := range tests {
+		diagnostics := Check(parse(t, tt.input))
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("%q: expected %d diagnostics, got %v", tt.input, len(tt.expected), diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("%q: expected %q, got %q", tt.input, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	intToInt := &Function{Params: []Type{Int}, Required: 1, Return: Int}
+	tests := []struct {
+		to, from Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{Int, String, false},
+		{Any, String, true},
+		{Int, Any, true},
+		{&Array{Element: Int}, &Array{Element: Any}, true},
+		{&Array{Element: Int}, &Array{Element: Bool}, false},
+		{&Hash{Key: String, Value: Int}, &Hash{Key: String, Value: Int}, true},
+		{&Named{Name: "P"}, &Named{Name: "Q"}, false},
+		{intToInt, &Function{Params: []Type{Any, Int}, Required: 1, Return: Int}, true},
+		{intToInt, &Function{Params: []Type{Int, Int}, Required: 2, Return: Int}, false},
+		{intToInt, &Function{Rest: Int, Return: Int}, true},
+	}
+	for _, tt := range tests {
+		if actual := assignable(tt.to, tt.from); actual != tt.expected {
+			t.Errorf("assignable(%s, %s): expected %t, got %t", tt.to, tt.from, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package typecheck
+
+import (
+	"strings"
+)
+
+// Type is the static type of an expression. Any stands for a value whose
+// type is not known before the program runs, and is compatible with every
+// other type.
+type Type interface {
+	String() string
+}
+
+type Basic string
+
+func (b Basic) String() string { return string(b) }
+
+const (
+	Int    Basic = "int"
+	String Basic = "string"
+	Bool   Basic = "bool"
+	Null   Basic = "null"
+	Any    Basic = "any"
+)
+
+// Array is the type of arrays whose elements are all of type Element.
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+// Function is the type of functions, builtins and constructors. Only the
+// first Required parameters must be passed; Rest, if set, is the type of
+// the arguments collected past the end of Params.
+type Function struct {
+	Params   []Type
+	Required int
+	Rest     Type
+	Return   Type
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for _, param := range f.Params {
+		params = append(params, param.String())
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// Named is the type of the instances of a struct, or of the variants of
+// an enum.
+type Named struct {
+	Name string
+}
+
+func (n *Named) String() string { return n.Name }
+
+// kind is what the evaluator reports as the type of a value of type t,
+// which is what its operators compare.
+func kind(t Type) string {
+	switch t := t.(type) {
+	case Basic:
+		return string(t)
+	case *Array:
+		return "array"
+	case *Hash:
+		return "hash"
+	case *Function:
+		return "fn"
+	case *Named:
+		return t.Name
+	}
+	return ""
+}
+
+// assignable reports whether a value of type from may be used where one
+// of type to is expected.
+func assignable(to, from Type) bool {
+	if to == Any || from == Any {
+		return true
+	}
+	switch to := to.(type) {
+	case *Array:
+		from, ok := from.(*Array)
+		return ok && assignable(to.Element, from.Element)
+	case *Hash:
+		from, ok := from.(*Hash)
+		return ok && assignable(to.Key, from.Key) && assignable(to.Value, from.Value)
+	case *Function:
+		from, ok := from.(*Function)
+		if !ok || len(to.Params) < from.Required || len(to.Params) > len(from.Params) && from.Rest == nil {
+			return false
+		}
+		for i, param := range to.Params {
+			expected := from.Rest
+			if i < len(from.Params) {
+				expected = from.Params[i]
+			}
+			if !assignable(expected, param) {
+				return false
+			}
+		}
+		return assignable(to.Return, from.Return)
+	case *Named:
+		from, ok := from.(*Named)
+		return ok && to.Name == from.Name
+	}
+	return to == from
+}
+
+// join is the type of a value that is either of type a or of type b.
+func join(a, b Type) Type {
+	if a == nil {
+		return b
+	}
+	if a.String() == b.String() {
+		return a
+	}
+	return Any
+}
@@ -0,0 +1,569 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nishokbanand/interpreter/code"
+	"github.com/nishokbanand/interpreter/compiler"
+	"github.com/nishokbanand/interpreter/object"
+)
+
+const (
+	StackSize    = 2048
+	MaxStackSize = 1 << 20
+	GlobalsSize  = 65536
+	MaxFrames    = 10000
+)
+
+var (
+	// ErrMaxDepth matches the evaluator's error so both backends report
+	// runaway recursion the same way.
+	ErrMaxDepth      = errors.New("maximum call depth exceeded")
+	ErrStackOverflow = errors.New("stack overflow")
+)
+
+var (
+	True  = object.True
+	False = object.False
+	Null  = object.Null
+)
+
+// Integers in this range are preallocated and shared, so the common small
+// loop counters and indexes do not allocate on every arithmetic result.
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 1024
+)
+
+var cachedIntegers [maxCachedInteger - minCachedInteger + 1]*object.Integer
+
+func init() {
+	for i := range cachedIntegers {
+		cachedIntegers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+	}
+}
+
+func newInteger(value int64) *object.Integer {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return cachedIntegers[value-minCachedInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
+var operators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+type VM struct {
+	constants   []object.Object
+	globalNames []string
+	stack       []object.Object
+	sp          int //always points to the next free slot, top of stack is stack[sp-1]
+	globals     []object.Object
+	frames      []*Frame
+	framesIndex int
+	result      object.Object
+	streams     map[string]*object.Builtin //replace the I/O entries of object.Builtins
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainClosure := &object.Closure{Fn: mainFn}
+	frames := make([]*Frame, 1, 64)
+	frames[0] = NewFrame(mainClosure, 0)
+	return &VM{
+		constants:   bytecode.Constants,
+		globalNames: bytecode.GlobalNames,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		globals:     make([]object.Object, len(bytecode.GlobalNames)),
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// NewWithGlobalsStore runs bytecode against an existing set of globals, which
+// is how the REPL keeps bindings between lines. The store must have room for
+// every global the compiler defined, GlobalsSize always does.
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = globals
+	return vm
+}
+
+// SetStreams routes the I/O builtins through streams instead of the process
+// streams.
+func (vm *VM) SetStreams(streams *object.Streams) {
+	vm.streams = streams.Builtins()
+}
+
+// Result returns the value the program evaluated to, or nil if it ended with
+// a statement that produces no value.
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return ErrMaxDepth
+	}
+	if vm.framesIndex < len(vm.frames) {
+		vm.frames[vm.framesIndex] = f
+	} else {
+		vm.frames = append(vm.frames, f)
+	}
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			if err := vm.push(vm.constants[constIndex]); err != nil {
+				return err
+			}
+		case code.OpPop:
+			vm.pop()
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			if err := vm.executeBinaryOperation(op); err != nil {
+				return err
+			}
+		case code.OpTrue:
+			if err := vm.push(True); err != nil {
+				return err
+			}
+		case code.OpFalse:
+			if err := vm.push(False); err != nil {
+				return err
+			}
+		case code.OpNull:
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+		case code.OpBang:
+			if err := vm.push(bang(vm.pop())); err != nil {
+				return err
+			}
+		case code.OpMinus:
+			operand := vm.pop()
+			integer, ok := operand.(*object.Integer)
+			if !ok {
+				return fmt.Errorf("Unknown operator -%s", operand.Type())
+			}
+			if err := vm.push(newInteger(-integer.Value)); err != nil {
+				return err
+			}
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			if !isTruthy(vm.pop()) {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			vm.globals[globalIndex] = vm.pop()
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			value := vm.globals[globalIndex]
+			if value == nil {
+				return fmt.Errorf("identifier not found %s", vm.globalName(int(globalIndex)))
+			}
+			if err := vm.push(value); err != nil {
+				return err
+			}
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			vm.stack[vm.currentFrame().basePointer+int(localIndex)] = vm.pop()
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			if err := vm.push(vm.stack[vm.currentFrame().basePointer+int(localIndex)]); err != nil {
+				return err
+			}
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			def := object.Builtins[builtinIndex]
+			builtin := def.Builtin
+			if override, ok := vm.streams[def.Name]; ok {
+				builtin = override
+			}
+			if err := vm.push(builtin); err != nil {
+				return err
+			}
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			if err := vm.push(vm.currentFrame().cl.Free[freeIndex]); err != nil {
+				return err
+			}
+		case code.OpCurrentClosure:
+			if err := vm.push(vm.currentFrame().cl); err != nil {
+				return err
+			}
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp -= numElements
+			if err := vm.push(&object.Array{Elements: elements}); err != nil {
+				return err
+			}
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp -= numElements
+			if err := vm.push(hash); err != nil {
+				return err
+			}
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			if err := vm.executeIndexExpression(left, index); err != nil {
+				return err
+			}
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			if vm.tailPosition() {
+				if err := vm.executeTailCall(int(numArgs)); err != nil {
+					return err
+				}
+				break
+			}
+			if err := vm.executeCall(int(numArgs)); err != nil {
+				return err
+			}
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+			if vm.framesIndex == 1 {
+				//returning from the main program ends it
+				vm.result = returnValue
+				return nil
+			}
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(returnValue); err != nil {
+				return err
+			}
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("unhandled opcode %s", def.Name)
+		}
+	}
+	return nil
+}
+
+func (vm *VM) globalName(index int) string {
+	if index < len(vm.globalNames) {
+		return vm.globalNames[index]
+	}
+	return fmt.Sprintf("global %d", index)
+}
+
+// grow makes sure the stack has room for n more slots above sp, doubling it
+// up to MaxStackSize.
+func (vm *VM) grow(n int) error {
+	if vm.sp+n <= len(vm.stack) {
+		return nil
+	}
+	if vm.sp+n > MaxStackSize {
+		return ErrStackOverflow
+	}
+	size := 2 * len(vm.stack)
+	for size < vm.sp+n {
+		size *= 2
+	}
+	stack := make([]object.Object, min(size, MaxStackSize))
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+	return nil
+}
+
+func (vm *VM) push(obj object.Object) error {
+	if err := vm.grow(1); err != nil {
+		return err
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+	operator := operators[op]
+	switch {
+	case left.Type() != right.Type():
+		return fmt.Errorf("Operands are not of the same type : %s %s %s", left.Type(), operator, right.Type())
+	case left.Type() == object.INTEGER_OBJ:
+		return vm.executeIntegerOperation(op, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case left.Type() == object.STRING_OBJ:
+		return vm.executeStringOperation(op, left.(*object.String).Value, right.(*object.String).Value)
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	default:
+		return fmt.Errorf("Unknown Operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func (vm *VM) executeIntegerOperation(op code.Opcode, left, right int64) error {
+	switch op {
+	case code.OpAdd:
+		return vm.push(newInteger(left + right))
+	case code.OpSub:
+		return vm.push(newInteger(left - right))
+	case code.OpMul:
+		return vm.push(newInteger(left * right))
+	case code.OpDiv:
+		if right == 0 {
+			return errors.New("division by zero")
+		}
+		return vm.push(newInteger(left / right))
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(left > right))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(left < right))
+	}
+	return fmt.Errorf("Unknown Operator %s %s %s", object.INTEGER_OBJ, operators[op], object.INTEGER_OBJ)
+}
+
+func (vm *VM) executeStringOperation(op code.Opcode, left, right string) error {
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.String{Value: left + right})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	}
+	return fmt.Errorf("Unknown Operator %s %s %s", object.STRING_OBJ, operators[op], object.STRING_OBJ)
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		max := int64(len(elements) - 1)
+		if idx < 0 || idx > max {
+			return fmt.Errorf("Out of bound Error :%d greater than %d", idx, max)
+		}
+		return vm.push(elements[idx])
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("Index unusable as hashKey %s", index.Type())
+		}
+		pair, ok := left.(*object.Hash).Pairs[key.HashKey()]
+		if !ok {
+			return vm.push(Null)
+		}
+		return vm.push(pair.Value)
+	default:
+		return fmt.Errorf("index Operator not supported %s", left.Type())
+	}
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("Cannot use as HashKey %s", key.Type())
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+	return &object.Hash{Pairs: pairs}, nil
+}
+
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return fmt.Errorf("not a function %s :", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
+	if err := vm.grow(cl.Fn.NumLocals); err != nil {
+		return err
+	}
+	//reserve the slots for the local bindings, they sit on top of the arguments
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+// tailPosition reports whether the call just decoded returns its result
+// straight away, possibly after jumping out of an if, in which case it can
+// reuse the frame of the function making it. Calls from the main program
+// always get a frame of their own.
+func (vm *VM) tailPosition() bool {
+	if vm.framesIndex == 1 {
+		return false
+	}
+	ins := vm.currentFrame().Instructions()
+	ip := vm.currentFrame().ip + 1
+	for ip < len(ins) && code.Opcode(ins[ip]) == code.OpJump {
+		ip = int(code.ReadUint16(ins[ip+1:]))
+	}
+	return ip < len(ins) && code.Opcode(ins[ip]) == code.OpReturnValue
+}
+
+// executeTailCall runs a call in tail position like the evaluator does:
+// a closure replaces the current frame instead of being pushed on top of it,
+// so tail recursion runs in constant space and never hits MaxFrames.
+func (vm *VM) executeTailCall(numArgs int) error {
+	cl, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure)
+	if !ok {
+		return vm.executeCall(numArgs)
+	}
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	frame := vm.currentFrame()
+	//the callee and its arguments take the place of the current ones
+	copy(vm.stack[frame.basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+	vm.sp = frame.basePointer + numArgs
+	if err := vm.grow(cl.Fn.NumLocals - numArgs); err != nil {
+		return err
+	}
+	frame.cl = cl
+	frame.ip = -1
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+	if result == nil {
+		return vm.push(Null)
+	}
+	if err, ok := result.(*object.Error); ok {
+		return errors.New(err.Message)
+	}
+	return vm.push(result)
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp -= numFree
+	return vm.push(&object.Closure{Fn: function, Free: free})
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
+
+func bang(obj object.Object) object.Object {
+	switch obj {
+	case True:
+		return False
+	case False:
+		return True
+	case Null:
+		return True
+	default:
+		return False
+	}
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case False, Null:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/nishokbanand/interpreter/compiler"
+	"github.com/nishokbanand/interpreter/lexer"
+	"github.com/nishokbanand/interpreter/object"
+	"github.com/nishokbanand/interpreter/parser"
+)
+
+func run(t *testing.T, input string) (object.Object, error) {
+	t.Helper()
+	program := parser.New(lexer.New(input)).ParseProgram()
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	machine := New(comp.Bytecode())
+	err := machine.Run()
+	return machine.Result(), err
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1 + 2", 3},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"-50 + 100 + -50", 0},
+		{"let a = 5000; a * a", 25000000},
+	}
+	for _, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		integer, ok := result.(*object.Integer)
+		if !ok || integer.Value != tt.expected {
+			t.Errorf("%q: want %d, got %v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestSmallIntegersAreShared(t *testing.T) {
+	first, _ := run(t, "1 + 1")
+	second, _ := run(t, "3 - 1")
+	if first != second {
+		t.Errorf("expected small integer results to share one object")
+	}
+}
+
+func TestDeepRecursion(t *testing.T) {
+	result, err := run(t, "let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(5000)")
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != 12502500 {
+		t.Errorf("want 12502500, got %v", result)
+	}
+	_, err = run(t, "let f = fn() { 1 + f() }; f()")
+	if err != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, err)
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + n) } }; loop(100000, 0)", 5000050000},
+		// the callee needs more locals than the caller has
+		{"let g = fn(n) { let a = n; let b = a; b }; let f = fn(n) { g(n) }; f(7)", 7},
+		{"let f = fn(arr) { if (true) { len(arr) } }; f([1, 2])", 2},
+	}
+	for _, tt := range tests {
+		result, err := run(t, tt.input)
+		if err != nil {
+			t.Errorf("%q: vm error: %s", tt.input, err)
+			continue
+		}
+		if integer, ok := result.(*object.Integer); !ok || integer.Value != tt.expected {
+			t.Errorf("%q: want %d, got %v", tt.input, tt.expected, result)
+		}
+	}
+	_, err := run(t, "let f = fn(a) { f(a, a) }; f(1)")
+	if err == nil || err.Error() != "wrong number of arguments: want=1, got=2" {
+		t.Errorf("expected arity error, got %v", err)
+	}
+}
+
+func TestCallingWithWrongArguments(t *testing.T) {
+	_, err := run(t, "fn(a, b) { a + b }(1)")
+	if err == nil || err.Error() != "wrong number of arguments: want=2, got=1" {
+		t.Errorf("expected arity error, got %v", err)
+	}
+}